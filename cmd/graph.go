@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xyzbit/ino/config"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+	"github.com/xyzbit/ino/internal/infra"
+)
+
+const graphUsage = `Usage:
+  ino graph export -domain ID [-format jsonld|graphml] [-o FILE]
+  ino graph export -domain ID -format csv [-nodes FILE] [-edges FILE]
+  ino graph import -domain ID [-format jsonld|graphml] [-i FILE] [-dry-run]
+  ino graph import -domain ID -format csv -nodes FILE [-edges FILE] [-dry-run]
+`
+
+// runGraphCommand 图谱导入导出子命令，返回进程退出码
+func runGraphCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, graphUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("graph "+args[0], flag.ContinueOnError)
+	domainID := fs.Uint64("domain", 0, "knowledge domain ID")
+	format := fs.String("format", models.GraphFormatJSONLD, "jsonld, graphml or csv")
+	file := fs.String("o", "", "output file for jsonld/graphml export (default stdout)")
+	input := fs.String("i", "", "input file for jsonld/graphml import (default stdin)")
+	nodes := fs.String("nodes", services.GraphCSVNodesFile, "nodes csv file")
+	edges := fs.String("edges", services.GraphCSVEdgesFile, "edges csv file")
+	dryRun := fs.Bool("dry-run", false, "validate the import without writing")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *domainID == 0 {
+		fmt.Fprint(os.Stderr, "-domain is required\n"+graphUsage)
+		return 2
+	}
+
+	config.Init()
+	infra.Init()
+	defer infra.Close()
+	app.Init()
+
+	ctx := context.Background()
+	var err error
+	switch args[0] {
+	case "export":
+		if *format == models.GraphFormatCSV {
+			err = exportGraphCSV(ctx, *domainID, *nodes, *edges)
+		} else {
+			err = withOutput(*file, func(w io.Writer) error {
+				return app.GraphTransfer.Export(ctx, *domainID, *format, w)
+			})
+		}
+	case "import":
+		err = importGraph(ctx, *domainID, *format, *input, *nodes, *edges, *dryRun)
+	default:
+		fmt.Fprint(os.Stderr, graphUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graph %s failed: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// exportGraphCSV 导出实体和关系CSV文件
+func exportGraphCSV(ctx context.Context, domainID uint64, nodesPath, edgesPath string) error {
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	paths := map[string]string{
+		services.GraphCSVNodesFile: nodesPath,
+		services.GraphCSVEdgesFile: edgesPath,
+	}
+	return app.GraphTransfer.ExportCSV(ctx, domainID, func(name string) (io.Writer, error) {
+		f, err := os.Create(paths[name])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		return f, nil
+	})
+}
+
+// importGraph 导入图谱文件并打印导入结果
+func importGraph(ctx context.Context, domainID uint64, format, input, nodesPath, edgesPath string, dryRun bool) error {
+	opts := services.GraphImportOptions{DryRun: dryRun}
+
+	var (
+		result *models.GraphImportResult
+		err    error
+	)
+	if format == models.GraphFormatCSV {
+		nodes, err := os.Open(nodesPath)
+		if err != nil {
+			return err
+		}
+		defer nodes.Close()
+
+		var edgesReader io.Reader
+		if edges, err := os.Open(edgesPath); err == nil {
+			defer edges.Close()
+			edgesReader = edges
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		result, err = app.GraphTransfer.ImportCSV(ctx, domainID, nodes, edgesReader, opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		in := io.Reader(os.Stdin)
+		if input != "" {
+			f, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		result, err = app.GraphTransfer.Import(ctx, domainID, format, in, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// withOutput 写入文件，路径为空时写入标准输出
+func withOutput(path string, fn func(w io.Writer) error) error {
+	if path == "" {
+		return fn(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -11,11 +11,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/xyzbit/ino/config"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/application/worker"
 	"github.com/xyzbit/ino/internal/infra"
 	"github.com/xyzbit/ino/internal/server"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 && os.Args[1] == "graph" {
+		os.Exit(runGraphCommand(os.Args[2:]))
+	}
+
 	// 加载配置
 	config.Init()
 
@@ -28,6 +35,17 @@ func main() {
 	infra.Init()
 	defer infra.Close()
 
+	// 初始化应用依赖
+	app.Init()
+
+	// 启动后台任务
+	scheduler := worker.NewScheduler()
+	for _, job := range app.Jobs() {
+		scheduler.Register(job)
+	}
+	scheduler.Start()
+	defer scheduler.Stop()
+
 	// 创建路由
 	r := gin.Default()
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -14,6 +15,9 @@ type Config struct {
 	Milvus   MilvusConfig   `mapstructure:"milvus"`
 	Neo4j    Neo4jConfig    `mapstructure:"neo4j"`
 	Eino     EinoConfig     `mapstructure:"eino"`
+	Search   SearchConfig   `mapstructure:"search"`
+	Jobs     JobsConfig     `mapstructure:"jobs"`
+	Cache    CacheConfig    `mapstructure:"cache"`
 }
 
 // ServerConfig 服务器配置
@@ -54,8 +58,76 @@ type Neo4jConfig struct {
 
 // EinoConfig Eino AI配置
 type EinoConfig struct {
-	APIKey string `mapstructure:"api_key"`
-	Model  string `mapstructure:"model"`
+	APIKey         string        `mapstructure:"api_key"`
+	Model          string        `mapstructure:"model"`
+	BaseURL        string        `mapstructure:"base_url"`        // OpenAI兼容接口地址
+	EmbeddingModel string        `mapstructure:"embedding_model"` // 向量化模型
+	Timeout        time.Duration `mapstructure:"timeout"`         // 单次请求超时
+}
+
+// SearchConfig 检索配置
+type SearchConfig struct {
+	GraphCentralityBoost float64           `mapstructure:"graph_centrality_boost"` // 图检索中心度加权系数
+	Rerank               RerankConfig      `mapstructure:"rerank"`
+	Planner              PlannerConfig     `mapstructure:"planner"`
+	Cache                ResultCacheConfig `mapstructure:"cache"`
+}
+
+// ResultCacheConfig 检索结果缓存配置
+type ResultCacheConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	TTL               time.Duration `mapstructure:"ttl"`
+	SemanticThreshold float64       `mapstructure:"semantic_threshold"` // 近义查询复用结果的向量相似度阈值，0表示只做精确匹配
+	SemanticEntries   int           `mapstructure:"semantic_entries"`   // 每个知识域的语义索引保留的最近查询数
+}
+
+// PlannerConfig 查询规划配置
+type PlannerConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Budget  time.Duration `mapstructure:"budget"` // 大模型规划耗时上限，超时使用规则规划
+}
+
+// RerankConfig 重排序配置
+type RerankConfig struct {
+	Provider string        `mapstructure:"provider"` // cross_encoder, llm, lexical
+	BaseURL  string        `mapstructure:"base_url"` // 交叉编码器重排序接口地址
+	APIKey   string        `mapstructure:"api_key"`
+	Model    string        `mapstructure:"model"`
+	Protocol string        `mapstructure:"protocol"` // cohere（兼容Jina）或tei
+	TopN     int           `mapstructure:"top_n"`    // 参与重排序的候选数
+	Budget   time.Duration `mapstructure:"budget"`   // 重排序耗时上限，超时保持原顺序
+}
+
+// JobsConfig 后台任务配置，间隔为0表示不调度
+type JobsConfig struct {
+	GraphAnalyticsInterval    time.Duration `mapstructure:"graph_analytics_interval"`
+	CommunitySummaryInterval  time.Duration `mapstructure:"community_summary_interval"`
+	MemoryDistillInterval     time.Duration `mapstructure:"memory_distill_interval"`
+	MemoryConsolidateInterval time.Duration `mapstructure:"memory_consolidate_interval"`
+	QualityPriorInterval      time.Duration `mapstructure:"quality_prior_interval"`
+	CorrectionInterval        time.Duration `mapstructure:"correction_interval"`
+	SuggestionInterval        time.Duration `mapstructure:"suggestion_interval"`
+}
+
+// CacheConfig 缓存配置
+type CacheConfig struct {
+	Driver      string `mapstructure:"driver"`      // redis, memory, tiered（本地内存在前、Redis在后）
+	LocalTTL    int    `mapstructure:"local_ttl"`   // 两级缓存中本地副本的过期时间（秒）
+	Namespace   string `mapstructure:"namespace"`   // 键前缀
+	Environment string `mapstructure:"environment"` // 运行环境，与命名空间一起组成键前缀，使多个环境可以共用同一个Redis
+	DefaultTTL  int    `mapstructure:"default_ttl"` // 默认过期时间（秒）
+	MaxKeys     int    `mapstructure:"max_keys"`    // 进程内缓存的键数上限，超出时按LRU淘汰
+}
+
+// KeyPrefix 缓存键前缀，形如namespace:environment
+func (c CacheConfig) KeyPrefix() string {
+	if c.Environment == "" {
+		return c.Namespace
+	}
+	if c.Namespace == "" {
+		return c.Environment
+	}
+	return c.Namespace + ":" + c.Environment
 }
 
 var AppConfig Config
@@ -111,4 +183,34 @@ func setDefaults() {
 	viper.SetDefault("neo4j.password", "password")
 
 	viper.SetDefault("eino.model", "gpt-3.5-turbo")
+	viper.SetDefault("eino.base_url", "https://api.openai.com/v1")
+	viper.SetDefault("eino.embedding_model", "text-embedding-ada-002")
+	viper.SetDefault("eino.timeout", "60s")
+
+	viper.SetDefault("search.graph_centrality_boost", 0.5)
+	viper.SetDefault("search.rerank.provider", "lexical")
+	viper.SetDefault("search.rerank.protocol", "cohere")
+	viper.SetDefault("search.rerank.top_n", 50)
+	viper.SetDefault("search.rerank.budget", "800ms")
+	viper.SetDefault("search.planner.enabled", true)
+	viper.SetDefault("search.planner.budget", "300ms")
+	viper.SetDefault("search.cache.enabled", true)
+	viper.SetDefault("search.cache.ttl", "30m")
+	viper.SetDefault("search.cache.semantic_threshold", 0.95)
+	viper.SetDefault("search.cache.semantic_entries", 256)
+
+	viper.SetDefault("cache.driver", "redis")
+	viper.SetDefault("cache.local_ttl", 60)
+	viper.SetDefault("cache.namespace", "ino")
+	viper.SetDefault("cache.environment", "dev")
+	viper.SetDefault("cache.default_ttl", 1800)
+	viper.SetDefault("cache.max_keys", 10000)
+
+	viper.SetDefault("jobs.graph_analytics_interval", "6h")
+	viper.SetDefault("jobs.community_summary_interval", "24h")
+	viper.SetDefault("jobs.memory_distill_interval", "1m")
+	viper.SetDefault("jobs.memory_consolidate_interval", "24h")
+	viper.SetDefault("jobs.quality_prior_interval", "15m")
+	viper.SetDefault("jobs.correction_interval", "10m")
+	viper.SetDefault("jobs.suggestion_interval", "1h")
 }
@@ -57,12 +57,15 @@ CREATE TABLE conversations (
     content JSON NOT NULL,
     tags JSON,
     processed_at TIMESTAMP NULL,
+    distill_attempts INT NOT NULL DEFAULT 0,
+    version BIGINT NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (domain_id) REFERENCES domains(id),
     FOREIGN KEY (user_id) REFERENCES users(id),
     INDEX idx_conversation_id (conversation_id),
     INDEX idx_domain_id (domain_id),
-    INDEX idx_user_id (user_id)
+    INDEX idx_user_id (user_id),
+    INDEX idx_unprocessed (processed_at, distill_attempts)
 );
 
 -- 反馈表
@@ -75,10 +78,12 @@ CREATE TABLE feedback (
     comment TEXT,
     context JSON,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id),
     INDEX idx_query_id (query_id),
     INDEX idx_user_id (user_id),
-    INDEX idx_feedback_type (feedback_type)
+    INDEX idx_feedback_type (feedback_type),
+    INDEX idx_updated_at (updated_at, id)
 );
 
 -- 检索记录表
@@ -96,9 +101,116 @@ CREATE TABLE search_logs (
     FOREIGN KEY (domain_id) REFERENCES domains(id),
     INDEX idx_query_id (query_id),
     INDEX idx_user_id (user_id),
+    INDEX idx_domain_id (domain_id),
+    INDEX idx_created_at (created_at)
+);
+
+-- 社区摘要表
+CREATE TABLE community_summaries (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    domain_id BIGINT NOT NULL,
+    community_id VARCHAR(64) NOT NULL,
+    title VARCHAR(500),
+    summary TEXT,
+    entity_ids JSON,
+    size INT DEFAULT 0,
+    fingerprint VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (domain_id) REFERENCES domains(id),
+    UNIQUE INDEX idx_domain_community (domain_id, community_id)
+);
+
+CREATE TABLE memories (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    memory_id VARCHAR(64) UNIQUE NOT NULL,
+    user_id BIGINT NOT NULL,
+    domain_id BIGINT NOT NULL,
+    conversation_id VARCHAR(64),
+    memory_type VARCHAR(50) NOT NULL,
+    content TEXT NOT NULL,
+    importance_score FLOAT DEFAULT 0,
+    entity_ids JSON,
+    source_message_ids JSON,
+    access_count INT DEFAULT 0,
+    last_accessed TIMESTAMP NULL,
+    superseded_by VARCHAR(64),
+    archived_at TIMESTAMP NULL,
+    pinned BOOLEAN DEFAULT FALSE,
+    decayed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (domain_id) REFERENCES domains(id),
+    INDEX idx_user_domain (user_id, domain_id),
+    INDEX idx_conversation_id (conversation_id),
+    INDEX idx_archived_at (archived_at)
+);
+
+-- 记忆审计日志表
+CREATE TABLE memory_audit_logs (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(255) NOT NULL,
+    actor VARCHAR(100),
+    detail JSON,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user_created (user_id, created_at)
+);
+
+-- 反馈学习的质量先验表
+CREATE TABLE quality_priors (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    score FLOAT DEFAULT 0,
+    positive FLOAT DEFAULT 0,
+    negative FLOAT DEFAULT 0,
+    voters INT DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_target (target_type, target_id)
+);
+
+-- 学习到的纠正表
+CREATE TABLE corrections (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    correction_id VARCHAR(64) UNIQUE NOT NULL,
+    domain_id BIGINT DEFAULT 0,
+    user_id BIGINT DEFAULT 0,
+    query_id VARCHAR(64),
+    query TEXT NOT NULL,
+    answer TEXT,
+    content TEXT NOT NULL,
+    chunk_ids JSON,
+    source_type VARCHAR(32) NOT NULL,
+    source_ref VARCHAR(191) UNIQUE NOT NULL,
+    confidence FLOAT DEFAULT 0,
+    confirmations INT DEFAULT 1,
+    duplicate_of VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_domain_id (domain_id)
 );
 
+-- 查询建议表
+CREATE TABLE query_suggestions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    domain_id BIGINT DEFAULT 0,
+    query VARCHAR(500) NOT NULL,
+    suggestion VARCHAR(500) NOT NULL,
+    score FLOAT DEFAULT 0,
+    source VARCHAR(16) NOT NULL DEFAULT 'auto',
+    method VARCHAR(32),
+    support INT DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_query_suggestion (domain_id, query(191), suggestion(191)),
+    INDEX idx_suggestion (suggestion(191))
+);
+
 -- 插入默认数据
 INSERT INTO domains (domain_name, description) VALUES 
 ('general', '通用知识域'),
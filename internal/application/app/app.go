@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/xyzbit/ino/config"
+	"github.com/xyzbit/ino/internal/application/worker"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/domain/services"
+	"github.com/xyzbit/ino/internal/infra/llm"
+	"github.com/xyzbit/ino/internal/infra/milvus"
+	"github.com/xyzbit/ino/internal/infra/mysql"
+	"github.com/xyzbit/ino/internal/infra/redis"
+	"github.com/xyzbit/ino/internal/infra/rerank"
+	memoryrepo "github.com/xyzbit/ino/internal/repo/memory"
+	milvusrepo "github.com/xyzbit/ino/internal/repo/milvus"
+	mysqlrepo "github.com/xyzbit/ino/internal/repo/mysql"
+	redisrepo "github.com/xyzbit/ino/internal/repo/redis"
+)
+
+// 应用依赖，在基础设施初始化之后由Init构建
+var (
+	Repo     *repository.Repository
+	LLM      services.ChatModel
+	Embedder services.Embedder
+
+	ConversationCollector *services.ConversationCollector
+	FeedbackCollector     *services.FeedbackCollector
+	MemoryDistiller       *services.MemoryDistiller
+	MemorySearcher        *services.MemorySearcher
+	MemoryConsolidator    *services.MemoryConsolidator
+	MemoryManager         *services.MemoryManager
+
+	GraphAnalyzer       *services.GraphAnalyzer
+	GraphWriter         *services.GraphWriter
+	GraphTransfer       *services.GraphTransfer
+	CommunitySummarizer *services.CommunitySummarizer
+	QualityPriors       *services.QualityPriors
+	CorrectionLearner   *services.CorrectionLearner
+	Searcher            *services.Searcher
+	ContextAssembler    *services.ContextAssembler
+	SearchCache         *services.SearchCache
+	QuerySuggester      *services.QuerySuggester
+)
+
+// Init 初始化仓储和领域服务
+func Init() {
+	cfg := config.AppConfig
+
+	Repo = mysqlrepo.NewRepository(mysql.DB)
+	Repo.Vector, _ = milvusrepo.NewVectorRepository(milvus.Client)
+	Repo.Cache = newCache(cfg.Cache)
+	if Repo.Graph == nil {
+		log.Printf("Warning: graph repository is not configured, graph features are disabled")
+	}
+
+	// 未配置时保持接口为nil，由服务返回不可用错误
+	if llm.DefaultClient != nil {
+		LLM = llm.DefaultClient
+		Embedder = llm.DefaultClient
+	}
+
+	if cfg.Search.Cache.Enabled {
+		cacheOpts := services.DefaultSearchCacheOptions()
+		if cfg.Search.Cache.TTL > 0 {
+			cacheOpts.TTL = cfg.Search.Cache.TTL
+		}
+		cacheOpts.SemanticThreshold = cfg.Search.Cache.SemanticThreshold
+		if cfg.Search.Cache.SemanticEntries > 0 {
+			cacheOpts.SemanticEntries = cfg.Search.Cache.SemanticEntries
+		}
+		SearchCache = services.NewSearchCache(Repo.Cache, Embedder, cacheOpts)
+		Repo.Document = SearchCache.WrapDocuments(Repo.Document)
+	}
+
+	ConversationCollector = services.NewConversationCollector(Repo.Conversation, Repo.Domain, Repo.Feedback)
+	FeedbackCollector = services.NewFeedbackCollector(Repo.Feedback, Repo.SearchLog, Repo.User)
+
+	GraphAnalyzer = services.NewGraphAnalyzer(Repo.Graph, services.DefaultGraphAnalyticsOptions())
+	GraphWriter = services.NewGraphWriter(Repo.Graph, Repo.Domain)
+	GraphTransfer = services.NewGraphTransfer(Repo.Graph, Repo.Domain)
+	CorrectionLearner = services.NewCorrectionLearner(Repo.Correction, Repo.Feedback, Repo.SearchLog, Repo.Conversation, Repo.Vector, Repo.Cache, LLM, Embedder, services.DefaultCorrectionLearnerOptions())
+	MemoryDistiller = services.NewMemoryDistiller(Repo.Conversation, Repo.Memory, Repo.Vector, GraphWriter, CorrectionLearner, LLM, Embedder, services.DefaultMemoryDistillerOptions())
+	MemorySearcher = services.NewMemorySearcher(Repo.Memory, Repo.Vector, Embedder, services.DefaultMemorySearchOptions())
+	MemoryManager = services.NewMemoryManager(Repo.User, Repo.Memory, Repo.Conversation, Repo.MemoryAudit, Repo.Vector, Repo.Graph, Embedder, MemorySearcher, services.DefaultMemoryManagerOptions())
+	MemoryConsolidator = services.NewMemoryConsolidator(Repo.Memory, Repo.MemoryAudit, Repo.Vector, LLM, Embedder, services.DefaultMemoryConsolidatorOptions())
+	CommunitySummarizer = services.NewCommunitySummarizer(GraphAnalyzer, Repo.Community, Repo.Vector, LLM, Embedder, services.DefaultCommunitySummaryOptions())
+
+	retrievers := map[string]services.Retriever{
+		"vector": services.NewVectorRetriever(Repo.Vector, Repo.DocumentChunk, Embedder),
+	}
+	if Repo.Graph != nil {
+		retrievers["graph"] = services.NewGraphRetriever(Repo.Graph, cfg.Search.GraphCentralityBoost)
+	}
+	global := services.NewGlobalSearcher(Repo.Community, Repo.Vector, LLM, Embedder, services.DefaultGlobalSearchOptions())
+	QualityPriors = services.NewQualityPriors(Repo.Feedback, Repo.SearchLog, Repo.DocumentChunk, Repo.QualityPrior, services.DefaultQualityPriorOptions())
+	rerankOpts := services.DefaultRerankOptions()
+	if cfg.Search.Rerank.TopN > 0 {
+		rerankOpts.TopN = cfg.Search.Rerank.TopN
+	}
+	if cfg.Search.Rerank.Budget > 0 {
+		rerankOpts.Budget = cfg.Search.Rerank.Budget
+	}
+	rerankStage := services.NewRerankStage(newReranker(cfg.Search.Rerank.Provider), rerankOpts)
+	var planner *services.QueryPlanner
+	if cfg.Search.Planner.Enabled {
+		names := make([]string, 0, len(retrievers))
+		for name := range retrievers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		plannerOpts := services.DefaultQueryPlannerOptions()
+		if cfg.Search.Planner.Budget > 0 {
+			plannerOpts.Budget = cfg.Search.Planner.Budget
+		}
+		planner = services.NewQueryPlanner(Repo.Conversation, LLM, names, plannerOpts)
+	}
+	highlighter := services.NewHighlighter(Embedder, services.DefaultHighlightOptions())
+	faceter := services.NewFaceter(Repo.Document, Repo.Domain, services.DefaultFacetOptions())
+	QuerySuggester = services.NewQuerySuggester(Repo.Suggestion, Repo.SearchLog, Embedder, services.DefaultQuerySuggesterOptions())
+	Searcher = services.NewSearcher(retrievers, planner, global, QualityPriors, rerankStage, CorrectionLearner, highlighter, faceter, QuerySuggester, SearchCache)
+	ContextAssembler = services.NewContextAssembler(Searcher, MemorySearcher, Repo.Graph, services.EstimateTokenizer{}, services.DefaultContextAssemblerOptions())
+}
+
+// newCache 按配置选择缓存实现，Redis不可用时退回进程内缓存
+func newCache(cfg config.CacheConfig) repository.CacheRepository {
+	if cfg.Driver != "memory" && !redis.Available() {
+		log.Printf("Warning: Redis is not available, using in-process cache")
+		return memoryrepo.NewCacheRepository(cfg.MaxKeys)
+	}
+	switch cfg.Driver {
+	case "memory":
+		return memoryrepo.NewCacheRepository(cfg.MaxKeys)
+	case "tiered":
+		remote := redisrepo.NewCacheRepository(redis.Redis, cfg.KeyPrefix())
+		return memoryrepo.NewTieredCacheRepository(memoryrepo.NewCacheRepository(cfg.MaxKeys), remote, cfg.LocalTTL)
+	default:
+		return redisrepo.NewCacheRepository(redis.Redis, cfg.KeyPrefix())
+	}
+}
+
+// newReranker 按配置选择重排序器，所选重排序器不可用时退回词重叠重排序
+func newReranker(provider string) services.Reranker {
+	switch provider {
+	case services.RerankerCrossEncoder:
+		if rerank.DefaultClient != nil {
+			return rerank.DefaultClient
+		}
+	case services.RerankerLLM:
+		if LLM != nil {
+			return services.NewLLMReranker(LLM)
+		}
+	case services.RerankerLexical:
+		return services.NewLexicalReranker()
+	}
+	log.Printf("Warning: reranker %q is unavailable, falling back to lexical reranking", provider)
+	return services.NewLexicalReranker()
+}
+
+// Jobs 返回需要后台调度的任务
+func Jobs() []worker.Job {
+	cfg := config.AppConfig.Jobs
+
+	return []worker.Job{
+		{
+			Name:     "graph-analytics",
+			Interval: cfg.GraphAnalyticsInterval,
+			Run: func(ctx context.Context) error {
+				if Repo.Graph == nil {
+					return nil
+				}
+				return forEachDomain(ctx, func(domain *models.Domain) error {
+					_, err := GraphAnalyzer.Run(ctx, domain.ID)
+					return err
+				})
+			},
+		},
+		{
+			Name:     "community-summary",
+			Interval: cfg.CommunitySummaryInterval,
+			Run: func(ctx context.Context) error {
+				if Repo.Graph == nil || LLM == nil {
+					return nil
+				}
+				return forEachDomain(ctx, func(domain *models.Domain) error {
+					_, err := CommunitySummarizer.Summarize(ctx, domain.ID)
+					return err
+				})
+			},
+		},
+		{
+			Name:     "memory-distill",
+			Interval: cfg.MemoryDistillInterval,
+			Run: func(ctx context.Context) error {
+				if LLM == nil || Embedder == nil {
+					return nil
+				}
+				processed, err := MemoryDistiller.ProcessPending(ctx)
+				if processed > 0 {
+					log.Printf("Distilled memories from %d conversations", processed)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "quality-priors",
+			Interval: cfg.QualityPriorInterval,
+			Run: func(ctx context.Context) error {
+				result, err := QualityPriors.Learn(ctx)
+				if err != nil {
+					return err
+				}
+				log.Printf("Learned %d quality priors from %d feedback", result.Priors, result.FeedbackProcessed)
+				return nil
+			},
+		},
+		{
+			Name:     "corrections",
+			Interval: cfg.CorrectionInterval,
+			Run: func(ctx context.Context) error {
+				if LLM == nil || Embedder == nil {
+					return nil
+				}
+				result, err := CorrectionLearner.LearnFromFeedback(ctx)
+				if err != nil {
+					return err
+				}
+				if len(result.Created)+result.Confirmed+result.Rejected > 0 {
+					log.Printf("Learned %d corrections from feedback, confirmed %d, rejected %d", len(result.Created), result.Confirmed, result.Rejected)
+				}
+				return nil
+			},
+		},
+		{
+			Name:     "query-suggestions",
+			Interval: cfg.SuggestionInterval,
+			Run: func(ctx context.Context) error {
+				result, err := QuerySuggester.Mine(ctx)
+				if err != nil {
+					return err
+				}
+				log.Printf("Mined query suggestions from %d search logs in %d sessions: %d co-occurrences, %d reformulations, %d clustered",
+					result.LogsScanned, result.Sessions, result.CoOccurrences, result.Reformulations, result.Clustered)
+				return nil
+			},
+		},
+		{
+			Name:     "memory-consolidate",
+			Interval: cfg.MemoryConsolidateInterval,
+			Run: func(ctx context.Context) error {
+				return forEachDomain(ctx, func(domain *models.Domain) error {
+					result, err := MemoryConsolidator.Consolidate(ctx, domain)
+					if err != nil {
+						return err
+					}
+					if result.Merged+result.Superseded+result.Archived+result.Deleted > 0 {
+						log.Printf("Consolidated memories of domain %s: merged %d, superseded %d, archived %d, deleted %d",
+							domain.DomainName, result.Merged, result.Superseded, result.Archived, result.Deleted)
+					}
+					return nil
+				})
+			},
+		},
+	}
+}
+
+// forEachDomain 遍历所有知识域，单个知识域失败不影响其他知识域
+func forEachDomain(ctx context.Context, fn func(domain *models.Domain) error) error {
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		domains, err := Repo.Domain.List(ctx, offset, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, domain := range domains {
+			if err := fn(domain); err != nil {
+				log.Printf("Warning: domain %s: %v", domain.DomainName, err)
+			}
+		}
+		if len(domains) < pageSize {
+			return nil
+		}
+	}
+}
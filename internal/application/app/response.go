@@ -0,0 +1,24 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Success 返回成功响应
+func Success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    http.StatusOK,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// Error 返回错误响应
+func Error(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
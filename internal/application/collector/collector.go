@@ -1,9 +1,13 @@
 package collector
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
 )
 
 // uploadDocument 上传文档
@@ -14,18 +18,46 @@ func UploadDocument(c *gin.Context) {
 	})
 }
 
-// collectConversation 收集对话
+// CollectConversation 收集对话，按conversation_id幂等写入，重复提交时追加新的轮次；消息可附带反馈
 func CollectConversation(c *gin.Context) {
-	// TODO: 实现对话收集逻辑
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Conversation collection endpoint - TODO",
-	})
+	var req models.CollectConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := app.ConversationCollector.Collect(c.Request.Context(), &req)
+	switch {
+	case errors.Is(err, services.ErrInvalidFeedbackType):
+		app.Error(c, http.StatusBadRequest, err)
+	case errors.Is(err, services.ErrDomainNotFound):
+		app.Error(c, http.StatusNotFound, err)
+	case errors.Is(err, services.ErrConversationConflict):
+		app.Error(c, http.StatusConflict, err)
+	case err != nil:
+		app.Error(c, http.StatusInternalServerError, err)
+	default:
+		app.Success(c, result)
+	}
 }
 
-// collectFeedback 收集反馈
+// CollectFeedback 收集检索反馈，query_id必须是已记录的检索
 func CollectFeedback(c *gin.Context) {
-	// TODO: 实现反馈收集逻辑
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Feedback collection endpoint - TODO",
-	})
+	var req models.CollectFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := app.FeedbackCollector.Collect(c.Request.Context(), &req)
+	switch {
+	case errors.Is(err, services.ErrInvalidFeedbackType):
+		app.Error(c, http.StatusBadRequest, err)
+	case errors.Is(err, services.ErrSearchLogNotFound), errors.Is(err, services.ErrUserNotFound):
+		app.Error(c, http.StatusNotFound, err)
+	case err != nil:
+		app.Error(c, http.StatusInternalServerError, err)
+	default:
+		app.Success(c, result)
+	}
 }
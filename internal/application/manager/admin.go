@@ -1,9 +1,14 @@
 package manager
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
 )
 
 // GetStats 获取统计信息
@@ -29,3 +34,70 @@ func CreateUser(c *gin.Context) {
 		"message": "Create user endpoint - TODO",
 	})
 }
+
+// GetFeedbackStats 获取反馈统计
+func GetFeedbackStats(c *gin.Context) {
+	stats, err := app.Repo.Feedback.GetStats(c.Request.Context())
+	if err != nil {
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	app.Success(c, stats)
+}
+
+// GetSearchStats 获取检索统计。时间范围由start、end（RFC3339）指定；
+// 未指定start时统计最近days天（默认7天），days为0表示不限
+func GetSearchStats(c *gin.Context) {
+	window, err := statsWindow(c)
+	if err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	stats, err := app.Repo.SearchLog.GetStats(c.Request.Context(), window)
+	if err != nil {
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	app.Success(c, stats)
+}
+
+// statsWindow 解析统计的时间范围参数
+func statsWindow(c *gin.Context) (models.TimeRange, error) {
+	var (
+		window models.TimeRange
+		err    error
+	)
+	if window.Start, err = timeParam(c, "start"); err != nil {
+		return window, err
+	}
+	if window.End, err = timeParam(c, "end"); err != nil {
+		return window, err
+	}
+	if window.Start == nil {
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days < 0 {
+			return window, fmt.Errorf("invalid days: %s", c.Query("days"))
+		}
+		if days > 0 {
+			start := time.Now().AddDate(0, 0, -days)
+			window.Start = &start
+		}
+	}
+	if window.Start != nil && window.End != nil && !window.End.After(*window.Start) {
+		return window, fmt.Errorf("end must be after start")
+	}
+	return window, nil
+}
+
+// timeParam 解析RFC3339格式的时间参数，参数为空时返回nil
+func timeParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return &t, nil
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+// GetGraphAnalytics 计算知识域图统计、中心实体和社区，不写回实体
+func GetGraphAnalytics(c *gin.Context) {
+	domainID, err := strconv.ParseUint(c.Query("domain_id"), 10, 64)
+	if err != nil {
+		app.Error(c, http.StatusBadRequest, errors.New("invalid domain_id"))
+		return
+	}
+
+	result, err := app.GraphAnalyzer.Analyze(c.Request.Context(), domainID)
+	if err != nil {
+		graphError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// RunGraphAnalytics 执行图分析并将分数写回实体属性
+func RunGraphAnalytics(c *gin.Context) {
+	var req models.GraphAnalyticsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := app.GraphAnalyzer.Run(c.Request.Context(), req.DomainID)
+	if err != nil {
+		graphError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// SummarizeCommunities 为知识域的图谱社区生成摘要
+func SummarizeCommunities(c *gin.Context) {
+	var req models.SummarizeCommunitiesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := app.CommunitySummarizer.Summarize(c.Request.Context(), req.DomainID)
+	if err != nil {
+		graphError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// ListCommunitySummaries 获取知识域的社区摘要列表
+func ListCommunitySummaries(c *gin.Context) {
+	domainID, err := strconv.ParseUint(c.Query("domain_id"), 10, 64)
+	if err != nil {
+		app.Error(c, http.StatusBadRequest, errors.New("invalid domain_id"))
+		return
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	ctx := c.Request.Context()
+	summaries, err := app.Repo.Community.ListByDomain(ctx, domainID, offset, limit)
+	if err != nil {
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	total, err := app.Repo.Community.CountByDomain(ctx, domainID)
+	if err != nil {
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	app.Success(c, gin.H{
+		"items": summaries,
+		"total": total,
+	})
+}
+
+// GetRelationHistory 获取实体间关系的全部时间版本
+func GetRelationHistory(c *gin.Context) {
+	from := c.Query("from")
+	if from == "" {
+		app.Error(c, http.StatusBadRequest, errors.New("from is required"))
+		return
+	}
+
+	relations, err := app.GraphWriter.RelationHistory(c.Request.Context(), from, c.Query("to"), c.Query("type"))
+	if err != nil {
+		graphError(c, err)
+		return
+	}
+	app.Success(c, relations)
+}
+
+// TraverseGraph 图遍历，支持按as_of时间点查询历史状态，深度受知识域配置限制
+func TraverseGraph(c *gin.Context) {
+	var req models.GraphTraversal
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.StartEntity == "" {
+		app.Error(c, http.StatusBadRequest, errors.New("start_entity is required"))
+		return
+	}
+
+	result, err := services.TraverseGraph(c.Request.Context(), app.Repo.Graph, app.Repo.Domain, &req)
+	if err != nil {
+		graphError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// graphError 图相关错误响应
+func graphError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrGraphUnavailable) ||
+		errors.Is(err, services.ErrLLMUnavailable) ||
+		errors.Is(err, services.ErrEmbedderUnavailable) {
+		app.Error(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	if errors.Is(err, services.ErrSchemaViolation) {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, services.ErrEntityNotFound) || errors.Is(err, services.ErrDomainNotFound) {
+		app.Error(c, http.StatusNotFound, err)
+		return
+	}
+	app.Error(c, http.StatusInternalServerError, err)
+}
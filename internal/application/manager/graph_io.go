@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+// graphContentTypes 导出格式对应的响应类型
+var graphContentTypes = map[string]string{
+	models.GraphFormatJSONLD:  "application/ld+json",
+	models.GraphFormatGraphML: "application/graphml+xml",
+	models.GraphFormatCSV:     "application/zip",
+}
+
+// graphFileExtensions 导出格式对应的文件扩展名
+var graphFileExtensions = map[string]string{
+	models.GraphFormatJSONLD:  "jsonld",
+	models.GraphFormatGraphML: "graphml",
+	models.GraphFormatCSV:     "zip",
+}
+
+// ExportGraph 导出知识域图谱，csv格式返回包含nodes.csv和edges.csv的zip
+func ExportGraph(c *gin.Context) {
+	domainID, err := strconv.ParseUint(c.Query("domain_id"), 10, 64)
+	if err != nil {
+		app.Error(c, http.StatusBadRequest, errors.New("invalid domain_id"))
+		return
+	}
+	format := c.DefaultQuery("format", models.GraphFormatJSONLD)
+	contentType, ok := graphContentTypes[format]
+	if !ok {
+		app.Error(c, http.StatusBadRequest, fmt.Errorf("%w: %s", services.ErrUnsupportedFormat, format))
+		return
+	}
+	// 开始写响应后无法再返回错误响应，先检查依赖
+	if app.Repo.Graph == nil {
+		graphError(c, services.ErrGraphUnavailable)
+		return
+	}
+
+	filename := fmt.Sprintf("graph_domain_%d.%s", domainID, graphFileExtensions[format])
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	ctx := c.Request.Context()
+	if format == models.GraphFormatCSV {
+		zw := zip.NewWriter(c.Writer)
+		err = app.GraphTransfer.ExportCSV(ctx, domainID, zw.Create)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+	} else {
+		err = app.GraphTransfer.Export(ctx, domainID, format, c.Writer)
+	}
+	if err != nil {
+		// 响应已部分写出，只能中断连接
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// ImportGraph 导入图谱到知识域。jsonld/graphml格式读取表单文件file，
+// csv格式读取表单文件nodes和edges；dry_run=true时只校验不写入。
+func ImportGraph(c *gin.Context) {
+	domainID, err := strconv.ParseUint(c.Query("domain_id"), 10, 64)
+	if err != nil {
+		app.Error(c, http.StatusBadRequest, errors.New("invalid domain_id"))
+		return
+	}
+	format := c.DefaultQuery("format", models.GraphFormatJSONLD)
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	opts := services.GraphImportOptions{DryRun: dryRun}
+
+	ctx := c.Request.Context()
+	var result *models.GraphImportResult
+	if format == models.GraphFormatCSV {
+		nodes, err := openFormFile(c, "nodes")
+		if err != nil {
+			app.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		if nodes == nil {
+			app.Error(c, http.StatusBadRequest, errors.New("nodes file is required"))
+			return
+		}
+		defer nodes.Close()
+
+		var edges io.Reader
+		edgesFile, err := openFormFile(c, "edges")
+		if err != nil {
+			app.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		if edgesFile != nil {
+			defer edgesFile.Close()
+			edges = edgesFile
+		}
+		result, err = app.GraphTransfer.ImportCSV(ctx, domainID, nodes, edges, opts)
+		if err != nil {
+			graphImportError(c, err)
+			return
+		}
+	} else {
+		file, err := openFormFile(c, "file")
+		if err != nil {
+			app.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		if file == nil {
+			app.Error(c, http.StatusBadRequest, errors.New("file is required"))
+			return
+		}
+		defer file.Close()
+		result, err = app.GraphTransfer.Import(ctx, domainID, format, file, opts)
+		if err != nil {
+			graphImportError(c, err)
+			return
+		}
+	}
+	app.Success(c, result)
+}
+
+// openFormFile 打开上传的表单文件，文件缺失时返回nil
+func openFormFile(c *gin.Context, name string) (multipart.File, error) {
+	header, err := c.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return header.Open()
+}
+
+// graphImportError 导入错误响应，格式和文件错误返回400
+func graphImportError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnsupportedFormat) ||
+		errors.Is(err, services.ErrInvalidGraphFile) ||
+		errors.Is(err, services.ErrDomainRequired) {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	graphError(c, err)
+}
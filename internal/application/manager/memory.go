@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+// ListUserMemories 获取用户的记忆列表
+func ListUserMemories(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	domainID, _ := strconv.ParseUint(c.Query("domain_id"), 10, 64)
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	result, err := app.MemoryManager.List(c.Request.Context(), userID, domainID, offset, limit)
+	if err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// SearchUserMemories 检索用户的记忆
+func SearchUserMemories(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	// 用户取自路径，预先填入以通过必填校验，并覆盖请求体中的user_id
+	req := models.MemoryQueryRequest{UserID: userID}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	req.UserID = userID
+
+	result, err := app.MemoryManager.Search(c.Request.Context(), &req)
+	if err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// UpdateUserMemory 编辑用户的记忆
+func UpdateUserMemory(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateMemoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	memory, err := app.MemoryManager.Update(c.Request.Context(), userID, c.Param("memory_id"), &req)
+	if err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, memory)
+}
+
+// PinUserMemory 固定用户的记忆
+func PinUserMemory(c *gin.Context) {
+	setMemoryPinned(c, true)
+}
+
+// UnpinUserMemory 取消固定用户的记忆
+func UnpinUserMemory(c *gin.Context) {
+	setMemoryPinned(c, false)
+}
+
+func setMemoryPinned(c *gin.Context, pinned bool) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	memory, err := app.MemoryManager.SetPinned(c.Request.Context(), userID, c.Param("memory_id"), pinned)
+	if err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, memory)
+}
+
+// DeleteUserMemory 删除用户的记忆
+func DeleteUserMemory(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	if err := app.MemoryManager.Delete(c.Request.Context(), userID, c.Param("memory_id")); err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, gin.H{"memory_id": c.Param("memory_id")})
+}
+
+// ForgetUserTopic 遗忘用户关于某个主题的记忆、派生的图谱事实和来源对话消息
+func ForgetUserTopic(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var req models.ForgetTopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := app.MemoryManager.ForgetTopic(c.Request.Context(), userID, &req)
+	if err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// ListUserMemoryAudit 获取用户记忆的删除审计日志
+func ListUserMemoryAudit(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	logs, total, err := app.MemoryManager.AuditLog(c.Request.Context(), userID, offset, limit)
+	if err != nil {
+		memoryError(c, err)
+		return
+	}
+	app.Success(c, gin.H{
+		"items": logs,
+		"total": total,
+	})
+}
+
+// userIDParam 解析路径中的用户ID，无效时写入错误响应
+func userIDParam(c *gin.Context) (uint64, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || userID == 0 {
+		app.Error(c, http.StatusBadRequest, errors.New("invalid user id"))
+		return 0, false
+	}
+	return userID, true
+}
+
+// memoryError 记忆管理错误响应
+func memoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrMemoryNotFound):
+		app.Error(c, http.StatusNotFound, err)
+	case errors.Is(err, services.ErrInvalidMemoryRequest), errors.Is(err, services.ErrInvalidMemoryType):
+		app.Error(c, http.StatusBadRequest, err)
+	case errors.Is(err, services.ErrEmbedderUnavailable):
+		app.Error(c, http.StatusServiceUnavailable, err)
+	default:
+		app.Error(c, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+// ListSuggestions 获取查询建议列表，可按知识域、查询前缀、来源和状态过滤
+func ListSuggestions(c *gin.Context) {
+	var filter models.SuggestionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	result, err := app.QuerySuggester.List(c.Request.Context(), filter)
+	if err != nil {
+		suggestionError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// CreateSuggestion 人工添加查询建议
+func CreateSuggestion(c *gin.Context) {
+	var req models.CreateSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	suggestion, err := app.QuerySuggester.Create(c.Request.Context(), &req)
+	if err != nil {
+		suggestionError(c, err)
+		return
+	}
+	app.Success(c, suggestion)
+}
+
+// UpdateSuggestion 编辑查询建议的内容、分数或状态
+func UpdateSuggestion(c *gin.Context) {
+	id, ok := suggestionIDParam(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	suggestion, err := app.QuerySuggester.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		suggestionError(c, err)
+		return
+	}
+	app.Success(c, suggestion)
+}
+
+// DeleteSuggestion 删除查询建议
+func DeleteSuggestion(c *gin.Context) {
+	id, ok := suggestionIDParam(c)
+	if !ok {
+		return
+	}
+	if err := app.QuerySuggester.Delete(c.Request.Context(), id); err != nil {
+		suggestionError(c, err)
+		return
+	}
+	app.Success(c, gin.H{"id": id})
+}
+
+// MineSuggestions 立即从检索日志挖掘查询建议
+func MineSuggestions(c *gin.Context) {
+	result, err := app.QuerySuggester.Mine(c.Request.Context())
+	if err != nil {
+		suggestionError(c, err)
+		return
+	}
+	app.Success(c, result)
+}
+
+// suggestionIDParam 解析路径中的查询建议ID，无效时写入错误响应
+func suggestionIDParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		app.Error(c, http.StatusBadRequest, errors.New("invalid suggestion id"))
+		return 0, false
+	}
+	return id, true
+}
+
+// suggestionError 查询建议管理错误响应
+func suggestionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSuggestionNotFound):
+		app.Error(c, http.StatusNotFound, err)
+	case errors.Is(err, services.ErrInvalidSuggestion):
+		app.Error(c, http.StatusBadRequest, err)
+	default:
+		app.Error(c, http.StatusInternalServerError, err)
+	}
+}
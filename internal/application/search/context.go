@@ -0,0 +1,37 @@
+package search
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+// AssembleContext 检索并组装按token预算截断、带编号引用的知识上下文，供大模型应用直接注入Prompt
+func AssembleContext(c *gin.Context) {
+	var req models.ContextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.MaxTokens < 0 {
+		app.Error(c, http.StatusBadRequest, errors.New("max_tokens must not be negative"))
+		return
+	}
+
+	resp, err := app.ContextAssembler.Assemble(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrDomainRequired) || errors.Is(err, services.ErrInvalidFacet) {
+			app.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	searchReq := &models.SearchRequest{Query: req.Query, DomainID: req.DomainID, UserID: req.UserID, Filters: req.Filters, Context: req.Context}
+	recordSearch(c.Request.Context(), searchReq, resp.Search)
+	app.Success(c, resp)
+}
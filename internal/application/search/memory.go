@@ -0,0 +1,39 @@
+package search
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+// QueryMemory 查询用户记忆，按相关度、时间衰减和重要度综合排序
+func QueryMemory(c *gin.Context) {
+	var req models.MemoryQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.TimeRange != nil && req.TimeRange.Start != nil && req.TimeRange.End != nil &&
+		req.TimeRange.End.Before(*req.TimeRange.Start) {
+		app.Error(c, http.StatusBadRequest, errors.New("time_range end is before start"))
+		return
+	}
+
+	resp, err := app.MemorySearcher.Search(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMemoryType):
+			app.Error(c, http.StatusBadRequest, err)
+		case errors.Is(err, services.ErrEmbedderUnavailable):
+			app.Error(c, http.StatusServiceUnavailable, err)
+		default:
+			app.Error(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	app.Success(c, resp)
+}
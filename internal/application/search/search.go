@@ -1,14 +1,70 @@
 package search
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
 )
 
+// SearchKnowledge 知识检索，支持local（分块和实体）与global（社区摘要map-reduce）两种模式
 func SearchKnowledge(c *gin.Context) {
-	// TODO: 实现知识搜索逻辑
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Search knowledge endpoint - TODO",
-	})
+	var req models.SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := app.Searcher.Search(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDomainRequired), errors.Is(err, services.ErrInvalidFacet):
+			app.Error(c, http.StatusBadRequest, err)
+			return
+		case errors.Is(err, services.ErrLLMUnavailable):
+			app.Error(c, http.StatusServiceUnavailable, err)
+			return
+		}
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	recordSearch(c.Request.Context(), &req, resp)
+	app.Success(c, resp)
+}
+
+// recordSearch 记录检索日志，供反馈引用和统计。结果只保存ID和分数，不保存内容
+func recordSearch(ctx context.Context, req *models.SearchRequest, resp *models.SearchResponse) {
+	results := make([]models.SearchResult, len(resp.Results))
+	for i, r := range resp.Results {
+		results[i] = models.SearchResult{ID: r.ID, Type: r.Type, Title: r.Title, Source: r.Source, Score: r.Score}
+	}
+	entry := &models.SearchLog{
+		QueryID:   resp.QueryID,
+		UserID:    req.UserID,
+		DomainID:  req.DomainID,
+		QueryText: req.Query,
+		SearchConfig: map[string]interface{}{
+			"options":      req.Options,
+			"filters":      req.Filters,
+			"context":      req.Context,
+			"plan":         resp.Plan,
+			"cache":        resp.Metadata["cache"],
+			"cached_query": resp.Metadata["cached_query"],
+			"cached_plan":  resp.Metadata["cached_plan"],
+		},
+		Results: models.SearchResults{
+			TotalHits:    resp.TotalHits,
+			ProcessingMS: resp.ProcessingMS,
+			Results:      results,
+		},
+		ResponseTime: resp.ProcessingMS,
+	}
+	if err := app.Repo.SearchLog.Create(ctx, entry); err != nil {
+		log.Printf("Warning: failed to record search %s: %v", resp.QueryID, err)
+	}
 }
@@ -0,0 +1,31 @@
+package search
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xyzbit/ino/internal/application/app"
+)
+
+// AutocompleteQuery 查询前缀自动补全，参数q为已输入的前缀，可选domain_id和limit
+func AutocompleteQuery(c *gin.Context) {
+	prefix := c.Query("q")
+	if prefix == "" {
+		app.Error(c, http.StatusBadRequest, errors.New("q is required"))
+		return
+	}
+	domainID, _ := strconv.ParseUint(c.Query("domain_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	completions, err := app.QuerySuggester.Autocomplete(c.Request.Context(), domainID, prefix, limit)
+	if err != nil {
+		app.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	app.Success(c, gin.H{
+		"prefix":      prefix,
+		"completions": completions,
+	})
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// CommunitySummary 知识图谱社区摘要，用于全局问题检索
+type CommunitySummary struct {
+	ID          uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	DomainID    uint64    `json:"domain_id" gorm:"index;not null"`
+	CommunityID string    `json:"community_id" gorm:"type:varchar(64);not null"`
+	Title       string    `json:"title" gorm:"size:500"`
+	Summary     string    `json:"summary" gorm:"type:text"`
+	EntityIDs   []string  `json:"entity_ids" gorm:"type:json;serializer:json"`
+	Size        int       `json:"size"`
+	Fingerprint string    `json:"fingerprint" gorm:"type:varchar(64)"` // 成员和关系指纹，未变化时复用摘要
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CommunitySummary) TableName() string {
+	return "community_summaries"
+}
+
+// VectorID 摘要在向量库中的ID
+func (s *CommunitySummary) VectorID() string {
+	return CommunitySummaryVectorID(s.DomainID, s.CommunityID)
+}
+
+// CommunitySummaryVectorID 社区摘要向量ID
+func CommunitySummaryVectorID(domainID uint64, communityID string) string {
+	return "community_" + strconv.FormatUint(domainID, 10) + "_" + communityID
+}
+
+// SummarizeCommunitiesRequest 生成社区摘要请求
+type SummarizeCommunitiesRequest struct {
+	DomainID uint64 `json:"domain_id" binding:"required"`
+}
+
+// SummarizeCommunitiesResult 生成社区摘要结果
+type SummarizeCommunitiesResult struct {
+	DomainID    uint64 `json:"domain_id"`
+	Communities int    `json:"communities"` // 满足规模要求的社区数
+	Generated   int    `json:"generated"`   // 新生成的摘要数
+	Reused      int    `json:"reused"`      // 复用的摘要数
+	Removed     int    `json:"removed"`     // 删除的过期摘要数
+}
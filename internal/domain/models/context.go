@@ -0,0 +1,47 @@
+package models
+
+// 上下文条目类型
+const (
+	ContextItemMemory   = "memory"   // 用户记忆
+	ContextItemTriple   = "triple"   // 图谱实体及其关系三元组
+	ContextItemDocument = "document" // 文档片段，相邻或重叠的分块已合并
+)
+
+// ContextRequest 上下文组装请求
+type ContextRequest struct {
+	Query     string                 `json:"query" binding:"required"`
+	DomainID  uint64                 `json:"domain_id"`
+	UserID    uint64                 `json:"user_id"`    // 非0时混入该用户的记忆
+	MaxTokens int                    `json:"max_tokens"` // token预算，为0时使用默认预算
+	Limit     int                    `json:"limit"`      // 检索候选数
+	Filters   map[string]interface{} `json:"filters"`
+	Context   SearchContext          `json:"context"`
+}
+
+// ContextCitation 上下文中编号引用对应的来源
+type ContextCitation struct {
+	Index     int      `json:"index"` // 上下文中的编号，从1开始
+	Type      string   `json:"type"`  // memory, triple, document
+	IDs       []string `json:"ids"`   // 分块、记忆或实体ID，合并的分块有多个
+	Title     string   `json:"title"`
+	Source    string   `json:"source"`
+	Score     float64  `json:"score"`
+	StartPos  int      `json:"start_pos,omitempty"` // 文档片段在原文中的起止位置
+	EndPos    int      `json:"end_pos,omitempty"`
+	Tokens    int      `json:"tokens"`
+	Truncated bool     `json:"truncated,omitempty"` // 内容因预算被截断
+}
+
+// ContextResponse 组装好的上下文，Context可直接注入Prompt
+type ContextResponse struct {
+	QueryID      string            `json:"query_id"`
+	Query        string            `json:"query"`
+	Context      string            `json:"context"`
+	Citations    []ContextCitation `json:"citations"`
+	Tokens       int               `json:"tokens"` // 分词器计算的token数，默认分词器为估算值
+	MaxTokens    int               `json:"max_tokens"`
+	Dropped      int               `json:"dropped"` // 因预算未放入的条目数
+	ProcessingMS int               `json:"processing_ms"`
+
+	Search *SearchResponse `json:"-"` // 底层检索响应，用于记录检索日志
+}
@@ -1,21 +1,26 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
 // Conversation 对话记录模型
 type Conversation struct {
-	ID             uint64              `json:"id" gorm:"primaryKey,autoIncrement"`
-	ConversationID string              `json:"conversation_id" gorm:"uniqueIndex,type:varchar(64),not null"`
-	DomainID       uint64              `json:"domain_id" gorm:"not null"`
-	Domain         *Domain             `json:"domain,omitempty" gorm:"foreignKey:DomainID"`
-	UserID         uint64              `json:"user_id"`
-	User           *User               `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Content        ConversationContent `json:"content" gorm:"type:json"`
-	Tags           []string            `json:"tags" gorm:"type:json"`
-	ProcessedAt    *time.Time          `json:"processed_at"`
-	CreatedAt      time.Time           `json:"created_at"`
+	ID              uint64              `json:"id" gorm:"primaryKey,autoIncrement"`
+	ConversationID  string              `json:"conversation_id" gorm:"uniqueIndex,type:varchar(64),not null"`
+	DomainID        uint64              `json:"domain_id" gorm:"not null"`
+	Domain          *Domain             `json:"domain,omitempty" gorm:"foreignKey:DomainID"`
+	UserID          uint64              `json:"user_id"`
+	User            *User               `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Content         ConversationContent `json:"content" gorm:"type:json"`
+	Tags            []string            `json:"tags" gorm:"type:json;serializer:json"`
+	ProcessedAt     *time.Time          `json:"processed_at"`
+	DistillAttempts int                 `json:"-" gorm:"not null;default:0"` // 记忆提炼失败次数，追加新消息时清零
+	Version         uint64              `json:"-" gorm:"not null;default:0"` // 乐观锁版本号，每次更新加一
+	CreatedAt       time.Time           `json:"created_at"`
 }
 
 // TableName 指定表名
@@ -29,13 +34,62 @@ type ConversationContent struct {
 	Context  Context   `json:"context"`
 }
 
+// Value 实现driver.Valuer，以JSON存储
+func (c ConversationContent) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan 实现sql.Scanner，从JSON列读取
+func (c *ConversationContent) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = ConversationContent{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported conversation content type %T", value)
+	}
+	return json.Unmarshal(data, c)
+}
+
 // Message 消息
 type Message struct {
-	ID        string    `json:"id"`
-	Role      string    `json:"role"` // user, assistant, system
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
-	Metadata  Metadata  `json:"metadata"`
+	ID        string           `json:"id"`
+	Role      string           `json:"role"` // user, assistant, system
+	Content   string           `json:"content"`
+	Timestamp time.Time        `json:"timestamp"`
+	Metadata  Metadata         `json:"metadata"`
+	Feedback  *MessageFeedback `json:"feedback,omitempty"`
+}
+
+// 消息反馈类型，除upvote和downvote外也接受positive、negative、neutral
+const (
+	MessageFeedbackUpvote   = "upvote"
+	MessageFeedbackDownvote = "downvote"
+)
+
+// MessageFeedback 消息级反馈。附在助手消息上时评价该回答；
+// 附在用户消息上时评价其之前最近的一条助手回答。
+type MessageFeedback struct {
+	Type   string `json:"type"`   // upvote, downvote, positive, negative, neutral
+	Reason string `json:"reason"` // 反馈原因
+	Rating int    `json:"rating"` // 1-5，可选
+}
+
+// FeedbackType 转换为检索反馈类型
+func (f *MessageFeedback) FeedbackType() (FeedbackType, bool) {
+	switch f.Type {
+	case MessageFeedbackUpvote:
+		return FeedbackTypePositive, true
+	case MessageFeedbackDownvote:
+		return FeedbackTypeNegative, true
+	}
+	t := FeedbackType(f.Type)
+	return t, ValidFeedbackType(t)
 }
 
 // Context 对话上下文
@@ -79,6 +133,15 @@ type CollectConversationRequest struct {
 	Tags           []string            `json:"tags"`
 }
 
+// CollectConversationResult 收集对话结果
+type CollectConversationResult struct {
+	Conversation     *ConversationResponse `json:"conversation"`
+	Created          bool                  `json:"created"`           // 是否新建对话
+	MessagesAdded    int                   `json:"messages_added"`    // 新追加的消息数
+	MessagesUpdated  int                   `json:"messages_updated"`  // 按消息ID覆盖的消息数
+	FeedbackRecorded int                   `json:"feedback_recorded"` // 记录的消息反馈数
+}
+
 // UpdateConversationRequest 更新对话请求
 type UpdateConversationRequest struct {
 	Content ConversationContent `json:"content"`
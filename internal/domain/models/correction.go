@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 纠正的来源类型
+const (
+	CorrectionSourceConversation = "conversation" // 对话中用户纠正助手的轮次
+	CorrectionSourceFeedback     = "feedback"     // 带评论的负面反馈
+)
+
+// CorrectionResultType 纠正在检索结果中的类型
+const CorrectionResultType = "correction"
+
+// Correction 从用户纠正中学到的知识，关联被纠正的原始问题和产生错误回答的分块。
+// 向量存储在corrections集合，由原始问题向量化，向量ID为CorrectionID；
+// 语义相近的检索会优先返回该纠正。每个来源对应一条记录，重复的纠正只记录来源并确认已有纠正。
+type Correction struct {
+	ID            uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	CorrectionID  string    `json:"correction_id" gorm:"uniqueIndex;type:varchar(64);not null"`
+	DomainID      uint64    `json:"domain_id" gorm:"index"`
+	UserID        uint64    `json:"user_id"`
+	QueryID       string    `json:"query_id,omitempty" gorm:"type:varchar(64)"` // 来自检索反馈时为检索的QueryID
+	Query         string    `json:"query" gorm:"type:text;not null"`            // 被纠正的原始问题
+	Answer        string    `json:"answer" gorm:"type:text"`                    // 被纠正的回答
+	Content       string    `json:"content" gorm:"type:text;not null"`          // 纠正后的知识
+	ChunkIDs      []string  `json:"chunk_ids" gorm:"type:json;serializer:json"` // 产生错误回答的分块
+	SourceType    string    `json:"source_type" gorm:"type:varchar(32);not null"`
+	SourceRef     string    `json:"source_ref" gorm:"uniqueIndex;type:varchar(191);not null"` // 来源标识，用于避免重复学习
+	Confidence    float64   `json:"confidence"`
+	Confirmations int       `json:"confirmations"`                                  // 独立来源给出相同纠正的次数
+	DuplicateOf   string    `json:"duplicate_of,omitempty" gorm:"type:varchar(64)"` // 与已有纠正相同时指向该纠正，自身不参与检索
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Correction) TableName() string {
+	return "corrections"
+}
+
+// CorrectionLearnResult 纠正学习结果
+type CorrectionLearnResult struct {
+	Candidates int      `json:"candidates"` // 检测到的候选纠正数
+	Created    []string `json:"created"`    // 新建的纠正ID
+	Confirmed  int      `json:"confirmed"`  // 与已有纠正相同而合并的条数
+	Rejected   int      `json:"rejected"`   // 核实后不是纠正的候选数
+}
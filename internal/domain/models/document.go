@@ -23,8 +23,8 @@ type Document struct {
 	ContentType string                 `json:"content_type" gorm:"size:50"`
 	FilePath    string                 `json:"file_path" gorm:"size:1000"`
 	FileSize    int64                  `json:"file_size"`
-	Metadata    map[string]interface{} `json:"metadata" gorm:"type:json"`
-	Tags        []string               `json:"tags" gorm:"type:json"`
+	Metadata    map[string]interface{} `json:"metadata" gorm:"type:json;serializer:json"`
+	Tags        []string               `json:"tags" gorm:"type:json;serializer:json"`
 	Status      DocumentStatus         `json:"status" gorm:"default:processing"`
 	ChunksCount int                    `json:"chunks_count" gorm:"default:0"`
 	CreatedAt   time.Time              `json:"created_at"`
@@ -59,7 +59,7 @@ type DocumentChunk struct {
 	Content    string                 `json:"content" gorm:"type:text"`
 	StartPos   int                    `json:"start_pos"`
 	EndPos     int                    `json:"end_pos"`
-	Metadata   map[string]interface{} `json:"metadata" gorm:"type:json"`
+	Metadata   map[string]interface{} `json:"metadata" gorm:"type:json;serializer:json"`
 	Vector     []float32              `json:"-" gorm:"-"` // 向量数据存储在Milvus中
 	CreatedAt  time.Time              `json:"created_at"`
 }
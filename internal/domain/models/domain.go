@@ -1,7 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Domain 知识域模型
@@ -9,7 +14,7 @@ type Domain struct {
 	ID          uint64                 `json:"id" gorm:"primaryKey,autoIncrement"`
 	DomainName  string                 `json:"domain_name" gorm:"uniqueIndex,size:100,not null"`
 	Description string                 `json:"description" gorm:"type:text"`
-	Config      map[string]interface{} `json:"config" gorm:"type:json"`
+	Config      map[string]interface{} `json:"config" gorm:"type:json;serializer:json"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 }
@@ -26,14 +31,88 @@ type DomainConfig struct {
 	MetricType      string            `json:"metric_type"`      // 相似度计算类型
 	SearchParams    map[string]string `json:"search_params"`    // 搜索参数
 	GraphConfig     GraphConfig       `json:"graph_config"`     // 图数据库配置
+	MemoryConfig    MemoryConfig      `json:"memory_config"`    // 用户记忆整理配置
 }
 
 // GraphConfig 图数据库配置
 type GraphConfig struct {
-	NodeTypes     []string `json:"node_types"`     // 节点类型
-	RelationTypes []string `json:"relation_types"` // 关系类型
-	MaxDepth      int      `json:"max_depth"`      // 最大搜索深度
-	MinScore      float64  `json:"min_score"`      // 最小相关度分数
+	NodeTypes              []string `json:"node_types"`               // 节点类型
+	RelationTypes          []string `json:"relation_types"`           // 关系类型
+	ExclusiveRelationTypes []string `json:"exclusive_relation_types"` // 同一源实体同时只能指向一个目标的关系类型
+	MaxDepth               int      `json:"max_depth"`                // 最大搜索深度
+	MinScore               float64  `json:"min_score"`                // 最小相关度分数
+	// 类型同义词：同义词 -> NodeTypes/RelationTypes中的规范类型，大小写不敏感
+	NodeTypeSynonyms     map[string]string `json:"node_type_synonyms"`
+	RelationTypeSynonyms map[string]string `json:"relation_type_synonyms"`
+}
+
+// 记忆低于遗忘阈值时的处理方式
+const (
+	MemoryForgetArchive = "archive"
+	MemoryForgetDelete  = "delete"
+)
+
+// MemoryConfig 用户记忆整理配置，零值字段使用系统默认值
+type MemoryConfig struct {
+	Disabled               bool    `json:"disabled"`                // 不整理该知识域的记忆
+	MergeThreshold         float64 `json:"merge_threshold"`         // 相似度达到该值的同类记忆直接合并
+	ContradictionThreshold float64 `json:"contradiction_threshold"` // 相似度达到该值的同类记忆交给大模型判断是否重复或矛盾
+	DecayHalfLifeDays      float64 `json:"decay_half_life_days"`    // 未被访问的记忆重要度减半所需天数，负数表示不衰减
+	ForgetThreshold        float64 `json:"forget_threshold"`        // 重要度低于该值的记忆被遗忘，负数表示不遗忘
+	ForgetAction           string  `json:"forget_action"`           // archive 或 delete
+}
+
+// ParseConfig 将Config解析为结构化的知识域配置
+func (d *Domain) ParseConfig() (*DomainConfig, error) {
+	cfg := &DomainConfig{}
+	if len(d.Config) == 0 {
+		return cfg, nil
+	}
+	data, err := json.Marshal(d.Config)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config of domain %s: %w", d.DomainName, err)
+	}
+	return cfg, nil
+}
+
+// BeforeSave 保存前校验知识域配置，避免写入无法生效的图谱模式
+func (d *Domain) BeforeSave(tx *gorm.DB) error {
+	cfg, err := d.ParseConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.GraphConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid config of domain %s: %w", d.DomainName, err)
+	}
+	return nil
+}
+
+// Validate 校验图配置：声明了节点或关系类型时，同义词必须指向声明的类型（大小写不敏感）
+func (c GraphConfig) Validate() error {
+	if err := validateSynonyms("node", c.NodeTypes, c.NodeTypeSynonyms); err != nil {
+		return err
+	}
+	return validateSynonyms("relation", c.RelationTypes, c.RelationTypeSynonyms)
+}
+
+// validateSynonyms 校验同义词的目标类型，未声明类型时不限制
+func validateSynonyms(kind string, types []string, synonyms map[string]string) error {
+	if len(types) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool, len(types))
+	for _, t := range types {
+		declared[strings.ToLower(t)] = true
+	}
+	for synonym, t := range synonyms {
+		if !declared[strings.ToLower(t)] {
+			return fmt.Errorf("%s type synonym %q refers to undeclared type %q", kind, synonym, t)
+		}
+	}
+	return nil
 }
 
 // CreateDomainRequest 创建知识域请求
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -22,8 +24,34 @@ type Feedback struct {
 	FeedbackType FeedbackType           `json:"feedback_type" gorm:"not null"`
 	Rating       int                    `json:"rating" gorm:"check:rating >= 1 AND rating <= 5"`
 	Comment      string                 `json:"comment" gorm:"type:text"`
-	Context      map[string]interface{} `json:"context" gorm:"type:json"`
+	Context      map[string]interface{} `json:"context" gorm:"type:json;serializer:json"`
 	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// ValidFeedbackType 是否为支持的反馈类型
+func ValidFeedbackType(t FeedbackType) bool {
+	switch t {
+	case FeedbackTypePositive, FeedbackTypeNegative, FeedbackTypeNeutral:
+		return true
+	}
+	return false
+}
+
+// ParseContext 将Context解析为结构化的反馈上下文
+func (f *Feedback) ParseContext() (*FeedbackContext, error) {
+	fc := &FeedbackContext{}
+	if len(f.Context) == 0 {
+		return fc, nil
+	}
+	data, err := json.Marshal(f.Context)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, fc); err != nil {
+		return nil, fmt.Errorf("invalid feedback context: %w", err)
+	}
+	return fc, nil
 }
 
 // TableName 指定表名
@@ -41,6 +69,9 @@ type FeedbackContext struct {
 	UserAgent      string                 `json:"user_agent"`
 	IPAddress      string                 `json:"ip_address"`
 	SessionID      string                 `json:"session_id"`
+	ConversationID string                 `json:"conversation_id,omitempty"` // 消息级反馈所属对话
+	MessageID      string                 `json:"message_id,omitempty"`      // 消息级反馈评价的助手消息
+	Sources        []string               `json:"sources,omitempty"`         // 该消息检索信息中的来源
 	CustomFields   map[string]interface{} `json:"custom_fields"`
 }
 
@@ -54,6 +85,12 @@ type CollectFeedbackRequest struct {
 	Context      map[string]interface{} `json:"context"`
 }
 
+// CollectFeedbackResult 收集反馈结果。同一用户对同一检索重复提交时更新已有反馈
+type CollectFeedbackResult struct {
+	Feedback *FeedbackResponse `json:"feedback"`
+	Created  bool              `json:"created"`
+}
+
 // UpdateFeedbackRequest 更新反馈请求
 type UpdateFeedbackRequest struct {
 	FeedbackType FeedbackType           `json:"feedback_type"`
@@ -97,21 +134,59 @@ func (f *Feedback) ToResponse() *FeedbackResponse {
 
 // FeedbackStats 反馈统计
 type FeedbackStats struct {
-	TotalFeedback int     `json:"total_feedback"`
-	PositiveCount int     `json:"positive_count"`
-	NegativeCount int     `json:"negative_count"`
-	NeutralCount  int     `json:"neutral_count"`
-	PositiveRate  float64 `json:"positive_rate"`
-	NegativeRate  float64 `json:"negative_rate"`
-	AverageRating float64 `json:"average_rating"`
-	TopIssues     []struct {
-		Issue string `json:"issue"`
-		Count int    `json:"count"`
-	} `json:"top_issues"`
-	TrendData []struct {
-		Date          time.Time `json:"date"`
-		PositiveCount int       `json:"positive_count"`
-		NegativeCount int       `json:"negative_count"`
-		AverageRating float64   `json:"average_rating"`
-	} `json:"trend_data"`
+	TotalFeedback int             `json:"total_feedback"`
+	PositiveCount int             `json:"positive_count"`
+	NegativeCount int             `json:"negative_count"`
+	NeutralCount  int             `json:"neutral_count"`
+	PositiveRate  float64         `json:"positive_rate"`
+	NegativeRate  float64         `json:"negative_rate"`
+	AverageRating float64         `json:"average_rating"`
+	TopIssues     []FeedbackIssue `json:"top_issues"` // 负面评论聚类得到的常见问题
+	TrendData     []FeedbackTrend `json:"trend_data"` // 按天统计
+}
+
+// FeedbackIssue 负面反馈中的常见问题
+type FeedbackIssue struct {
+	Issue string `json:"issue"`
+	Count int    `json:"count"`
+}
+
+// FeedbackTrend 单日反馈统计
+type FeedbackTrend struct {
+	Date          time.Time `json:"date"`
+	PositiveCount int       `json:"positive_count"`
+	NegativeCount int       `json:"negative_count"`
+	AverageRating float64   `json:"average_rating"`
+}
+
+// 质量先验的目标类型，与检索结果的Type一致
+const (
+	QualityTargetChunk    = "chunk"
+	QualityTargetDocument = "document"
+)
+
+// QualityPrior 从反馈学习得到的分块或文档质量先验，Score在[-1,1]之间，
+// 检索排序时按Score加权或降权
+type QualityPrior struct {
+	ID         uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	TargetType string    `json:"target_type" gorm:"type:varchar(32);not null"`
+	TargetID   string    `json:"target_id" gorm:"type:varchar(64);not null"`
+	Score      float64   `json:"score"`
+	Positive   float64   `json:"positive"` // 衰减后的正向信号总和
+	Negative   float64   `json:"negative"` // 衰减后的负向信号总和（绝对值）
+	Voters     int       `json:"voters"`   // 贡献信号的独立用户数
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (QualityPrior) TableName() string {
+	return "quality_priors"
+}
+
+// QualityLearnResult 质量先验学习结果
+type QualityLearnResult struct {
+	FeedbackProcessed int `json:"feedback_processed"`
+	Priors            int `json:"priors"`
+	Boosted           int `json:"boosted"`   // 正向先验数
+	Penalized         int `json:"penalized"` // 负向先验数
 }
@@ -11,12 +11,40 @@ type KnowledgeEntity struct {
 	Name       string                 `json:"name"`
 	Labels     []string               `json:"labels"`
 	Properties map[string]interface{} `json:"properties"`
-	Source     string                 `json:"source"` // 来源文档或对话
-	Score      float64                `json:"score"`  // 置信度分数
+	Source     string                 `json:"source"`    // 来源文档或对话
+	Score      float64                `json:"score"`     // 置信度分数
+	DomainID   uint64                 `json:"domain_id"` // 所属知识域
 	CreatedAt  time.Time              `json:"created_at"`
 	UpdatedAt  time.Time              `json:"updated_at"`
 }
 
+// 图分析写回实体属性的键
+const (
+	EntityPropPageRank   = "pagerank"    // PageRank原始分数
+	EntityPropCentrality = "centrality"  // 归一化中心度 [0,1]
+	EntityPropCommunity  = "community"   // 社区ID
+	EntityPropDegree     = "degree"      // 度数
+	EntityPropAnalyzedAt = "analyzed_at" // 最近一次分析时间
+)
+
+// Centrality 返回图分析写回的归一化中心度，未分析时返回0
+func (e *KnowledgeEntity) Centrality() float64 {
+	if e.Properties == nil {
+		return 0
+	}
+	switch v := e.Properties[EntityPropCentrality].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
 // KnowledgeRelation 知识关系
 type KnowledgeRelation struct {
 	ID         string                 `json:"id"`
@@ -24,10 +52,59 @@ type KnowledgeRelation struct {
 	FromEntity string                 `json:"from_entity"` // 源实体ID
 	ToEntity   string                 `json:"to_entity"`   // 目标实体ID
 	Properties map[string]interface{} `json:"properties"`
-	Source     string                 `json:"source"` // 来源文档或对话
-	Score      float64                `json:"score"`  // 置信度分数
-	CreatedAt  time.Time              `json:"created_at"`
-	UpdatedAt  time.Time              `json:"updated_at"`
+	Source     string                 `json:"source"`    // 来源文档或对话
+	Score      float64                `json:"score"`     // 置信度分数
+	DomainID   uint64                 `json:"domain_id"` // 所属知识域
+	// 时间有效性：关系在 [ValidFrom, ValidTo) 内成立，ValidTo为空表示当前仍成立
+	ValidFrom    time.Time  `json:"valid_from"`
+	ValidTo      *time.Time `json:"valid_to,omitempty"`
+	SupersededBy string     `json:"superseded_by,omitempty"` // 取代该关系的新关系ID
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ValidAt 关系在指定时间是否成立
+func (r *KnowledgeRelation) ValidAt(t time.Time) bool {
+	if !r.ValidFrom.IsZero() && t.Before(r.ValidFrom) {
+		return false
+	}
+	return r.ValidTo == nil || t.Before(*r.ValidTo)
+}
+
+// IsCurrent 关系当前是否成立
+func (r *KnowledgeRelation) IsCurrent() bool {
+	return r.ValidAt(time.Now())
+}
+
+// 关系变更动作
+const (
+	RelationActionCreated     = "created"     // 新建关系
+	RelationActionReinforced  = "reinforced"  // 已有相同关系，更新置信度
+	RelationActionSuperseded  = "superseded"  // 新关系取代了冲突的旧关系
+	RelationActionHistorical  = "historical"  // 新关系早于已有冲突关系，作为历史记录写入
+	RelationActionInvalidated = "invalidated" // 否定性抽取使已有关系失效
+)
+
+// RelationPropNegated 关系属性：抽取结果为否定事实（如"A不再依赖B"）
+const RelationPropNegated = "negated"
+
+// RelationChange 关系写入结果
+type RelationChange struct {
+	Action     string             `json:"action"`
+	Relation   *KnowledgeRelation `json:"relation"`
+	Conflicts  []string           `json:"conflicts,omitempty"` // 受影响的已有关系ID
+	DetectedAt time.Time          `json:"detected_at"`
+}
+
+// GraphWriteResult 抽取结果写入图谱的汇总
+type GraphWriteResult struct {
+	EntitiesCreated   int              `json:"entities_created"`
+	EntitiesMerged    int              `json:"entities_merged"`
+	EntityIDs         []string         `json:"entity_ids"` // 写入（新建或合并）的图谱实体ID，按抽取顺序
+	RelationChanges   []RelationChange `json:"relation_changes"`
+	EntitiesRejected  int              `json:"entities_rejected"`  // 类型不在知识域模式中
+	RelationsRejected int              `json:"relations_rejected"` // 类型不在模式中或端点被拒绝
+	Filtered          int              `json:"filtered"`           // 置信度低于知识域MinScore
 }
 
 // KnowledgeGraph 知识图谱
@@ -50,13 +127,15 @@ type GraphMetadata struct {
 
 // GraphTraversal 图遍历配置
 type GraphTraversal struct {
-	StartEntity   string   `json:"start_entity"`
-	MaxDepth      int      `json:"max_depth"`
-	Direction     string   `json:"direction"`      // IN, OUT, BOTH
-	RelationTypes []string `json:"relation_types"` // 关系类型过滤
-	EntityTypes   []string `json:"entity_types"`   // 实体类型过滤
-	MinScore      float64  `json:"min_score"`      // 最小置信度
-	Limit         int      `json:"limit"`          // 结果数量限制
+	StartEntity   string     `json:"start_entity"`
+	DomainID      uint64     `json:"domain_id"`       // 为空时取起始实体所属知识域
+	MaxDepth      int        `json:"max_depth"`       // 不超过知识域配置的MaxDepth
+	Direction     string     `json:"direction"`       // IN, OUT, BOTH
+	RelationTypes []string   `json:"relation_types"`  // 关系类型过滤
+	EntityTypes   []string   `json:"entity_types"`    // 实体类型过滤
+	MinScore      float64    `json:"min_score"`       // 最小置信度
+	Limit         int        `json:"limit"`           // 结果数量限制
+	AsOf          *time.Time `json:"as_of,omitempty"` // 只遍历该时间点成立的关系，为空表示当前
 }
 
 // GraphTraversalResult 图遍历结果
@@ -137,18 +216,79 @@ type GraphSearchResult struct {
 
 // GraphStats 图统计信息
 type GraphStats struct {
-	TotalEntities  int `json:"total_entities"`
-	TotalRelations int `json:"total_relations"`
-	EntityTypes    []struct {
-		Type  string `json:"type"`
-		Count int    `json:"count"`
-	} `json:"entity_types"`
-	RelationTypes []struct {
-		Type  string `json:"type"`
-		Count int    `json:"count"`
-	} `json:"relation_types"`
-	Density             float64 `json:"density"`
-	AvgDegree           float64 `json:"avg_degree"`
-	MaxDegree           int     `json:"max_degree"`
-	ConnectedComponents int     `json:"connected_components"`
+	TotalEntities       int         `json:"total_entities"`
+	TotalRelations      int         `json:"total_relations"`
+	EntityTypes         []TypeCount `json:"entity_types"`
+	RelationTypes       []TypeCount `json:"relation_types"`
+	Density             float64     `json:"density"`
+	AvgDegree           float64     `json:"avg_degree"`
+	MaxDegree           int         `json:"max_degree"`
+	ConnectedComponents int         `json:"connected_components"`
+}
+
+// TypeCount 类型计数
+type TypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// GraphCommunity 图社区
+type GraphCommunity struct {
+	ID      string   `json:"id"`
+	Size    int      `json:"size"`
+	Members []string `json:"members"` // 实体ID，按中心度降序
+}
+
+// EntityRank 实体中心度排名
+type EntityRank struct {
+	EntityID   string  `json:"entity_id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	PageRank   float64 `json:"pagerank"`
+	Centrality float64 `json:"centrality"`
+	Degree     int     `json:"degree"`
+	Community  string  `json:"community"`
+}
+
+// GraphAnalyticsRequest 图分析请求
+type GraphAnalyticsRequest struct {
+	DomainID uint64 `json:"domain_id" binding:"required"`
+}
+
+// GraphAnalyticsResult 图分析结果
+type GraphAnalyticsResult struct {
+	DomainID      uint64           `json:"domain_id"`
+	Stats         GraphStats       `json:"stats"`
+	TopEntities   []EntityRank     `json:"top_entities"`
+	Communities   []GraphCommunity `json:"communities"`
+	Persisted     bool             `json:"persisted"` // 分数是否已写回实体
+	ExecutionTime int              `json:"execution_time_ms"`
+	AnalyzedAt    time.Time        `json:"analyzed_at"`
+}
+
+// 图谱交换格式
+const (
+	GraphFormatJSONLD  = "jsonld"
+	GraphFormatGraphML = "graphml"
+	GraphFormatCSV     = "csv" // 实体和关系各一个CSV文件
+)
+
+// GraphImportError 导入时被拒绝的记录
+type GraphImportError struct {
+	Record  string `json:"record"` // 记录位置，如 nodes.csv:12、node[3]
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// GraphImportResult 图谱导入结果
+type GraphImportResult struct {
+	DomainID         uint64             `json:"domain_id"`
+	Format           string             `json:"format"`
+	DryRun           bool               `json:"dry_run"`
+	EntitiesCreated  int                `json:"entities_created"`
+	EntitiesUpdated  int                `json:"entities_updated"`
+	RelationsCreated int                `json:"relations_created"`
+	RelationsUpdated int                `json:"relations_updated"`
+	Rejected         int                `json:"rejected"`
+	Errors           []GraphImportError `json:"errors,omitempty"` // 最多保留前若干条
 }
@@ -0,0 +1,151 @@
+package models
+
+import "time"
+
+// MemoryType 记忆类型
+type MemoryType string
+
+const (
+	MemoryTypeFact       MemoryType = "fact"       // 关于用户或其环境的事实
+	MemoryTypePreference MemoryType = "preference" // 偏好和习惯
+	MemoryTypeDecision   MemoryType = "decision"   // 做出的决定
+	MemoryTypeCorrection MemoryType = "correction" // 对助手错误的纠正
+)
+
+// Memory 从对话中提炼的用户长期记忆。向量存储在conversation_memories集合，向量ID为MemoryID；
+// 访问计数等频繁变化的字段只保存在MySQL中。
+type Memory struct {
+	ID               uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	MemoryID         string     `json:"memory_id" gorm:"uniqueIndex;type:varchar(64);not null"`
+	UserID           uint64     `json:"user_id" gorm:"index;not null"`
+	DomainID         uint64     `json:"domain_id" gorm:"index;not null"`
+	ConversationID   string     `json:"conversation_id" gorm:"index;type:varchar(64)"`
+	MemoryType       MemoryType `json:"memory_type" gorm:"type:varchar(50);not null"`
+	Content          string     `json:"content" gorm:"type:text;not null"`
+	ImportanceScore  float64    `json:"importance_score"`
+	EntityIDs        []string   `json:"entity_ids" gorm:"type:json;serializer:json"`         // 关联的图谱实体
+	SourceMessageIDs []string   `json:"source_message_ids" gorm:"type:json;serializer:json"` // 来源消息
+	AccessCount      int        `json:"access_count"`
+	LastAccessed     *time.Time `json:"last_accessed"`
+	Pinned           bool       `json:"pinned"`                                          // 用户固定的记忆不会被衰减、合并或遗忘
+	SupersededBy     string     `json:"superseded_by,omitempty" gorm:"type:varchar(64)"` // 被更新的矛盾记忆取代时指向新记忆
+	ArchivedAt       *time.Time `json:"archived_at,omitempty" gorm:"index"`              // 归档的记忆保留历史，但不再参与检索
+	DecayedAt        *time.Time `json:"-"`                                               // 最近一次重要度衰减的时间
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Memory) TableName() string {
+	return "memories"
+}
+
+// MemoryDistillResult 单个对话的记忆提炼结果
+type MemoryDistillResult struct {
+	ConversationID string   `json:"conversation_id"`
+	Created        []string `json:"created"`    // 新建的记忆ID
+	Duplicates     int      `json:"duplicates"` // 与已有记忆重复而跳过的条数
+	LinkedEntities int      `json:"linked_entities"`
+	Corrections    []string `json:"corrections"` // 从对话中学到的纠正ID
+}
+
+// MemoryConsolidateResult 知识域记忆整理结果
+type MemoryConsolidateResult struct {
+	DomainID   uint64 `json:"domain_id"`
+	Users      int    `json:"users"`
+	Merged     int    `json:"merged"`     // 合并掉的近似重复记忆
+	Superseded int    `json:"superseded"` // 被更新的陈述取代而归档的记忆
+	Decayed    int    `json:"decayed"`
+	Archived   int    `json:"archived"` // 重要度低于阈值而归档的记忆
+	Deleted    int    `json:"deleted"`  // 重要度低于阈值而删除的记忆
+}
+
+// TimeRange 时间范围，边界为空表示不限
+type TimeRange struct {
+	Start *time.Time `json:"start"`
+	End   *time.Time `json:"end"`
+}
+
+// MemoryQueryRequest 记忆查询请求
+type MemoryQueryRequest struct {
+	UserID      uint64       `json:"user_id" binding:"required"`
+	DomainID    uint64       `json:"domain_id"` // 为空时查询用户在所有知识域的记忆
+	Query       string       `json:"query" binding:"required"`
+	TimeRange   *TimeRange   `json:"time_range"` // 按记忆创建时间过滤
+	MemoryTypes []MemoryType `json:"memory_types"`
+	Limit       int          `json:"limit"`
+}
+
+// MemoryHit 记忆查询命中
+type MemoryHit struct {
+	Memory
+	Score     float64 `json:"score"`     // 综合分数
+	Relevance float64 `json:"relevance"` // 向量相似度
+	Recency   float64 `json:"recency"`   // 时间衰减 (0,1]
+}
+
+// MemoryQueryResponse 记忆查询响应
+type MemoryQueryResponse struct {
+	Memories  []MemoryHit `json:"memories"`
+	Total     int         `json:"total"`
+	QueryTime int         `json:"query_time_ms"`
+}
+
+// MemoryListResponse 记忆列表响应
+type MemoryListResponse struct {
+	Memories []*Memory `json:"memories"`
+	Total    int64     `json:"total"`
+	Offset   int       `json:"offset"`
+	Limit    int       `json:"limit"`
+}
+
+// UpdateMemoryRequest 编辑记忆请求，为空的字段保持不变
+type UpdateMemoryRequest struct {
+	Content         *string     `json:"content"`
+	MemoryType      *MemoryType `json:"memory_type"`
+	ImportanceScore *float64    `json:"importance_score"`
+}
+
+// ForgetTopicRequest 遗忘主题请求
+type ForgetTopicRequest struct {
+	Topic    string `json:"topic" binding:"required"`
+	DomainID uint64 `json:"domain_id"` // 为空时遗忘用户在所有知识域中的相关内容
+}
+
+// ForgetTopicResult 遗忘主题结果
+type ForgetTopicResult struct {
+	Memories  []string `json:"memories"`  // 删除的记忆ID
+	Entities  []string `json:"entities"`  // 删除的图谱实体ID
+	Relations int      `json:"relations"` // 随实体删除的关系数
+	Messages  int      `json:"messages"`  // 删除的对话消息数
+}
+
+// 记忆审计的操作和对象类型
+const (
+	MemoryAuditDelete = "delete" // 用户删除记忆
+	MemoryAuditForget = "forget" // 遗忘主题
+	MemoryAuditMerge  = "merge"  // 整理时合并重复记忆
+	MemoryAuditExpire = "expire" // 整理时遗忘低重要度记忆
+
+	MemoryAuditTargetMemory   = "memory"
+	MemoryAuditTargetEntity   = "entity"
+	MemoryAuditTargetRelation = "relation"
+	MemoryAuditTargetMessage  = "message"
+)
+
+// MemoryAuditLog 记忆相关删除的审计日志。只记录对象ID，不保存被删除的内容
+type MemoryAuditLog struct {
+	ID         uint64                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint64                 `json:"user_id" gorm:"index;not null"`
+	Action     string                 `json:"action" gorm:"type:varchar(50);not null"`
+	TargetType string                 `json:"target_type" gorm:"type:varchar(50);not null"`
+	TargetID   string                 `json:"target_id" gorm:"type:varchar(255);not null"`
+	Actor      string                 `json:"actor" gorm:"type:varchar(100)"` // 操作方：user 或后台任务名
+	Detail     map[string]interface{} `json:"detail" gorm:"type:json;serializer:json"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// TableName 指定表名
+func (MemoryAuditLog) TableName() string {
+	return "memory_audit_logs"
+}
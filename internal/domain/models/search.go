@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -13,8 +14,8 @@ type SearchLog struct {
 	DomainID     uint64                 `json:"domain_id"`
 	Domain       *Domain                `json:"domain,omitempty" gorm:"foreignKey:DomainID"`
 	QueryText    string                 `json:"query_text" gorm:"type:text,not null"`
-	SearchConfig map[string]interface{} `json:"search_config" gorm:"type:json"`
-	Results      SearchResults          `json:"results" gorm:"type:json"`
+	SearchConfig map[string]interface{} `json:"search_config" gorm:"type:json;serializer:json"`
+	Results      SearchResults          `json:"results" gorm:"type:json;serializer:json"`
 	ResponseTime int                    `json:"response_time_ms"`
 	CreatedAt    time.Time              `json:"created_at"`
 }
@@ -35,7 +36,7 @@ type SearchResults struct {
 // SearchResult 单个搜索结果
 type SearchResult struct {
 	ID         string                 `json:"id"`
-	Type       string                 `json:"type"` // document, chunk, conversation
+	Type       string                 `json:"type"` // document, chunk, conversation, correction
 	Title      string                 `json:"title"`
 	Content    string                 `json:"content"`
 	Source     string                 `json:"source"`
@@ -45,6 +46,29 @@ type SearchResult struct {
 	CreatedAt  time.Time              `json:"created_at"`
 }
 
+// FacetBucket 分面中一个取值及其命中数
+type FacetBucket struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"` // 取值的展示名称，如知识域名和文档标题
+	Count int    `json:"count"`
+}
+
+// Highlight 检索结果的高亮片段，位置按字符计算。分块结果的位置已换算到原文档，其他结果为内容中的位置
+type Highlight struct {
+	Snippet  string     `json:"snippet"` // 带高亮标记的片段
+	Start    int        `json:"start"`
+	End      int        `json:"end"`
+	Matches  []TextSpan `json:"matches"`  // 高亮部分的位置
+	Score    float64    `json:"score"`    // 词命中时为覆盖的查询词数，语义匹配时为句子与查询的相似度
+	Semantic bool       `json:"semantic"` // 没有词命中，按句子语义相似度选取
+}
+
+// TextSpan 文本区间 [Start, End)
+type TextSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
 // SearchRequest 搜索请求
 type SearchRequest struct {
 	Query    string                 `json:"query" binding:"required"`
@@ -67,8 +91,16 @@ type SearchOptions struct {
 	SortOrder       string   `json:"sort_order"`       // 排序顺序
 	Highlight       bool     `json:"highlight"`        // 是否高亮
 	Rerank          bool     `json:"rerank"`           // 是否重排序
+	Mode            string   `json:"mode"`             // 检索模式: local, global
+	Plan            string   `json:"plan"`             // 查询规划方式: auto（默认）, llm, heuristic
 }
 
+// 检索模式
+const (
+	SearchModeLocal  = "local"  // 分块和实体级检索
+	SearchModeGlobal = "global" // 基于社区摘要的全局问答
+)
+
 // SearchContext 搜索上下文
 type SearchContext struct {
 	SessionID      string                 `json:"session_id"`
@@ -92,9 +124,45 @@ type SearchResponse struct {
 	Aggregations   map[string]interface{} `json:"aggregations"`
 	Suggestions    []string               `json:"suggestions"`
 	RelatedQueries []string               `json:"related_queries"`
+	Plan           *QueryPlan             `json:"plan,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata"`
 }
 
+// 查询规划方式，llm和heuristic也是检索选项plan的取值
+const (
+	QueryPlannerNone      = "none"      // 未规划，按原始查询检索
+	QueryPlannerAuto      = "auto"      // 检索选项：规则判断为多部分问题或追问时才调用大模型
+	QueryPlannerLLM       = "llm"       // 大模型改写和规划
+	QueryPlannerHeuristic = "heuristic" // 规则改写和拆分，大模型不可用或超时时使用
+)
+
+// QueryPlan 检索前的查询规划，记录在检索日志的search_config.plan中便于排查
+type QueryPlan struct {
+	Original           string   `json:"original"`
+	Rewritten          string   `json:"rewritten"`                     // 消解指代、补全上下文后的查询
+	SubQueries         []string `json:"sub_queries"`                   // 多部分问题拆分出的子查询
+	HypotheticalAnswer string   `json:"hypothetical_answer,omitempty"` // 用于向量检索的假设答案（HyDE）
+	Retrievers         []string `json:"retrievers"`                    // 启用的检索器，为空表示全部
+	Planner            string   `json:"planner"`
+	Reason             string   `json:"reason,omitempty"`
+	Error              string   `json:"error,omitempty"` // 大模型规划失败时的原因
+}
+
+// Queries 需要检索的查询：改写后的查询和各子查询，去除重复
+func (p *QueryPlan) Queries() []string {
+	seen := make(map[string]bool, len(p.SubQueries)+1)
+	var queries []string
+	for _, q := range append([]string{p.Rewritten}, p.SubQueries...) {
+		q = strings.TrimSpace(q)
+		if q == "" || seen[q] {
+			continue
+		}
+		seen[q] = true
+		queries = append(queries, q)
+	}
+	return queries
+}
+
 // ToResponse 转换为响应格式
 func (sl *SearchLog) ToResponse() *SearchResponse {
 	return &SearchResponse{
@@ -108,38 +176,133 @@ func (sl *SearchLog) ToResponse() *SearchResponse {
 	}
 }
 
-// SearchStats 搜索统计
+// SearchStats 搜索统计，只统计TimeRange内的检索
 type SearchStats struct {
-	TotalSearches   int     `json:"total_searches"`
-	UniqueUsers     int     `json:"unique_users"`
-	AvgResponseTime float64 `json:"avg_response_time"`
-	TopQueries      []struct {
-		Query string `json:"query"`
-		Count int    `json:"count"`
-	} `json:"top_queries"`
-	PopularDomains []struct {
-		DomainName string `json:"domain_name"`
-		Count      int    `json:"count"`
-	} `json:"popular_domains"`
-	HourlyDistribution []struct {
-		Hour  int `json:"hour"`
-		Count int `json:"count"`
-	} `json:"hourly_distribution"`
-	QualityMetrics struct {
-		ClickThroughRate float64 `json:"click_through_rate"`
-		ZeroResultRate   float64 `json:"zero_result_rate"`
-		AvgResultsCount  float64 `json:"avg_results_count"`
-	} `json:"quality_metrics"`
-}
-
-// QuerySuggestion 查询建议
+	TimeRange          TimeRange            `json:"time_range"`
+	TotalSearches      int                  `json:"total_searches"`
+	UniqueUsers        int                  `json:"unique_users"`
+	AvgResponseTime    float64              `json:"avg_response_time"`
+	TopQueries         []QueryCount         `json:"top_queries"`
+	PopularDomains     []DomainCount        `json:"popular_domains"`
+	HourlyDistribution []HourlyCount        `json:"hourly_distribution"` // 按小时（0-23）统计，包含没有检索的小时
+	QualityMetrics     SearchQualityMetrics `json:"quality_metrics"`
+}
+
+// QueryCount 查询次数，查询按忽略大小写和首尾空白归并
+type QueryCount struct {
+	Query string `json:"query"`
+	Count int    `json:"count"`
+}
+
+// DomainCount 知识域的检索次数
+type DomainCount struct {
+	DomainName string `json:"domain_name"`
+	Count      int    `json:"count"`
+}
+
+// HourlyCount 单个小时的检索次数
+type HourlyCount struct {
+	Hour  int `json:"hour"`
+	Count int `json:"count"`
+}
+
+// SearchQualityMetrics 检索质量指标
+type SearchQualityMetrics struct {
+	ClickThroughRate float64 `json:"click_through_rate"` // 反馈中有点击记录的检索占比
+	ZeroResultRate   float64 `json:"zero_result_rate"`   // 没有命中任何结果的检索占比
+	AvgResultsCount  float64 `json:"avg_results_count"`  // 平均命中结果数
+}
+
+// 查询建议来源
+const (
+	SuggestionSourceAuto   = "auto"   // 从检索日志挖掘
+	SuggestionSourceManual = "manual" // 人工维护
+)
+
+// 查询建议状态
+const (
+	SuggestionStatusActive   = "active"
+	SuggestionStatusInactive = "inactive"
+)
+
+// 查询建议的挖掘方式
+const (
+	SuggestionMethodCoOccurrence  = "co_occurrence" // 同一会话中相继出现的查询，作为相关查询
+	SuggestionMethodReformulation = "reformulation" // 零结果查询之后改写出的有结果查询，作为查询建议
+	SuggestionMethodCluster       = "cluster"       // 向量聚类中语义相近的查询，作为相关查询
+)
+
+// QuerySuggestion 查询建议，Query为归一化（小写、合并空白）后的查询
 type QuerySuggestion struct {
-	ID         uint64    `json:"id" gorm:"primaryKey,autoIncrement"`
-	Query      string    `json:"query" gorm:"size:500,not null"`
-	Suggestion string    `json:"suggestion" gorm:"size:500,not null"`
+	ID         uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	DomainID   uint64    `json:"domain_id"` // 0表示适用于全部知识域
+	Query      string    `json:"query" gorm:"size:500;not null"`
+	Suggestion string    `json:"suggestion" gorm:"size:500;not null"`
 	Score      float64   `json:"score"`
-	Source     string    `json:"source"` // auto, manual
-	Status     string    `json:"status"` // active, inactive
+	Source     string    `json:"source"`           // auto, manual
+	Method     string    `json:"method,omitempty"` // 自动挖掘的方式，人工维护的建议为空
+	Support    int       `json:"support"`          // 支持该建议的会话数
+	Status     string    `json:"status"`           // active, inactive
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
+
+// TableName 指定表名
+func (QuerySuggestion) TableName() string {
+	return "query_suggestions"
+}
+
+// Related 是否作为相关查询展示，否则作为查询建议展示
+func (s *QuerySuggestion) Related() bool {
+	return s.Method == SuggestionMethodCoOccurrence || s.Method == SuggestionMethodCluster
+}
+
+// SuggestionFilter 查询建议列表过滤条件，为空的字段不过滤
+type SuggestionFilter struct {
+	DomainID uint64 `form:"domain_id"`
+	Query    string `form:"query"` // 按查询前缀过滤
+	Source   string `form:"source"`
+	Status   string `form:"status"`
+	Offset   int    `form:"offset"`
+	Limit    int    `form:"limit"`
+}
+
+// SuggestionListResponse 查询建议列表响应
+type SuggestionListResponse struct {
+	Suggestions []*QuerySuggestion `json:"suggestions"`
+	Total       int64              `json:"total"`
+	Offset      int                `json:"offset"`
+	Limit       int                `json:"limit"`
+}
+
+// CreateSuggestionRequest 人工添加查询建议请求，人工维护的建议作为查询建议展示
+type CreateSuggestionRequest struct {
+	DomainID   uint64  `json:"domain_id"`
+	Query      string  `json:"query" binding:"required"`
+	Suggestion string  `json:"suggestion" binding:"required"`
+	Score      float64 `json:"score"`
+	Status     string  `json:"status"` // 为空时为active
+}
+
+// UpdateSuggestionRequest 编辑查询建议请求，为空的字段保持不变
+type UpdateSuggestionRequest struct {
+	Suggestion *string  `json:"suggestion"`
+	Score      *float64 `json:"score"`
+	Status     *string  `json:"status"`
+}
+
+// QueryCompletion 查询自动补全候选
+type QueryCompletion struct {
+	Query  string `json:"query"`
+	Count  int    `json:"count"`  // 历史检索次数，人工维护的候选为0
+	Source string `json:"source"` // manual, history
+}
+
+// SuggestionMineResult 查询建议挖掘结果
+type SuggestionMineResult struct {
+	LogsScanned    int `json:"logs_scanned"`
+	Sessions       int `json:"sessions"`
+	CoOccurrences  int `json:"co_occurrences"`
+	Reformulations int `json:"reformulations"`
+	Clustered      int `json:"clustered"`
+}
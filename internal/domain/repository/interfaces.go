@@ -2,10 +2,20 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/xyzbit/ino/internal/domain/models"
 )
 
+// ErrNotFound 记录不存在，仓储实现返回包装了该错误的错误，上层用errors.Is判断
+var ErrNotFound = errors.New("record not found")
+
+// ErrStaleVersion 乐观锁冲突：记录在读取之后已被其他请求修改，调用方应重新读取后重试
+var ErrStaleVersion = errors.New("record was modified concurrently")
+
 // UserRepository 用户仓储接口
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
@@ -34,6 +44,7 @@ type DocumentRepository interface {
 	Create(ctx context.Context, document *models.Document) error
 	GetByID(ctx context.Context, id uint64) (*models.Document, error)
 	GetByDocumentID(ctx context.Context, documentID string) (*models.Document, error)
+	GetByDocumentIDs(ctx context.Context, documentIDs []string) ([]*models.Document, error)
 	Update(ctx context.Context, document *models.Document) error
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, offset, limit int) ([]*models.Document, error)
@@ -49,6 +60,7 @@ type DocumentChunkRepository interface {
 	Create(ctx context.Context, chunk *models.DocumentChunk) error
 	GetByID(ctx context.Context, id uint64) (*models.DocumentChunk, error)
 	GetByChunkID(ctx context.Context, chunkID string) (*models.DocumentChunk, error)
+	GetByChunkIDs(ctx context.Context, chunkIDs []string) ([]*models.DocumentChunk, error)
 	Update(ctx context.Context, chunk *models.DocumentChunk) error
 	Delete(ctx context.Context, id uint64) error
 	ListByDocument(ctx context.Context, documentID string, offset, limit int) ([]*models.DocumentChunk, error)
@@ -62,6 +74,7 @@ type ConversationRepository interface {
 	Create(ctx context.Context, conversation *models.Conversation) error
 	GetByID(ctx context.Context, id uint64) (*models.Conversation, error)
 	GetByConversationID(ctx context.Context, conversationID string) (*models.Conversation, error)
+	// Update 以Version做乐观锁更新对话，记录已被修改时返回ErrStaleVersion，成功后Version加一
 	Update(ctx context.Context, conversation *models.Conversation) error
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, offset, limit int) ([]*models.Conversation, error)
@@ -70,6 +83,42 @@ type ConversationRepository interface {
 	Count(ctx context.Context) (int64, error)
 	CountByDomain(ctx context.Context, domainID uint64) (int64, error)
 	CountByUser(ctx context.Context, userID uint64) (int64, error)
+	// ListUnprocessed 获取尚未提炼记忆且失败次数少于maxAttempts的对话，失败次数少的优先，其次按创建顺序
+	ListUnprocessed(ctx context.Context, maxAttempts, limit int) ([]*models.Conversation, error)
+	// MarkProcessed 只更新processed_at并递增版本号，对话在读取版本之后被修改过时返回ErrStaleVersion
+	MarkProcessed(ctx context.Context, id, version uint64, processedAt time.Time) error
+	// UpdateContent 只更新对话内容，以Version做乐观锁，记录已被修改时返回ErrStaleVersion
+	UpdateContent(ctx context.Context, conversation *models.Conversation) error
+	// RecordDistillFailure 递增对话的提炼失败次数
+	RecordDistillFailure(ctx context.Context, id uint64) error
+}
+
+// MemoryRepository 用户记忆仓储接口
+type MemoryRepository interface {
+	Create(ctx context.Context, memory *models.Memory) error
+	GetByMemoryID(ctx context.Context, memoryID string) (*models.Memory, error)
+	GetByMemoryIDs(ctx context.Context, memoryIDs []string) ([]*models.Memory, error)
+	Update(ctx context.Context, memory *models.Memory) error
+	Delete(ctx context.Context, id uint64) error
+	// ListByUser 和 CountByUser 只包含未归档的记忆
+	ListByUser(ctx context.Context, userID, domainID uint64, offset, limit int) ([]*models.Memory, error)
+	ListByConversation(ctx context.Context, conversationID string) ([]*models.Memory, error)
+	CountByUser(ctx context.Context, userID, domainID uint64) (int64, error)
+	// ListUserIDs 获取在知识域中有未归档记忆的用户
+	ListUserIDs(ctx context.Context, domainID uint64) ([]uint64, error)
+	// ListByKeyword 获取内容包含关键词的记忆（含已归档），domainID为0时不限知识域
+	ListByKeyword(ctx context.Context, userID, domainID uint64, keyword string) ([]*models.Memory, error)
+	// RecordAccess 记录记忆被检索命中：访问次数加一并更新最后访问时间
+	RecordAccess(ctx context.Context, memoryIDs []string, at time.Time) error
+	// UpdateDecay 只更新重要度和衰减时间，不覆盖并发记录的访问次数和最后访问时间
+	UpdateDecay(ctx context.Context, memoryID string, importance float64, decayedAt time.Time) error
+}
+
+// MemoryAuditRepository 记忆审计日志仓储接口
+type MemoryAuditRepository interface {
+	Create(ctx context.Context, logs ...*models.MemoryAuditLog) error
+	ListByUser(ctx context.Context, userID uint64, offset, limit int) ([]*models.MemoryAuditLog, error)
+	CountByUser(ctx context.Context, userID uint64) (int64, error)
 }
 
 // FeedbackRepository 反馈仓储接口
@@ -77,6 +126,8 @@ type FeedbackRepository interface {
 	Create(ctx context.Context, feedback *models.Feedback) error
 	GetByID(ctx context.Context, id uint64) (*models.Feedback, error)
 	GetByQueryID(ctx context.Context, queryID string) ([]*models.Feedback, error)
+	// GetByQueryAndUser 获取用户对某次检索的反馈
+	GetByQueryAndUser(ctx context.Context, queryID string, userID uint64) (*models.Feedback, error)
 	Update(ctx context.Context, feedback *models.Feedback) error
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, offset, limit int) ([]*models.Feedback, error)
@@ -85,6 +136,26 @@ type FeedbackRepository interface {
 	Count(ctx context.Context) (int64, error)
 	CountByType(ctx context.Context, feedbackType models.FeedbackType) (int64, error)
 	GetStats(ctx context.Context) (*models.FeedbackStats, error)
+	// ListSince 按ID顺序分页获取since之后的反馈，afterID为上一页最后一条的ID
+	ListSince(ctx context.Context, since time.Time, afterID uint64, limit int) ([]*models.Feedback, error)
+	// ListUpdatedAfter 按更新时间和ID顺序分页获取在(after, afterID)之后创建或修改的反馈
+	ListUpdatedAfter(ctx context.Context, after time.Time, afterID uint64, limit int) ([]*models.Feedback, error)
+}
+
+// QualityPriorRepository 质量先验仓储接口
+type QualityPriorRepository interface {
+	// ReplaceAll 用新计算的先验整体替换已有先验
+	ReplaceAll(ctx context.Context, priors []*models.QualityPrior) error
+	List(ctx context.Context) ([]*models.QualityPrior, error)
+}
+
+// CorrectionRepository 纠正仓储接口
+type CorrectionRepository interface {
+	Create(ctx context.Context, correction *models.Correction) error
+	Update(ctx context.Context, correction *models.Correction) error
+	GetByCorrectionIDs(ctx context.Context, correctionIDs []string) ([]*models.Correction, error)
+	// ExistingSourceRefs 返回已学习过的来源标识
+	ExistingSourceRefs(ctx context.Context, refs []string) (map[string]bool, error)
 }
 
 // SearchLogRepository 搜索日志仓储接口
@@ -92,13 +163,44 @@ type SearchLogRepository interface {
 	Create(ctx context.Context, log *models.SearchLog) error
 	GetByID(ctx context.Context, id uint64) (*models.SearchLog, error)
 	GetByQueryID(ctx context.Context, queryID string) (*models.SearchLog, error)
+	GetByQueryIDs(ctx context.Context, queryIDs []string) ([]*models.SearchLog, error)
 	Update(ctx context.Context, log *models.SearchLog) error
 	Delete(ctx context.Context, id uint64) error
 	List(ctx context.Context, offset, limit int) ([]*models.SearchLog, error)
 	ListByUser(ctx context.Context, userID uint64, offset, limit int) ([]*models.SearchLog, error)
 	ListByDomain(ctx context.Context, domainID uint64, offset, limit int) ([]*models.SearchLog, error)
 	Count(ctx context.Context) (int64, error)
-	GetStats(ctx context.Context) (*models.SearchStats, error)
+	// GetStats 统计时间范围内的检索，范围边界为空表示不限
+	GetStats(ctx context.Context, window models.TimeRange) (*models.SearchStats, error)
+	// ListRecent 按ID倒序获取since之后创建、ID小于beforeID的搜索日志，beforeID为0时从最新的开始，用于分批扫描
+	ListRecent(ctx context.Context, since time.Time, beforeID uint64, limit int) ([]*models.SearchLog, error)
+	// PopularQueries 时间范围内有命中结果的热门查询，按归一化查询归并，prefix为空时不过滤前缀
+	PopularQueries(ctx context.Context, domainID uint64, prefix string, window models.TimeRange, limit int) ([]models.QueryCount, error)
+}
+
+// QuerySuggestionRepository 查询建议仓储接口
+type QuerySuggestionRepository interface {
+	Create(ctx context.Context, suggestion *models.QuerySuggestion) error
+	Update(ctx context.Context, suggestion *models.QuerySuggestion) error
+	Delete(ctx context.Context, id uint64) error
+	GetByID(ctx context.Context, id uint64) (*models.QuerySuggestion, error)
+	List(ctx context.Context, filter models.SuggestionFilter) ([]*models.QuerySuggestion, int64, error)
+	// ListActive 获取查询在知识域（及全部知识域）下生效的建议，按分数降序
+	ListActive(ctx context.Context, domainID uint64, query string, limit int) ([]*models.QuerySuggestion, error)
+	// Complete 获取建议文本以prefix开头的生效建议，按分数降序
+	Complete(ctx context.Context, domainID uint64, prefix string, limit int) ([]*models.QuerySuggestion, error)
+	// ReplaceAuto 用新挖掘的建议替换生效的自动建议，与人工建议或已停用的建议重复时保留原建议
+	ReplaceAuto(ctx context.Context, suggestions []*models.QuerySuggestion) error
+}
+
+// CommunitySummaryRepository 社区摘要仓储接口
+type CommunitySummaryRepository interface {
+	Create(ctx context.Context, summary *models.CommunitySummary) error
+	Update(ctx context.Context, summary *models.CommunitySummary) error
+	Delete(ctx context.Context, id uint64) error
+	ListByDomain(ctx context.Context, domainID uint64, offset, limit int) ([]*models.CommunitySummary, error)
+	ListByCommunityIDs(ctx context.Context, domainID uint64, communityIDs []string) ([]*models.CommunitySummary, error)
+	CountByDomain(ctx context.Context, domainID uint64) (int64, error)
 }
 
 // VectorRepository 向量数据库仓储接口
@@ -113,7 +215,7 @@ type VectorRepository interface {
 	Delete(ctx context.Context, collectionName string, ids []string) error
 	Update(ctx context.Context, collectionName string, vectors []VectorData) error
 
-	// 搜索
+	// 搜索，params支持 ef(int) 和 expr(string，基于metadata的过滤表达式)
 	Search(ctx context.Context, collectionName string, vectors [][]float32, topK int, params map[string]interface{}) ([]VectorSearchResult, error)
 
 	// 索引管理
@@ -124,7 +226,7 @@ type VectorRepository interface {
 	GetCollectionStats(ctx context.Context, collectionName string) (*VectorCollectionStats, error)
 }
 
-// GraphRepository 图数据库仓储接口
+// GraphRepository 图数据库仓储接口。本仓库尚未提供实现，未配置时Repo.Graph为nil，图谱相关服务返回不可用错误
 type GraphRepository interface {
 	// 实体操作
 	CreateEntity(ctx context.Context, entity *models.KnowledgeEntity) error
@@ -140,18 +242,21 @@ type GraphRepository interface {
 	DeleteRelation(ctx context.Context, id string) error
 	ListRelations(ctx context.Context, fromEntity, toEntity string, relationType string) ([]*models.KnowledgeRelation, error)
 
-	// 图遍历
+	// 图遍历。实现宜在查询中只展开在config.AsOf时间点t成立的关系，
+	// 即 valid_from <= t AND (valid_to IS NULL OR valid_to > t)，使Limit作用于满足条件的路径；
+	// 服务层还会剔除包含不成立关系的路径
 	TraverseGraph(ctx context.Context, config *models.GraphTraversal) (*models.GraphTraversalResult, error)
 	FindPath(ctx context.Context, fromEntity, toEntity string, maxDepth int) ([]*models.GraphPath, error)
 
-	// 图搜索
-	SearchEntities(ctx context.Context, query string, entityTypes []string, limit int) ([]*models.KnowledgeEntity, error)
+	// 图搜索。domainIDs和entityTypes为空时不按知识域或实体类型过滤，过滤在查询中进行，使limit作用于满足条件的实体
+	SearchEntities(ctx context.Context, query string, domainIDs []uint64, entityTypes []string, limit int) ([]*models.KnowledgeEntity, error)
 
 	// 统计
 	GetGraphStats(ctx context.Context) (*models.GraphStats, error)
 }
 
-// CacheRepository 缓存仓储接口
+// CacheRepository 缓存仓储接口。ttl单位为秒，0表示不过期；键不存在时Get、HGet、LPop和RPop
+// 返回包装了ErrNotFound的错误；字符串和字节切片按原样保存，其他值序列化为JSON
 type CacheRepository interface {
 	Set(ctx context.Context, key string, value interface{}, ttl int) error
 	Get(ctx context.Context, key string) (string, error)
@@ -159,6 +264,10 @@ type CacheRepository interface {
 	Exists(ctx context.Context, key string) (bool, error)
 	Expire(ctx context.Context, key string, ttl int) error
 	Keys(ctx context.Context, pattern string) ([]string, error)
+	// TTL 键剩余的过期时间，不过期时返回0
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Incr 原子地将整数值加一并返回新值，键不存在时从0开始
+	Incr(ctx context.Context, key string) (int64, error)
 
 	// 哈希操作
 	HSet(ctx context.Context, key, field string, value interface{}) error
@@ -181,6 +290,35 @@ type CacheRepository interface {
 	SCard(ctx context.Context, key string) (int64, error)
 }
 
+type remoteOnlyKey struct{}
+
+// WithRemoteOnly 返回要求缓存读写跳过本地层、只访问远端缓存的上下文，
+// 用于需要跨实例立即一致的键（如版本号）。对单层缓存没有影响
+func WithRemoteOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, remoteOnlyKey{}, true)
+}
+
+// IsRemoteOnly 上下文是否要求缓存读写跳过本地层
+func IsRemoteOnly(ctx context.Context) bool {
+	remoteOnly, _ := ctx.Value(remoteOnlyKey{}).(bool)
+	return remoteOnly
+}
+
+// EncodeCacheValue 缓存值的序列化：字符串和字节切片按原样保存，其他值序列化为JSON
+func EncodeCacheValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache value: %w", err)
+	}
+	return string(data), nil
+}
+
 // 向量数据结构
 type VectorData struct {
 	ID       string                 `json:"id"`
@@ -208,8 +346,14 @@ type Repository struct {
 	Document      DocumentRepository
 	DocumentChunk DocumentChunkRepository
 	Conversation  ConversationRepository
+	Memory        MemoryRepository
+	MemoryAudit   MemoryAuditRepository
 	Feedback      FeedbackRepository
 	SearchLog     SearchLogRepository
+	QualityPrior  QualityPriorRepository
+	Correction    CorrectionRepository
+	Suggestion    QuerySuggestionRepository
+	Community     CommunitySummaryRepository
 	Vector        VectorRepository
 	Graph         GraphRepository
 	Cache         CacheRepository
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// CommunitySummaryCollection 社区摘要向量集合
+const CommunitySummaryCollection = "community_summaries"
+
+// CommunitySummaryOptions 社区摘要参数
+type CommunitySummaryOptions struct {
+	MinCommunitySize int     // 参与摘要的最小社区规模
+	MaxEntities      int     // 提示词中每个社区最多包含的实体数
+	MaxRelations     int     // 提示词中每个社区最多包含的关系数
+	RetainOverlap    float64 // 生成失败时沿用成员Jaccard相似度达到该值的旧摘要，社区每次重新编号，不能按ID沿用
+}
+
+// DefaultCommunitySummaryOptions 默认社区摘要参数
+func DefaultCommunitySummaryOptions() CommunitySummaryOptions {
+	return CommunitySummaryOptions{
+		MinCommunitySize: 3,
+		MaxEntities:      30,
+		MaxRelations:     60,
+		RetainOverlap:    0.5,
+	}
+}
+
+// CommunitySummarizer 社区摘要服务，为图谱社区生成可检索的摘要
+type CommunitySummarizer struct {
+	analyzer  *GraphAnalyzer
+	summaries repository.CommunitySummaryRepository
+	vector    repository.VectorRepository
+	llm       ChatModel
+	embedder  Embedder
+	opts      CommunitySummaryOptions
+}
+
+// NewCommunitySummarizer 创建社区摘要服务
+func NewCommunitySummarizer(analyzer *GraphAnalyzer, summaries repository.CommunitySummaryRepository, vector repository.VectorRepository, llm ChatModel, embedder Embedder, opts CommunitySummaryOptions) *CommunitySummarizer {
+	return &CommunitySummarizer{
+		analyzer:  analyzer,
+		summaries: summaries,
+		vector:    vector,
+		llm:       llm,
+		embedder:  embedder,
+		opts:      opts,
+	}
+}
+
+// communityReport 大模型生成的社区报告
+type communityReport struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+const communitySummaryPrompt = `你是知识图谱分析助手。下面给出知识图谱中一个社区的实体和关系，请概括该社区讨论的主题、关键实体、反复出现的问题或模式。
+只输出JSON：{"title": "不超过20字的标题", "summary": "不超过300字的摘要"}`
+
+// Summarize 为知识域的所有社区生成摘要。社区成员和关系未变化时复用已有摘要。
+// 新摘要写入成功后才替换同一社区的旧摘要并删除已不存在的社区摘要，写入失败时旧摘要保持不变；
+// 单个社区生成失败时沿用成员与其大部分重合的旧摘要，没有时该社区暂无摘要。
+func (s *CommunitySummarizer) Summarize(ctx context.Context, domainID uint64) (*models.SummarizeCommunitiesResult, error) {
+	if s.llm == nil {
+		return nil, ErrLLMUnavailable
+	}
+	if s.embedder == nil {
+		return nil, ErrEmbedderUnavailable
+	}
+
+	kg, communities, err := s.analyzer.DetectCommunities(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.listAll(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	byFingerprint := make(map[string]*models.CommunitySummary, len(existing))
+	byCommunity := make(map[string]*models.CommunitySummary, len(existing))
+	for _, summary := range existing {
+		byFingerprint[summary.Fingerprint] = summary
+		byCommunity[summary.CommunityID] = summary
+	}
+
+	entities := make(map[string]*models.KnowledgeEntity, len(kg.Entities))
+	for i := range kg.Entities {
+		entities[kg.Entities[i].ID] = &kg.Entities[i]
+	}
+
+	result := &models.SummarizeCommunitiesResult{DomainID: domainID}
+	kept := make(map[uint64]bool)
+	var fresh []*models.CommunitySummary
+	for _, community := range communities {
+		if community.Size < s.opts.MinCommunitySize {
+			continue
+		}
+		result.Communities++
+
+		relations := communityRelations(kg, community)
+		fingerprint := communityFingerprint(community, relations)
+		if old, ok := byFingerprint[fingerprint]; ok {
+			result.Reused++
+			if old.CommunityID == community.ID {
+				kept[old.ID] = true
+				continue
+			}
+			// 社区重新编号但内容不变，复用摘要文本
+			fresh = append(fresh, &models.CommunitySummary{
+				DomainID:    domainID,
+				CommunityID: community.ID,
+				Title:       old.Title,
+				Summary:     old.Summary,
+				EntityIDs:   community.Members,
+				Size:        community.Size,
+				Fingerprint: fingerprint,
+			})
+			continue
+		}
+
+		report, err := s.summarizeCommunity(ctx, community, entities, relations)
+		if err != nil {
+			log.Printf("Warning: failed to summarize community %s of domain %d: %v", community.ID, domainID, err)
+			// 沿用的摘要不记录指纹，下次运行重新生成
+			if old := closestSummary(existing, community.Members, s.opts.RetainOverlap); old != nil {
+				fresh = append(fresh, &models.CommunitySummary{
+					DomainID:    domainID,
+					CommunityID: community.ID,
+					Title:       old.Title,
+					Summary:     old.Summary,
+					EntityIDs:   community.Members,
+					Size:        community.Size,
+				})
+			}
+			continue
+		}
+		fresh = append(fresh, &models.CommunitySummary{
+			DomainID:    domainID,
+			CommunityID: community.ID,
+			Title:       report.Title,
+			Summary:     report.Summary,
+			EntityIDs:   community.Members,
+			Size:        community.Size,
+			Fingerprint: fingerprint,
+		})
+		result.Generated++
+	}
+
+	// 同一社区的新摘要覆盖旧记录，向量按相同ID覆盖
+	for _, summary := range fresh {
+		if old, ok := byCommunity[summary.CommunityID]; ok && !kept[old.ID] {
+			summary.ID = old.ID
+			summary.CreatedAt = old.CreatedAt
+			kept[old.ID] = true
+		}
+	}
+	if err := s.store(ctx, fresh); err != nil {
+		return nil, err
+	}
+
+	// 新摘要全部写入后再删除过期摘要
+	var staleVectorIDs []string
+	for _, summary := range existing {
+		if kept[summary.ID] {
+			continue
+		}
+		if err := s.summaries.Delete(ctx, summary.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete community summary: %w", err)
+		}
+		staleVectorIDs = append(staleVectorIDs, summary.VectorID())
+		result.Removed++
+	}
+	if len(staleVectorIDs) > 0 {
+		if err := s.vector.Delete(ctx, CommunitySummaryCollection, staleVectorIDs); err != nil {
+			log.Printf("Warning: failed to delete community summary vectors: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// summarizeCommunity 调用大模型生成单个社区的报告
+func (s *CommunitySummarizer) summarizeCommunity(ctx context.Context, community models.GraphCommunity, entities map[string]*models.KnowledgeEntity, relations []models.KnowledgeRelation) (*communityReport, error) {
+	var b strings.Builder
+	b.WriteString("实体：\n")
+	for i, id := range community.Members {
+		if i >= s.opts.MaxEntities {
+			break
+		}
+		e := entities[id]
+		fmt.Fprintf(&b, "- %s (%s)", e.Name, e.Type)
+		if desc, ok := e.Properties["description"].(string); ok && desc != "" {
+			fmt.Fprintf(&b, ": %s", desc)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("关系：\n")
+	for i, r := range relations {
+		if i >= s.opts.MaxRelations {
+			break
+		}
+		fmt.Fprintf(&b, "- %s -[%s]-> %s\n", entities[r.FromEntity].Name, r.Type, entities[r.ToEntity].Name)
+	}
+
+	output, err := complete(ctx, s.llm, communitySummaryPrompt, b.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var report communityReport
+	if err := parseJSONResponse(output, &report); err != nil {
+		return nil, err
+	}
+	if report.Summary == "" {
+		return nil, fmt.Errorf("empty community summary")
+	}
+	return &report, nil
+}
+
+// store 保存摘要并写入向量库，已有ID的摘要覆盖原记录
+func (s *CommunitySummarizer) store(ctx context.Context, summaries []*models.CommunitySummary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(summaries))
+	for i, summary := range summaries {
+		texts[i] = summary.Title + "\n" + summary.Summary
+	}
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed community summaries: %w", err)
+	}
+	if err := EnsureCollection(ctx, s.vector, CommunitySummaryCollection, len(vectors[0])); err != nil {
+		return err
+	}
+
+	data := make([]repository.VectorData, len(summaries))
+	for i, summary := range summaries {
+		save := s.summaries.Create
+		if summary.ID != 0 {
+			save = s.summaries.Update
+		}
+		if err := save(ctx, summary); err != nil {
+			return fmt.Errorf("failed to save community summary: %w", err)
+		}
+		data[i] = repository.VectorData{
+			ID:     summary.VectorID(),
+			Vector: vectors[i],
+			Metadata: map[string]interface{}{
+				"domain_id":    summary.DomainID,
+				"community_id": summary.CommunityID,
+				"size":         summary.Size,
+			},
+		}
+	}
+	return s.vector.Update(ctx, CommunitySummaryCollection, data)
+}
+
+// closestSummary 成员与members的Jaccard相似度最高且不低于threshold的摘要
+func closestSummary(summaries []*models.CommunitySummary, members []string, threshold float64) *models.CommunitySummary {
+	current := make(map[string]bool, len(members))
+	for _, id := range members {
+		current[id] = true
+	}
+	var best *models.CommunitySummary
+	bestScore := threshold
+	for _, summary := range summaries {
+		previous := make(map[string]bool, len(summary.EntityIDs))
+		for _, id := range summary.EntityIDs {
+			previous[id] = true
+		}
+		if score := textutil.Jaccard(current, previous); score >= bestScore && score > 0 {
+			best, bestScore = summary, score
+		}
+	}
+	return best
+}
+
+// listAll 获取知识域的全部社区摘要
+func (s *CommunitySummarizer) listAll(ctx context.Context, domainID uint64) ([]*models.CommunitySummary, error) {
+	const pageSize = 200
+	var all []*models.CommunitySummary
+	for offset := 0; ; offset += pageSize {
+		page, err := s.summaries.ListByDomain(ctx, domainID, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+// communityRelations 社区内部的关系
+func communityRelations(kg *models.KnowledgeGraph, community models.GraphCommunity) []models.KnowledgeRelation {
+	members := make(map[string]bool, len(community.Members))
+	for _, id := range community.Members {
+		members[id] = true
+	}
+
+	var relations []models.KnowledgeRelation
+	for _, r := range kg.Relations {
+		if members[r.FromEntity] && members[r.ToEntity] {
+			relations = append(relations, r)
+		}
+	}
+	return relations
+}
+
+// communityFingerprint 基于成员和内部关系计算社区指纹
+func communityFingerprint(community models.GraphCommunity, relations []models.KnowledgeRelation) string {
+	parts := make([]string, 0, len(community.Members)+len(relations))
+	for _, id := range community.Members {
+		parts = append(parts, "e:"+id)
+	}
+	for _, r := range relations {
+		parts = append(parts, "r:"+r.FromEntity+"|"+r.Type+"|"+r.ToEntity)
+	}
+	sort.Strings(parts)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
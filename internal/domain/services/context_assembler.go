@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// Tokenizer 计算文本的token数，用于控制上下文预算
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// EstimateTokenizer 按字符类别估算token数的分词器，不依赖具体模型的词表。
+// 结果是估算值，与目标模型BPE分词器的实际token数会有出入，组装上下文时按SafetyMargin预留余量；
+// 需要精确计数时可注入基于模型词表的Tokenizer实现
+type EstimateTokenizer struct{}
+
+// CountTokens 估算token数
+func (EstimateTokenizer) CountTokens(text string) int {
+	return textutil.EstimateTokens(text)
+}
+
+// ContextAssemblerOptions 上下文组装参数
+type ContextAssemblerOptions struct {
+	MaxTokens        int     // 默认token预算
+	MaxTokensLimit   int     // 请求可指定的最大预算
+	SearchLimit      int     // 默认检索候选数
+	MemoryLimit      int     // 混入的记忆数上限
+	GraphEntities    int     // 展开关系的实体数上限
+	TriplesPerEntity int     // 每个实体列出的关系数上限
+	DedupThreshold   float64 // 与已选条目的词集合Jaccard相似度达到该值时视为重复
+	MinItemTokens    int     // 截断后正文少于该token数的条目不再放入
+	SafetyMargin     float64 // token数为估算值，按预算的该比例预留余量，使用精确分词器时可设为0
+}
+
+// DefaultContextAssemblerOptions 默认上下文组装参数
+func DefaultContextAssemblerOptions() ContextAssemblerOptions {
+	return ContextAssemblerOptions{
+		MaxTokens:        2000,
+		MaxTokensLimit:   32000,
+		SearchLimit:      20,
+		MemoryLimit:      5,
+		GraphEntities:    5,
+		TriplesPerEntity: 8,
+		DedupThreshold:   0.8,
+		MinItemTokens:    32,
+		SafetyMargin:     0.1,
+	}
+}
+
+// ContextAssembler 把检索结果组装为可直接注入Prompt的知识上下文：合并同一文档相邻或重叠的分块，
+// 去除重复内容，把用户记忆、图谱三元组和文档片段交替排列，并按token预算截断，条目带编号引用
+type ContextAssembler struct {
+	searcher  *Searcher
+	memories  *MemorySearcher
+	graph     repository.GraphRepository
+	tokenizer Tokenizer
+	opts      ContextAssemblerOptions
+}
+
+// NewContextAssembler 创建上下文组装服务，memories或graph为nil时不混入对应内容
+func NewContextAssembler(searcher *Searcher, memories *MemorySearcher, graph repository.GraphRepository, tokenizer Tokenizer, opts ContextAssemblerOptions) *ContextAssembler {
+	if tokenizer == nil {
+		tokenizer = EstimateTokenizer{}
+	}
+	return &ContextAssembler{searcher: searcher, memories: memories, graph: graph, tokenizer: tokenizer, opts: opts}
+}
+
+// contextItem 待放入上下文的条目
+type contextItem struct {
+	kind     string
+	ids      []string
+	title    string
+	source   string
+	content  string
+	score    float64
+	startPos int
+	endPos   int
+}
+
+const contextHeader = "以下是与问题相关的参考知识，引用时请使用方括号中的编号：\n\n"
+
+// Assemble 检索并组装上下文
+func (a *ContextAssembler) Assemble(ctx context.Context, req *models.ContextRequest) (*models.ContextResponse, error) {
+	start := time.Now()
+	budget := req.MaxTokens
+	if budget <= 0 {
+		budget = a.opts.MaxTokens
+	}
+	if budget > a.opts.MaxTokensLimit {
+		budget = a.opts.MaxTokensLimit
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = a.opts.SearchLimit
+	}
+
+	searchResp, err := a.searcher.Search(ctx, &models.SearchRequest{
+		Query:    req.Query,
+		DomainID: req.DomainID,
+		UserID:   req.UserID,
+		Filters:  req.Filters,
+		Options:  models.SearchOptions{Limit: limit, Mode: models.SearchModeLocal, IncludeContent: true},
+		Context:  req.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var documents, entities []models.SearchResult
+	for _, r := range searchResp.Results {
+		if r.Type == "entity" {
+			entities = append(entities, r)
+		} else {
+			documents = append(documents, r)
+		}
+	}
+	items := interleave(
+		a.memoryItems(ctx, req),
+		a.tripleItems(ctx, entities),
+		mergeChunks(documents),
+	)
+	items = a.dedup(items)
+
+	resp := &models.ContextResponse{
+		QueryID:   searchResp.QueryID,
+		Query:     req.Query,
+		MaxTokens: budget,
+		Search:    searchResp,
+	}
+	a.render(resp, items, budget-int(float64(budget)*a.opts.SafetyMargin))
+	resp.ProcessingMS = int(time.Since(start).Milliseconds())
+	return resp, nil
+}
+
+// memoryItems 检索用户记忆，失败时只记录警告
+func (a *ContextAssembler) memoryItems(ctx context.Context, req *models.ContextRequest) []contextItem {
+	if a.memories == nil || req.UserID == 0 || a.opts.MemoryLimit <= 0 {
+		return nil
+	}
+	resp, err := a.memories.Search(ctx, &models.MemoryQueryRequest{
+		UserID:   req.UserID,
+		DomainID: req.DomainID,
+		Query:    req.Query,
+		Limit:    a.opts.MemoryLimit,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to search memories for context: %v", err)
+		return nil
+	}
+	items := make([]contextItem, 0, len(resp.Memories))
+	for _, m := range resp.Memories {
+		items = append(items, contextItem{
+			kind:    models.ContextItemMemory,
+			ids:     []string{m.MemoryID},
+			title:   string(m.MemoryType),
+			source:  m.ConversationID,
+			content: m.Content,
+			score:   m.Score,
+		})
+	}
+	return items
+}
+
+// tripleItems 把实体结果展开为关系三元组，每个实体一个条目。图数据库不可用时只保留实体描述
+func (a *ContextAssembler) tripleItems(ctx context.Context, entities []models.SearchResult) []contextItem {
+	if len(entities) > a.opts.GraphEntities {
+		entities = entities[:a.opts.GraphEntities]
+	}
+	names := make(map[string]string)
+	for _, e := range entities {
+		names[e.ID] = e.Title
+	}
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		n := id
+		if a.graph != nil {
+			if entity, err := a.graph.GetEntity(ctx, id); err == nil {
+				n = entity.Name
+			}
+		}
+		names[id] = n
+		return n
+	}
+
+	items := make([]contextItem, 0, len(entities))
+	for _, e := range entities {
+		lines := []string{e.Content}
+		for _, rel := range a.relations(ctx, e.ID) {
+			lines = append(lines, fmt.Sprintf("%s -[%s]-> %s", name(rel.FromEntity), rel.Type, name(rel.ToEntity)))
+		}
+		items = append(items, contextItem{
+			kind:    models.ContextItemTriple,
+			ids:     []string{e.ID},
+			title:   e.Title,
+			source:  e.Source,
+			content: strings.Join(lines, "\n"),
+			score:   e.Score,
+		})
+	}
+	return items
+}
+
+// relations 实体当前成立的出边和入边，按置信度降序
+func (a *ContextAssembler) relations(ctx context.Context, entityID string) []*models.KnowledgeRelation {
+	if a.graph == nil {
+		return nil
+	}
+	var relations []*models.KnowledgeRelation
+	for _, ends := range [][2]string{{entityID, ""}, {"", entityID}} {
+		rels, err := a.graph.ListRelations(ctx, ends[0], ends[1], "")
+		if err != nil {
+			log.Printf("Warning: failed to list relations of entity %s: %v", entityID, err)
+			continue
+		}
+		for _, rel := range rels {
+			if rel.IsCurrent() {
+				relations = append(relations, rel)
+			}
+		}
+	}
+	sort.SliceStable(relations, func(i, j int) bool { return relations[i].Score > relations[j].Score })
+	if len(relations) > a.opts.TriplesPerEntity {
+		relations = relations[:a.opts.TriplesPerEntity]
+	}
+	return relations
+}
+
+// mergeChunks 合并同一文档中相邻或重叠的分块，重叠部分只保留一次。起止位置无效的结果保持独立，
+// 起止位置显示重叠但内容对不上的分块也不合并。
+// 合并后的条目取成员的最高分，并按分数降序排列
+func mergeChunks(results []models.SearchResult) []contextItem {
+	byDocument := make(map[string][]models.SearchResult)
+	var items []contextItem
+	for _, r := range results {
+		docID, _ := r.Metadata["document_id"].(string)
+		startPos, endPos := metadataInt(r.Metadata, "start_pos"), metadataInt(r.Metadata, "end_pos")
+		if r.Type != "chunk" || docID == "" || endPos <= startPos {
+			items = append(items, resultItem(r))
+			continue
+		}
+		byDocument[docID] = append(byDocument[docID], r)
+	}
+
+	for _, chunks := range byDocument {
+		sort.SliceStable(chunks, func(i, j int) bool {
+			return metadataInt(chunks[i].Metadata, "start_pos") < metadataInt(chunks[j].Metadata, "start_pos")
+		})
+		var cur *contextItem
+		for _, c := range chunks {
+			startPos, endPos := metadataInt(c.Metadata, "start_pos"), metadataInt(c.Metadata, "end_pos")
+			if cur != nil && startPos <= cur.endPos {
+				if overlap, ok := chunkOverlap(cur.content, c.Content, cur.endPos-startPos); ok {
+					// 跳过与已合并内容重叠的前缀
+					cur.content += string([]rune(c.Content)[overlap:])
+					if endPos > cur.endPos {
+						cur.endPos = endPos
+					}
+					if c.Score > cur.score {
+						cur.score = c.Score
+					}
+					cur.ids = append(cur.ids, c.ID)
+					continue
+				}
+			}
+			if cur != nil {
+				items = append(items, *cur)
+			}
+			item := resultItem(c)
+			cur = &item
+		}
+		items = append(items, *cur)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].score > items[j].score })
+	return items
+}
+
+// chunkOverlap 已合并内容与下一个分块开头重叠的字符数。先校验按起止位置推算的重叠expected，
+// 分块内容与起止位置不一致（如内容经过清洗）时按内容查找最长的重叠，内容对不上时返回false
+func chunkOverlap(merged, next string, expected int) (int, bool) {
+	a, b := []rune(merged), []rune(next)
+	if expected <= len(a) && expected <= len(b) && string(a[len(a)-expected:]) == string(b[:expected]) {
+		return expected, true
+	}
+	if strings.Contains(merged, next) {
+		return len(b), true
+	}
+	for n := min(len(a), len(b)) - 1; n > 0; n-- {
+		if string(a[len(a)-n:]) == string(b[:n]) {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// resultItem 将检索结果转换为文档条目
+func resultItem(r models.SearchResult) contextItem {
+	return contextItem{
+		kind:     models.ContextItemDocument,
+		ids:      []string{r.ID},
+		title:    r.Title,
+		source:   r.Source,
+		content:  r.Content,
+		score:    r.Score,
+		startPos: metadataInt(r.Metadata, "start_pos"),
+		endPos:   metadataInt(r.Metadata, "end_pos"),
+	}
+}
+
+// metadataInt 读取整数元数据，兼容JSON反序列化得到的float64
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// interleave 轮流从各列表中取条目，使预算截断时各类内容都能保留最相关的部分
+func interleave(lists ...[]contextItem) []contextItem {
+	var items []contextItem
+	for i := 0; ; i++ {
+		added := false
+		for _, list := range lists {
+			if i < len(list) {
+				items = append(items, list[i])
+				added = true
+			}
+		}
+		if !added {
+			return items
+		}
+	}
+}
+
+// dedup 去除空条目，以及被已选条目包含或与其词集合高度相似的条目
+func (a *ContextAssembler) dedup(items []contextItem) []contextItem {
+	var (
+		kept []contextItem
+		sets []map[string]bool
+	)
+	for _, item := range items {
+		content := strings.TrimSpace(item.content)
+		if content == "" {
+			continue
+		}
+		set := textutil.TokenSet(content)
+		duplicate := false
+		for i, k := range kept {
+			if strings.Contains(k.content, content) || textutil.Jaccard(set, sets[i]) >= a.opts.DedupThreshold {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		item.content = content
+		kept = append(kept, item)
+		sets = append(sets, set)
+	}
+	return kept
+}
+
+// contextKindLabels 条目类型在上下文中的标注
+var contextKindLabels = map[string]string{
+	models.ContextItemMemory:   "记忆",
+	models.ContextItemTriple:   "图谱",
+	models.ContextItemDocument: "文档",
+}
+
+// render 按预算依次放入条目并生成编号引用。放不下的条目在剩余预算足够时截断放入，否则丢弃
+func (a *ContextAssembler) render(resp *models.ContextResponse, items []contextItem, budget int) {
+	var b strings.Builder
+	b.WriteString(contextHeader)
+	used := a.tokenizer.CountTokens(contextHeader)
+
+	for _, item := range items {
+		index := len(resp.Citations) + 1
+		heading := strings.TrimSpace(fmt.Sprintf("[%d] (%s) %s", index, contextKindLabels[item.kind], item.title)) + "\n"
+		overhead := a.tokenizer.CountTokens(heading) + 1
+		content := item.content
+		tokens := a.tokenizer.CountTokens(content)
+		truncated := false
+		if used+overhead+tokens > budget {
+			remaining := budget - used - overhead
+			if remaining < a.opts.MinItemTokens {
+				resp.Dropped++
+				continue
+			}
+			content = a.truncateTokens(content, remaining)
+			tokens = a.tokenizer.CountTokens(content)
+			truncated = true
+		}
+
+		b.WriteString(heading)
+		b.WriteString(content)
+		b.WriteString("\n\n")
+		used += overhead + tokens
+		resp.Citations = append(resp.Citations, models.ContextCitation{
+			Index:     index,
+			Type:      item.kind,
+			IDs:       item.ids,
+			Title:     item.title,
+			Source:    item.source,
+			Score:     item.score,
+			StartPos:  item.startPos,
+			EndPos:    item.endPos,
+			Tokens:    tokens,
+			Truncated: truncated,
+		})
+	}
+
+	if len(resp.Citations) == 0 {
+		return
+	}
+	resp.Context = strings.TrimSpace(b.String())
+	resp.Tokens = a.tokenizer.CountTokens(resp.Context)
+}
+
+// truncateTokens 截取不超过maxTokens的最长前缀，末尾加省略号
+func (a *ContextAssembler) truncateTokens(content string, maxTokens int) string {
+	runes := []rune(content)
+	// 省略号计1个token
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if a.tokenizer.CountTokens(string(runes[:mid]))+1 <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo]) + "…"
+}
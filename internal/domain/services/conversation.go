@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// collectMaxRetries 合并对话遇到并发修改时的最大重试次数
+const collectMaxRetries = 5
+
+// ConversationCollector 对话收集服务。按ConversationID幂等写入，
+// 代理可以反复提交同一对话以追加新的轮次。消息上附带的反馈同步写入反馈表，
+// 并关联被评价回答的检索来源，供质量先验学习使用。
+type ConversationCollector struct {
+	conversations repository.ConversationRepository
+	domains       repository.DomainRepository
+	feedback      repository.FeedbackRepository
+	now           func() time.Time
+}
+
+// NewConversationCollector 创建对话收集服务
+func NewConversationCollector(conversations repository.ConversationRepository, domains repository.DomainRepository, feedback repository.FeedbackRepository) *ConversationCollector {
+	return &ConversationCollector{conversations: conversations, domains: domains, feedback: feedback, now: time.Now}
+}
+
+// Collect 写入对话：对话不存在时创建，已存在时合并消息、上下文和标签。
+// 追加了新消息的对话会清空ProcessedAt，以便重新提炼记忆。并发提交以对话的版本号做乐观锁，冲突时重新合并。
+func (s *ConversationCollector) Collect(ctx context.Context, req *models.CollectConversationRequest) (*models.CollectConversationResult, error) {
+	if _, err := s.domains.GetByID(ctx, req.DomainID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrDomainNotFound
+		}
+		return nil, err
+	}
+	for _, m := range req.Content.Messages {
+		if m.Feedback == nil {
+			continue
+		}
+		if _, ok := m.Feedback.FeedbackType(); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFeedbackType, m.Feedback.Type)
+		}
+	}
+
+	incoming := s.normalizeMessages(req.Content.Messages)
+	existing, err := s.conversations.GetByConversationID(ctx, req.ConversationID)
+	if errors.Is(err, repository.ErrNotFound) {
+		conversation := &models.Conversation{
+			ConversationID: req.ConversationID,
+			DomainID:       req.DomainID,
+			UserID:         req.UserID,
+			Content:        models.ConversationContent{Context: req.Content.Context},
+			Tags:           mergeLabels(nil, req.Tags),
+		}
+		added, _ := mergeMessages(&conversation.Content, incoming)
+		createErr := s.conversations.Create(ctx, conversation)
+		if createErr == nil {
+			recorded, err := s.recordFeedback(ctx, conversation, incoming)
+			if err != nil {
+				return nil, err
+			}
+			return &models.CollectConversationResult{
+				Conversation:     conversation.ToResponse(),
+				Created:          true,
+				MessagesAdded:    added,
+				FeedbackRecorded: recorded,
+			}, nil
+		}
+		// 并发创建同一对话时唯一索引冲突，改为合并到已创建的对话
+		existing, err = s.conversations.GetByConversationID(ctx, req.ConversationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create conversation: %w", createErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 并发提交同一对话时乐观锁冲突，重新读取后再合并，避免覆盖其他请求追加的消息
+	var added, updated int
+	for attempt := 0; ; attempt++ {
+		if existing.DomainID != req.DomainID {
+			return nil, fmt.Errorf("%w: conversation %s belongs to domain %d", ErrConversationConflict, req.ConversationID, existing.DomainID)
+		}
+		if req.UserID != 0 && existing.UserID != 0 && existing.UserID != req.UserID {
+			return nil, fmt.Errorf("%w: conversation %s belongs to user %d", ErrConversationConflict, req.ConversationID, existing.UserID)
+		}
+
+		added, updated = mergeMessages(&existing.Content, incoming)
+		mergeContext(&existing.Content.Context, req.Content.Context)
+		existing.Tags = mergeLabels(existing.Tags, req.Tags)
+		if existing.UserID == 0 {
+			existing.UserID = req.UserID
+		}
+		if added > 0 || updated > 0 {
+			existing.ProcessedAt = nil
+			existing.DistillAttempts = 0
+		}
+
+		err = s.conversations.Update(ctx, existing)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, repository.ErrStaleVersion) || attempt >= collectMaxRetries {
+			return nil, fmt.Errorf("failed to update conversation: %w", err)
+		}
+		if existing, err = s.conversations.GetByConversationID(ctx, req.ConversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	recorded, err := s.recordFeedback(ctx, existing, incoming)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CollectConversationResult{
+		Conversation:     existing.ToResponse(),
+		MessagesAdded:    added,
+		MessagesUpdated:  updated,
+		FeedbackRecorded: recorded,
+	}, nil
+}
+
+// recordFeedback 将本次提交的消息反馈写入反馈表，同一用户对同一回答只保留一条反馈。
+// 反馈的上下文记录被评价的助手消息及其检索来源；匿名对话的反馈只保存在消息中。
+func (s *ConversationCollector) recordFeedback(ctx context.Context, conversation *models.Conversation, incoming []models.Message) (int, error) {
+	if conversation.UserID == 0 {
+		return 0, nil
+	}
+	recorded := 0
+	for _, m := range incoming {
+		if m.Feedback == nil {
+			continue
+		}
+		feedbackType, _ := m.Feedback.FeedbackType()
+		target := answeredMessage(conversation.Content.Messages, m.ID)
+		if target == nil {
+			continue
+		}
+
+		queryID := messageFeedbackQueryID(conversation.ConversationID, target.ID)
+		feedback, err := s.feedback.GetByQueryAndUser(ctx, queryID, conversation.UserID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return recorded, err
+		}
+		if feedback == nil {
+			feedback = &models.Feedback{QueryID: queryID, UserID: conversation.UserID}
+		}
+		feedback.FeedbackType = feedbackType
+		feedback.Rating = messageFeedbackRating(m.Feedback, feedbackType)
+		feedback.Comment = m.Feedback.Reason
+		feedback.Context = map[string]interface{}{
+			"session_id":      conversation.Content.Context.SessionID,
+			"conversation_id": conversation.ConversationID,
+			"message_id":      target.ID,
+			"sources":         target.Metadata.RetrievalInfo.Sources,
+		}
+		if feedback.ID == 0 {
+			err = s.feedback.Create(ctx, feedback)
+		} else {
+			err = s.feedback.Update(ctx, feedback)
+		}
+		if err != nil {
+			return recorded, fmt.Errorf("failed to save message feedback: %w", err)
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// answeredMessage 反馈评价的助手消息：消息本身是助手消息时即为该消息，否则为其之前最近的助手消息
+func answeredMessage(messages []models.Message, messageID string) *models.Message {
+	pos := -1
+	for i := range messages {
+		if messages[i].ID == messageID {
+			pos = i
+			break
+		}
+	}
+	for i := pos; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			return &messages[i]
+		}
+	}
+	return nil
+}
+
+// messageFeedbackQueryID 消息级反馈的QueryID，由对话ID和消息ID生成，重复提交时定位到同一反馈
+func messageFeedbackQueryID(conversationID, messageID string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", conversationID, messageID)
+	return "msgfb_" + hex.EncodeToString(h.Sum(nil))[:24]
+}
+
+// messageFeedbackRating 未给出有效评分时按反馈类型取默认评分
+func messageFeedbackRating(f *models.MessageFeedback, feedbackType models.FeedbackType) int {
+	if f.Rating >= 1 && f.Rating <= 5 {
+		return f.Rating
+	}
+	switch feedbackType {
+	case models.FeedbackTypePositive:
+		return 5
+	case models.FeedbackTypeNegative:
+		return 1
+	}
+	return 3
+}
+
+// normalizeMessages 为缺少ID和时间戳的消息补全字段。
+// 缺少ID的消息使用角色、时间戳和内容的指纹作为ID，重复提交同一消息不会产生重复记录。
+// 时间戳先于指纹补全，同一批内容相同但没有时间戳的消息按提交顺序错开时间，不会合并为一条；
+// 这类消息重复提交时无法识别，需要幂等的客户端应提供消息ID
+func (s *ConversationCollector) normalizeMessages(messages []models.Message) []models.Message {
+	now := s.now()
+	normalized := make([]models.Message, 0, len(messages))
+	for i, m := range messages {
+		if m.Timestamp.IsZero() {
+			m.Timestamp = now.Add(time.Duration(i))
+		}
+		if m.ID == "" {
+			m.ID = messageFingerprint(m)
+		}
+		normalized = append(normalized, m)
+	}
+	return normalized
+}
+
+// messageFingerprint 消息内容指纹
+func messageFingerprint(m models.Message) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", m.Role, m.Timestamp.UTC().Format(time.RFC3339Nano), m.Content)
+	return "msg_" + hex.EncodeToString(h.Sum(nil))[:24]
+}
+
+// mergeMessages 按消息ID合并：已有的消息被覆盖，新消息追加，最终按时间排序。返回追加数和覆盖数。
+func mergeMessages(content *models.ConversationContent, incoming []models.Message) (added, updated int) {
+	index := make(map[string]int, len(content.Messages))
+	for i, m := range content.Messages {
+		index[m.ID] = i
+	}
+	for _, m := range incoming {
+		if i, ok := index[m.ID]; ok {
+			if content.Messages[i].Content != m.Content || content.Messages[i].Role != m.Role {
+				updated++
+			}
+			content.Messages[i] = m
+			continue
+		}
+		index[m.ID] = len(content.Messages)
+		content.Messages = append(content.Messages, m)
+		added++
+	}
+	sort.SliceStable(content.Messages, func(i, j int) bool {
+		return content.Messages[i].Timestamp.Before(content.Messages[j].Timestamp)
+	})
+	return added, updated
+}
+
+// mergeContext 用新提交的非空上下文字段覆盖已有字段
+func mergeContext(dst *models.Context, src models.Context) {
+	if src.SessionID != "" {
+		dst.SessionID = src.SessionID
+	}
+	if src.Platform != "" {
+		dst.Platform = src.Platform
+	}
+	if src.Source != "" {
+		dst.Source = src.Source
+	}
+	if src.UserAgent != "" {
+		dst.UserAgent = src.UserAgent
+	}
+	if src.IPAddress != "" {
+		dst.IPAddress = src.IPAddress
+	}
+	if src.Location != "" {
+		dst.Location = src.Location
+	}
+	if len(src.CustomFields) > 0 && dst.CustomFields == nil {
+		dst.CustomFields = make(map[string]interface{}, len(src.CustomFields))
+	}
+	for k, v := range src.CustomFields {
+		dst.CustomFields[k] = v
+	}
+}
@@ -0,0 +1,690 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// CorrectionCollection 纠正向量集合，向量为被纠正的原始问题，向量ID为纠正ID
+const CorrectionCollection = "corrections"
+
+// CorrectionLearnerOptions 纠正学习参数
+type CorrectionLearnerOptions struct {
+	FeedbackWindow     time.Duration // 首次运行时回溯的反馈时间窗口
+	RejectionTTL       time.Duration // 核实后不是纠正的来源在该时间内不再送入大模型
+	BatchSize          int           // 每批处理的反馈数
+	VerifyBatch        int           // 每次送入大模型核实的候选数
+	MaxChars           int           // 问题、回答和回复送入大模型的截断长度
+	MinConfidence      float64       // 低于该置信度的纠正被丢弃
+	DuplicateThreshold float64       // 原始问题的向量相似度达到该值且纠正内容相近时视为同一纠正
+	ContentSimilarity  float64       // 纠正内容词集合的Jaccard相似度阈值
+	ShownResults       int           // 检索反馈没有点击记录时，关联前N个展示的分块
+	SurfaceLimit       int           // 每次检索最多返回的纠正数
+	SurfaceThreshold   float64       // 纠正与检索查询的最低向量相似度
+}
+
+// DefaultCorrectionLearnerOptions 默认纠正学习参数
+func DefaultCorrectionLearnerOptions() CorrectionLearnerOptions {
+	return CorrectionLearnerOptions{
+		FeedbackWindow:     30 * 24 * time.Hour,
+		RejectionTTL:       30 * 24 * time.Hour,
+		BatchSize:          100,
+		VerifyBatch:        10,
+		MaxChars:           800,
+		MinConfidence:      0.6,
+		DuplicateThreshold: 0.9,
+		ContentSimilarity:  0.5,
+		ShownResults:       3,
+		SurfaceLimit:       2,
+		SurfaceThreshold:   0.8,
+	}
+}
+
+// CorrectionLearner 将用户对回答的纠正沉淀为知识：检测对话中用户纠正助手的轮次和带评论的负面反馈，
+// 经大模型核实并改写为独立陈述后，关联原始问题和产生错误回答的分块保存。
+// 语义相近的检索会把纠正排在结果最前面，并在被纠正的分块上标注。
+type CorrectionLearner struct {
+	corrections   repository.CorrectionRepository
+	feedback      repository.FeedbackRepository
+	searchLogs    repository.SearchLogRepository
+	conversations repository.ConversationRepository
+	vector        repository.VectorRepository
+	cache         repository.CacheRepository
+	llm           ChatModel
+	embedder      Embedder
+	opts          CorrectionLearnerOptions
+	now           func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewCorrectionLearner 创建纠正学习服务，反馈的处理进度和被否决的来源保存在cache中
+func NewCorrectionLearner(corrections repository.CorrectionRepository, feedback repository.FeedbackRepository, searchLogs repository.SearchLogRepository, conversations repository.ConversationRepository, vector repository.VectorRepository, cache repository.CacheRepository, llm ChatModel, embedder Embedder, opts CorrectionLearnerOptions) *CorrectionLearner {
+	return &CorrectionLearner{
+		corrections:   corrections,
+		feedback:      feedback,
+		searchLogs:    searchLogs,
+		conversations: conversations,
+		vector:        vector,
+		cache:         cache,
+		llm:           llm,
+		embedder:      embedder,
+		opts:          opts,
+		now:           time.Now,
+	}
+}
+
+// correctionCandidate 待核实的纠正
+type correctionCandidate struct {
+	ref        string // 来源标识
+	sourceType string
+	domainID   uint64
+	userID     uint64
+	queryID    string
+	query      string
+	answer     string
+	reply      string // 用户的纠正回复或反馈评论
+	chunkIDs   []string
+}
+
+// verifiedCorrection 大模型核实后的纠正
+type verifiedCorrection struct {
+	ID         string  `json:"id"`
+	Correction string  `json:"correction"`
+	Confidence float64 `json:"confidence"`
+}
+
+const correctionVerifyPrompt = `你是知识纠错助手。每个候选包含用户的原始问题、助手的回答，以及用户随后的回复或对回答的负面反馈。
+判断回复是否指出了回答中的错误并给出了正确的信息。只输出确实包含纠正信息的候选：把正确的知识改写为一句不依赖上下文的完整陈述，给出0-1的置信度。
+单纯表达不满、要求重试或没有给出正确信息的回复不要输出。
+只输出JSON：{"corrections": [{"id": "候选ID", "correction": "纠正后的知识", "confidence": 0.9}]}`
+
+// correctionCues 提示用户在纠正助手的常见表达，仅用于筛选候选，是否为纠正由大模型核实
+var correctionCues = []string{
+	"不对", "不是", "错了", "有误", "不正确", "不会", "并不", "其实", "应该是", "搞错", "不准确",
+	"wrong", "incorrect", "actually", "not true", "that's not", "that is not", "isn't", "doesn't",
+}
+
+// hasCorrectionCue 消息是否包含纠正的表达
+func hasCorrectionCue(content string) bool {
+	lower := strings.ToLower(strings.TrimSpace(content))
+	if lower == "no" || strings.HasPrefix(lower, "no,") || strings.HasPrefix(lower, "no ") {
+		return true
+	}
+	for _, cue := range correctionCues {
+		if strings.Contains(lower, cue) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromConversation 从对话中学习纠正：用户回复紧跟在助手回答之后，且包含纠正的表达或对回答点了踩
+func (l *CorrectionLearner) FromConversation(ctx context.Context, conversation *models.Conversation) (*models.CorrectionLearnResult, error) {
+	if l.llm == nil {
+		return nil, ErrLLMUnavailable
+	}
+	if l.embedder == nil {
+		return nil, ErrEmbedderUnavailable
+	}
+	result := &models.CorrectionLearnResult{}
+	if err := l.learn(ctx, l.conversationCandidates(conversation), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// conversationCandidates 检测对话中的纠正轮次
+func (l *CorrectionLearner) conversationCandidates(conversation *models.Conversation) []correctionCandidate {
+	var messages []models.Message
+	for _, m := range conversation.Content.Messages {
+		if m.Role != "system" && strings.TrimSpace(m.Content) != "" {
+			messages = append(messages, m)
+		}
+	}
+
+	var candidates []correctionCandidate
+	for i := 1; i < len(messages); i++ {
+		m, answer := messages[i], messages[i-1]
+		if m.Role != "user" || answer.Role != "assistant" {
+			continue
+		}
+		downvoted := false
+		if m.Feedback != nil {
+			t, _ := m.Feedback.FeedbackType()
+			downvoted = t == models.FeedbackTypeNegative
+		}
+		if !downvoted && !hasCorrectionCue(m.Content) {
+			continue
+		}
+		query := precedingUserMessage(messages[:i-1])
+		if query == "" {
+			continue
+		}
+		reply := m.Content
+		if m.Feedback != nil && m.Feedback.Reason != "" {
+			reply += "\n反馈：" + m.Feedback.Reason
+		}
+		candidates = append(candidates, correctionCandidate{
+			ref:        fmt.Sprintf("%s:%s:%s", models.CorrectionSourceConversation, conversation.ConversationID, m.ID),
+			sourceType: models.CorrectionSourceConversation,
+			domainID:   conversation.DomainID,
+			userID:     conversation.UserID,
+			query:      query,
+			answer:     answer.Content,
+			reply:      reply,
+			chunkIDs:   sourceChunkIDs(answer.Metadata.RetrievalInfo.Sources),
+		})
+	}
+	return candidates
+}
+
+// precedingUserMessage 最后一条用户消息的内容
+func precedingUserMessage(messages []models.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// sourceChunkIDs 回答检索来源中的分块ID，document:前缀的文档来源被忽略
+func sourceChunkIDs(sources []string) []string {
+	var ids []string
+	for _, source := range sources {
+		if strings.HasPrefix(source, models.QualityTargetDocument+":") {
+			continue
+		}
+		ids = append(ids, strings.TrimPrefix(source, models.QualityTargetChunk+":"))
+	}
+	return mergeLabels(nil, ids)
+}
+
+// correctionCursorKey 反馈学习进度的缓存键
+const correctionCursorKey = "correction:feedback_cursor"
+
+// feedbackCursor 反馈学习进度：已处理的最后一条反馈的更新时间和ID，
+// 按更新时间推进，评论被修改的反馈会重新处理
+type feedbackCursor struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint64    `json:"id"`
+}
+
+// loadCursor 读取反馈学习进度，没有进度时从FeedbackWindow之前开始
+func (l *CorrectionLearner) loadCursor(ctx context.Context) (feedbackCursor, error) {
+	cursor := feedbackCursor{UpdatedAt: l.now().Add(-l.opts.FeedbackWindow)}
+	data, err := l.cache.Get(repository.WithRemoteOnly(ctx), correctionCursorKey)
+	if errors.Is(err, repository.ErrNotFound) {
+		return cursor, nil
+	}
+	if err != nil {
+		return cursor, fmt.Errorf("failed to load feedback cursor: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &cursor); err != nil {
+		return cursor, fmt.Errorf("failed to decode feedback cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// saveCursor 保存反馈学习进度，不过期
+func (l *CorrectionLearner) saveCursor(ctx context.Context, cursor feedbackCursor) error {
+	if err := l.cache.Set(repository.WithRemoteOnly(ctx), correctionCursorKey, cursor, 0); err != nil {
+		return fmt.Errorf("failed to save feedback cursor: %w", err)
+	}
+	return nil
+}
+
+// LearnFromFeedback 从上次处理之后新建或修改的负面反馈评论中学习纠正，首次运行时回溯FeedbackWindow。
+// 处理进度保存在缓存中，每批处理完成后推进，重启或多实例运行时不会重复处理
+func (l *CorrectionLearner) LearnFromFeedback(ctx context.Context) (*models.CorrectionLearnResult, error) {
+	if l.llm == nil {
+		return nil, ErrLLMUnavailable
+	}
+	if l.embedder == nil {
+		return nil, ErrEmbedderUnavailable
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cursor, err := l.loadCursor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.CorrectionLearnResult{}
+	for {
+		batch, err := l.feedback.ListUpdatedAfter(ctx, cursor.UpdatedAt, cursor.ID, l.opts.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			return result, nil
+		}
+		candidates, err := l.feedbackCandidates(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.learn(ctx, candidates, result); err != nil {
+			return nil, err
+		}
+		last := batch[len(batch)-1]
+		cursor = feedbackCursor{UpdatedAt: last.UpdatedAt, ID: last.ID}
+		if err := l.saveCursor(ctx, cursor); err != nil {
+			return nil, err
+		}
+		if len(batch) < l.opts.BatchSize {
+			return result, nil
+		}
+	}
+}
+
+// feedbackCandidates 将带评论的负面反馈转换为候选纠正。消息级反馈从对话中取原始问题和回答，
+// 检索反馈从检索日志中取查询和展示的分块。
+func (l *CorrectionLearner) feedbackCandidates(ctx context.Context, batch []*models.Feedback) ([]correctionCandidate, error) {
+	var (
+		negative []*models.Feedback
+		contexts []*models.FeedbackContext
+		queryIDs []string
+	)
+	for _, f := range batch {
+		if f.FeedbackType != models.FeedbackTypeNegative || strings.TrimSpace(f.Comment) == "" {
+			continue
+		}
+		fc, err := f.ParseContext()
+		if err != nil {
+			fc = &models.FeedbackContext{}
+		}
+		negative = append(negative, f)
+		contexts = append(contexts, fc)
+		if fc.MessageID == "" {
+			queryIDs = append(queryIDs, f.QueryID)
+		}
+	}
+	logs, err := l.searchLogs.GetByQueryIDs(ctx, queryIDs)
+	if err != nil {
+		return nil, err
+	}
+	byQuery := make(map[string]*models.SearchLog, len(logs))
+	for _, searchLog := range logs {
+		byQuery[searchLog.QueryID] = searchLog
+	}
+
+	conversations := make(map[string]*models.Conversation)
+	var candidates []correctionCandidate
+	for i, f := range negative {
+		fc := contexts[i]
+		candidate := correctionCandidate{
+			ref:        fmt.Sprintf("%s:%d:%d", models.CorrectionSourceFeedback, f.ID, f.UpdatedAt.Unix()),
+			sourceType: models.CorrectionSourceFeedback,
+			userID:     f.UserID,
+			reply:      f.Comment,
+		}
+		if fc.MessageID != "" {
+			conversation, ok := conversations[fc.ConversationID]
+			if !ok {
+				conversation, err = l.conversations.GetByConversationID(ctx, fc.ConversationID)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return nil, err
+				}
+				conversations[fc.ConversationID] = conversation
+			}
+			if conversation == nil || !l.fromMessage(&candidate, conversation, fc.MessageID) {
+				continue
+			}
+			candidate.chunkIDs = sourceChunkIDs(fc.Sources)
+		} else {
+			searchLog := byQuery[f.QueryID]
+			if searchLog == nil {
+				continue
+			}
+			candidate.domainID = searchLog.DomainID
+			candidate.queryID = searchLog.QueryID
+			candidate.query = searchLog.QueryText
+			candidate.answer, candidate.chunkIDs = l.shownChunks(fc, searchLog.Results.Results)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// fromMessage 以被评价的助手消息和其之前的用户问题补全候选，找不到时返回false
+func (l *CorrectionLearner) fromMessage(candidate *correctionCandidate, conversation *models.Conversation, messageID string) bool {
+	messages := conversation.Content.Messages
+	for i, m := range messages {
+		if m.ID != messageID {
+			continue
+		}
+		candidate.domainID = conversation.DomainID
+		candidate.answer = m.Content
+		candidate.query = precedingUserMessage(messages[:i])
+		return candidate.query != ""
+	}
+	return false
+}
+
+// shownChunks 检索反馈关联的分块：有点击时为点击的分块，否则为前几个展示的分块。
+// 检索日志不保存结果内容，以结果标题作为回答。
+func (l *CorrectionLearner) shownChunks(fc *models.FeedbackContext, shown []models.SearchResult) (string, []string) {
+	clicked := make(map[string]bool, len(fc.ClickedResults))
+	for _, id := range fc.ClickedResults {
+		clicked[id] = true
+	}
+	var (
+		titles []string
+		ids    []string
+	)
+	for rank, r := range shown {
+		if len(clicked) > 0 && !clicked[r.ID] || len(clicked) == 0 && rank >= l.opts.ShownResults {
+			continue
+		}
+		if r.Title != "" {
+			titles = append(titles, r.Title)
+		}
+		if r.Type == models.QualityTargetChunk {
+			ids = append(ids, r.ID)
+		}
+	}
+	return strings.Join(titles, "；"), ids
+}
+
+// learn 跳过已学习和已被否决的来源，核实候选并保存纠正，没有核实为纠正的来源记录为已否决
+func (l *CorrectionLearner) learn(ctx context.Context, candidates []correctionCandidate, result *models.CorrectionLearnResult) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+	refs := make([]string, len(candidates))
+	for i, c := range candidates {
+		refs[i] = c.ref
+	}
+	existing, err := l.corrections.ExistingSourceRefs(ctx, refs)
+	if err != nil {
+		return err
+	}
+	fresh := candidates[:0]
+	for _, c := range candidates {
+		if !existing[c.ref] && !l.rejected(ctx, c.ref) {
+			fresh = append(fresh, c)
+		}
+	}
+	result.Candidates += len(fresh)
+
+	for start := 0; start < len(fresh); start += l.opts.VerifyBatch {
+		end := start + l.opts.VerifyBatch
+		if end > len(fresh) {
+			end = len(fresh)
+		}
+		group := fresh[start:end]
+		verified, err := l.verify(ctx, group)
+		if err != nil {
+			return err
+		}
+		if err := l.store(ctx, group, verified, result); err != nil {
+			return err
+		}
+		for i, c := range group {
+			if _, ok := verified[i]; !ok {
+				l.reject(ctx, c.ref)
+				result.Rejected++
+			}
+		}
+	}
+	return nil
+}
+
+// correctionRejectedKey 被否决来源的缓存键
+func correctionRejectedKey(ref string) string {
+	return "correction:rejected:" + ref
+}
+
+// rejected 来源是否已被大模型否决，读取失败时视为未否决
+func (l *CorrectionLearner) rejected(ctx context.Context, ref string) bool {
+	exists, err := l.cache.Exists(ctx, correctionRejectedKey(ref))
+	if err != nil {
+		log.Printf("Warning: failed to check rejected correction source %s: %v", ref, err)
+		return false
+	}
+	return exists
+}
+
+// reject 记录被否决的来源，RejectionTTL内不再核实。反馈的来源标识包含更新时间，修改评论后会重新核实
+func (l *CorrectionLearner) reject(ctx context.Context, ref string) {
+	if err := l.cache.Set(ctx, correctionRejectedKey(ref), 1, int(l.opts.RejectionTTL.Seconds())); err != nil {
+		log.Printf("Warning: failed to record rejected correction source %s: %v", ref, err)
+	}
+}
+
+// verify 调用大模型核实一组候选，返回候选下标到纠正内容的映射
+func (l *CorrectionLearner) verify(ctx context.Context, group []correctionCandidate) (map[int]verifiedCorrection, error) {
+	var b strings.Builder
+	for i, c := range group {
+		fmt.Fprintf(&b, "候选ID：%d\n问题：%s\n回答：%s\n回复：%s\n\n", i,
+			textutil.Truncate(c.query, l.opts.MaxChars),
+			textutil.Truncate(c.answer, l.opts.MaxChars),
+			textutil.Truncate(c.reply, l.opts.MaxChars))
+	}
+	output, err := complete(ctx, l.llm, correctionVerifyPrompt, b.String())
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Corrections []verifiedCorrection `json:"corrections"`
+	}
+	if err := parseJSONResponse(output, &parsed); err != nil {
+		return nil, err
+	}
+
+	verified := make(map[int]verifiedCorrection, len(parsed.Corrections))
+	for _, v := range parsed.Corrections {
+		var i int
+		if _, err := fmt.Sscanf(v.ID, "%d", &i); err != nil || i < 0 || i >= len(group) {
+			continue
+		}
+		v.Correction = strings.TrimSpace(v.Correction)
+		v.Confidence = clamp01(v.Confidence)
+		if v.Correction == "" || v.Confidence < l.opts.MinConfidence {
+			continue
+		}
+		verified[i] = v
+	}
+	return verified, nil
+}
+
+// store 保存核实后的纠正。与已有纠正相同的只记录来源并增加已有纠正的确认次数
+func (l *CorrectionLearner) store(ctx context.Context, group []correctionCandidate, verified map[int]verifiedCorrection, result *models.CorrectionLearnResult) error {
+	if len(verified) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(verified))
+	texts := make([]string, 0, len(verified))
+	for i := range group {
+		if _, ok := verified[i]; ok {
+			indexes = append(indexes, i)
+			texts = append(texts, group[i].query)
+		}
+	}
+	vectors, err := l.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed corrections: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(texts), len(vectors))
+	}
+	if err := EnsureCollection(ctx, l.vector, CorrectionCollection, len(vectors[0])); err != nil {
+		return err
+	}
+
+	now := l.now()
+	for n, i := range indexes {
+		c, v := group[i], verified[i]
+		correction := &models.Correction{
+			CorrectionID:  NewID("corr"),
+			DomainID:      c.domainID,
+			UserID:        c.userID,
+			QueryID:       c.queryID,
+			Query:         c.query,
+			Answer:        textutil.Truncate(c.answer, l.opts.MaxChars),
+			Content:       v.Correction,
+			ChunkIDs:      c.chunkIDs,
+			SourceType:    c.sourceType,
+			SourceRef:     c.ref,
+			Confidence:    v.Confidence,
+			Confirmations: 1,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+
+		original, err := l.findDuplicate(ctx, correction, vectors[n])
+		if err != nil {
+			return err
+		}
+		if original != nil {
+			original.Confirmations++
+			original.ChunkIDs = mergeLabels(original.ChunkIDs, correction.ChunkIDs)
+			if correction.Confidence > original.Confidence {
+				original.Confidence = correction.Confidence
+			}
+			original.UpdatedAt = now
+			if err := l.corrections.Update(ctx, original); err != nil {
+				return fmt.Errorf("failed to confirm correction: %w", err)
+			}
+			correction.DuplicateOf = original.CorrectionID
+			correction.Confirmations = 0
+			if err := l.corrections.Create(ctx, correction); err != nil {
+				return fmt.Errorf("failed to save correction: %w", err)
+			}
+			result.Confirmed++
+			continue
+		}
+
+		// 先写向量再写记录：向量写入失败时不会留下被当作已学习的来源
+		data := []repository.VectorData{{
+			ID:     correction.CorrectionID,
+			Vector: vectors[n],
+			Metadata: map[string]interface{}{
+				"correction_id": correction.CorrectionID,
+				"domain_id":     correction.DomainID,
+				"created_at":    correction.CreatedAt.Unix(),
+			},
+		}}
+		if err := l.vector.Insert(ctx, CorrectionCollection, data); err != nil {
+			return fmt.Errorf("failed to insert correction vector: %w", err)
+		}
+		if err := l.corrections.Create(ctx, correction); err != nil {
+			return fmt.Errorf("failed to save correction: %w", err)
+		}
+		result.Created = append(result.Created, correction.CorrectionID)
+	}
+	return nil
+}
+
+// findDuplicate 查找原始问题语义相同且纠正内容相近的已有纠正
+func (l *CorrectionLearner) findDuplicate(ctx context.Context, correction *models.Correction, vector []float32) (*models.Correction, error) {
+	hits, err := l.vector.Search(ctx, CorrectionCollection, [][]float32{vector}, 3, domainFilter(correction.DomainID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search corrections: %w", err)
+	}
+	var ids []string
+	for _, hit := range hits {
+		if hit.Score >= l.opts.DuplicateThreshold {
+			ids = append(ids, hit.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	candidates, err := l.corrections.GetByCorrectionIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	content := textutil.TokenSet(correction.Content)
+	for _, c := range candidates {
+		if c.DomainID == correction.DomainID && textutil.Jaccard(content, textutil.TokenSet(c.Content)) >= l.opts.ContentSimilarity {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// Surface 将与查询语义相近的纠正排在结果最前面，并在被纠正的分块结果上标注corrected_by。
+// 纠正集合不存在或检索失败时原样返回结果。
+func (l *CorrectionLearner) Surface(ctx context.Context, query string, domainID uint64, results []models.SearchResult) []models.SearchResult {
+	if l == nil || l.embedder == nil || l.vector == nil {
+		return results
+	}
+	exists, err := l.vector.HasCollection(ctx, CorrectionCollection)
+	if err != nil || !exists {
+		return results
+	}
+	vector, err := embedOne(ctx, l.embedder, query)
+	if err != nil {
+		return results
+	}
+	hits, err := l.vector.Search(ctx, CorrectionCollection, [][]float32{vector}, l.opts.SurfaceLimit, domainFilter(domainID))
+	if err != nil {
+		return results
+	}
+
+	scores := make(map[string]float64, len(hits))
+	var ids []string
+	for _, hit := range hits {
+		if hit.Score >= l.opts.SurfaceThreshold {
+			scores[hit.ID] = hit.Score
+			ids = append(ids, hit.ID)
+		}
+	}
+	corrections, err := l.corrections.GetByCorrectionIDs(ctx, ids)
+	if err != nil || len(corrections) == 0 {
+		return results
+	}
+
+	byID := make(map[string]*models.Correction, len(corrections))
+	correctedBy := make(map[string][]string)
+	for _, c := range corrections {
+		byID[c.CorrectionID] = c
+		for _, chunkID := range c.ChunkIDs {
+			correctedBy[chunkID] = append(correctedBy[chunkID], c.CorrectionID)
+		}
+	}
+	surfaced := make([]models.SearchResult, 0, len(ids)+len(results))
+	for _, id := range ids {
+		c, ok := byID[id]
+		if !ok {
+			continue
+		}
+		surfaced = append(surfaced, models.SearchResult{
+			ID:      c.CorrectionID,
+			Type:    models.CorrectionResultType,
+			Title:   c.Query,
+			Content: c.Content,
+			Source:  c.SourceRef,
+			Score:   scores[id],
+			Metadata: map[string]interface{}{
+				"query":         c.Query,
+				"chunk_ids":     c.ChunkIDs,
+				"confirmations": c.Confirmations,
+				"confidence":    c.Confidence,
+			},
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	for _, r := range results {
+		if ids, ok := correctedBy[r.ID]; ok && r.Type == models.QualityTargetChunk {
+			if r.Metadata == nil {
+				r.Metadata = make(map[string]interface{})
+			}
+			r.Metadata["corrected_by"] = ids
+		}
+		surfaced = append(surfaced, r)
+	}
+	return surfaced
+}
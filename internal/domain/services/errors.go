@@ -0,0 +1,44 @@
+package services
+
+import "errors"
+
+var (
+	// ErrGraphUnavailable 图数据库未配置
+	ErrGraphUnavailable = errors.New("graph repository is not configured")
+	// ErrLLMUnavailable 大模型未配置
+	ErrLLMUnavailable = errors.New("llm is not configured")
+	// ErrEmbedderUnavailable 向量化模型未配置
+	ErrEmbedderUnavailable = errors.New("embedder is not configured")
+	// ErrDomainRequired 请求缺少知识域
+	ErrDomainRequired = errors.New("domain_id is required")
+	// ErrUnsupportedFormat 不支持的导入导出格式
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrInvalidGraphFile 导入的图谱文件无法解析
+	ErrInvalidGraphFile = errors.New("invalid graph file")
+	// ErrSchemaViolation 实体或关系类型不符合知识域的图谱模式
+	ErrSchemaViolation = errors.New("graph schema violation")
+	// ErrEntityNotFound 图谱实体不存在
+	ErrEntityNotFound = errors.New("entity not found")
+	// ErrDomainNotFound 知识域不存在
+	ErrDomainNotFound = errors.New("domain not found")
+	// ErrConversationConflict 对话已属于其他知识域或用户
+	ErrConversationConflict = errors.New("conversation conflict")
+	// ErrInvalidMemoryType 不支持的记忆类型
+	ErrInvalidMemoryType = errors.New("invalid memory type")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("user not found")
+	// ErrMemoryNotFound 记忆不存在或不属于该用户
+	ErrMemoryNotFound = errors.New("memory not found")
+	// ErrInvalidMemoryRequest 记忆管理请求参数无效
+	ErrInvalidMemoryRequest = errors.New("invalid memory request")
+	// ErrInvalidFeedbackType 不支持的反馈类型
+	ErrInvalidFeedbackType = errors.New("invalid feedback type")
+	// ErrInvalidFacet 不支持的分面
+	ErrInvalidFacet = errors.New("invalid facet")
+	// ErrSuggestionNotFound 查询建议不存在
+	ErrSuggestionNotFound = errors.New("query suggestion not found")
+	// ErrInvalidSuggestion 查询建议参数无效
+	ErrInvalidSuggestion = errors.New("invalid query suggestion")
+	// ErrSearchLogNotFound 反馈引用的检索记录不存在
+	ErrSearchLogNotFound = errors.New("search log not found")
+)
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// 分面名称，同时作为SearchRequest.Filters中按分面取值收窄结果的键
+const (
+	FacetDomain      = "domain"       // 知识域ID
+	FacetDocument    = "document"     // 文档ID
+	FacetTag         = "tag"          // 文档标签
+	FacetContentType = "content_type" // 文档内容类型
+	FacetSourceType  = "source_type"  // 结果类型：chunk, entity, correction
+	FacetTime        = "time"         // 创建时间区间
+)
+
+// facetsFilterKey Filters中指定需要计算的分面的键，未指定时不计算分面
+const facetsFilterKey = "facets"
+
+// allFacets 全部分面，也是Aggregations中的默认顺序
+var allFacets = []string{FacetDomain, FacetDocument, FacetTag, FacetContentType, FacetSourceType, FacetTime}
+
+// 时间分面的区间，按距今时长划分，互不重叠
+var timeBuckets = []struct {
+	value string
+	label string
+	age   time.Duration
+}{
+	{"last_day", "最近一天", 24 * time.Hour},
+	{"last_week", "最近一周", 7 * 24 * time.Hour},
+	{"last_month", "最近一月", 30 * 24 * time.Hour},
+	{"last_year", "最近一年", 365 * 24 * time.Hour},
+}
+
+const timeBucketOlder = "older"
+
+// FacetOptions 分面参数
+type FacetOptions struct {
+	MaxBuckets int // 每个分面返回的取值数上限，按命中数降序截断
+	Candidates int // 请求分面时每路检索至少取的候选数，分面统计在这些候选上进行
+}
+
+// DefaultFacetOptions 默认分面参数
+func DefaultFacetOptions() FacetOptions {
+	return FacetOptions{MaxBuckets: 20, Candidates: 100}
+}
+
+// Faceter 在全部命中结果上计算分面，并按Filters中的分面取值收窄结果，供检索界面的筛选侧栏使用
+type Faceter struct {
+	documents repository.DocumentRepository
+	domains   repository.DomainRepository
+	opts      FacetOptions
+	now       func() time.Time
+}
+
+// NewFaceter 创建分面服务
+func NewFaceter(documents repository.DocumentRepository, domains repository.DomainRepository, opts FacetOptions) *Faceter {
+	return &Faceter{documents: documents, domains: domains, opts: opts, now: time.Now}
+}
+
+// facetValue 结果在某个分面上的取值
+type facetValue struct {
+	value string
+	label string
+}
+
+// Apply 按Filters中的分面取值收窄结果，并在收窄后的结果上计算请求的分面。
+// 同一分面的多个取值之间为或，不同分面之间为且
+func (f *Faceter) Apply(ctx context.Context, filters map[string]interface{}, results []models.SearchResult) ([]models.SearchResult, map[string]interface{}, error) {
+	if f == nil {
+		return results, nil, nil
+	}
+	requested, err := requestedFacets(filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	narrowing := make(map[string]map[string]bool)
+	for _, facet := range allFacets {
+		if values := filterStrings(filters[facet]); len(values) > 0 {
+			narrowing[facet] = make(map[string]bool, len(values))
+			for _, v := range values {
+				narrowing[facet][v] = true
+			}
+		}
+	}
+	if len(requested) == 0 && len(narrowing) == 0 {
+		return results, nil, nil
+	}
+
+	values := f.values(ctx, results)
+	narrowed := results[:0]
+	var kept []map[string][]facetValue
+	for i, r := range results {
+		if matchesFacets(values[i], narrowing) {
+			narrowed = append(narrowed, r)
+			kept = append(kept, values[i])
+		}
+	}
+
+	aggregations := make(map[string]interface{}, len(requested))
+	for _, facet := range requested {
+		aggregations[facet] = f.count(facet, kept)
+	}
+	return narrowed, aggregations, nil
+}
+
+// Pushdown 把Filters中的分面取值转换为下推到检索器的过滤条件，并返回每路检索至少应取的候选数。
+// 未按分面过滤时过滤条件为nil，未请求分面时候选数为0
+func (f *Faceter) Pushdown(filters map[string]interface{}) (*RetrievalFilter, int, error) {
+	if f == nil {
+		return nil, 0, nil
+	}
+	requested, err := requestedFacets(filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pushed := &RetrievalFilter{
+		DocumentIDs:  filterStrings(filters[FacetDocument]),
+		Tags:         filterStrings(filters[FacetTag]),
+		ContentTypes: filterStrings(filters[FacetContentType]),
+		SourceTypes:  filterStrings(filters[FacetSourceType]),
+	}
+	for _, v := range filterStrings(filters[FacetDomain]) {
+		// 无法解析的取值不下推，由收窄过滤掉全部结果
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			pushed.DomainIDs = append(pushed.DomainIDs, id)
+		}
+	}
+	pushed.Created = timeRanges(filterStrings(filters[FacetTime]), f.now())
+
+	empty := len(pushed.DomainIDs) == 0 && len(pushed.DocumentIDs) == 0 && len(pushed.Tags) == 0 &&
+		len(pushed.ContentTypes) == 0 && len(pushed.SourceTypes) == 0 && len(pushed.Created) == 0
+	if empty {
+		pushed = nil
+	}
+	// 分面取值已下推到检索器，只有统计分面时才需要放大候选数
+	if len(requested) == 0 {
+		return pushed, 0, nil
+	}
+	return pushed, f.opts.Candidates, nil
+}
+
+// timeRanges 把时间分面的取值转换为创建时间区间，与timeBucket的划分一致
+func timeRanges(values []string, now time.Time) []models.TimeRange {
+	var ranges []models.TimeRange
+	for _, v := range values {
+		var newer time.Duration // 区间较新一端距今的时长
+		for i, b := range timeBuckets {
+			if i > 0 {
+				newer = timeBuckets[i-1].age
+			}
+			if v == b.value {
+				start, end := now.Add(-b.age), now.Add(-newer)
+				r := models.TimeRange{Start: &start}
+				if i > 0 {
+					r.End = &end
+				}
+				ranges = append(ranges, r)
+			}
+		}
+		if v == timeBucketOlder {
+			end := now.Add(-timeBuckets[len(timeBuckets)-1].age)
+			ranges = append(ranges, models.TimeRange{End: &end})
+		}
+	}
+	return ranges
+}
+
+// requestedFacets 解析Filters中指定的分面，未指定时返回nil，避免为不需要分面的检索放大候选数
+func requestedFacets(filters map[string]interface{}) ([]string, error) {
+	raw, ok := filters[facetsFilterKey]
+	if !ok {
+		return nil, nil
+	}
+	valid := make(map[string]bool, len(allFacets))
+	for _, facet := range allFacets {
+		valid[facet] = true
+	}
+	var facets []string
+	for _, facet := range filterStrings(raw) {
+		if !valid[facet] {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFacet, facet)
+		}
+		facets = append(facets, facet)
+	}
+	return mergeLabels(nil, facets), nil
+}
+
+// filterStrings 把过滤值统一为字符串列表，支持单个值和数组
+func filterStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, filterStrings(item)...)
+		}
+		return values
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// matchesFacets 结果是否满足全部分面过滤
+func matchesFacets(values map[string][]facetValue, narrowing map[string]map[string]bool) bool {
+	for facet, allowed := range narrowing {
+		matched := false
+		for _, v := range values[facet] {
+			if allowed[v.value] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// values 计算每个结果在各分面上的取值。分块结果通过所属文档取得知识域、标签和内容类型，
+// 文档或知识域加载失败时对应分面缺失
+func (f *Faceter) values(ctx context.Context, results []models.SearchResult) []map[string][]facetValue {
+	documents := f.loadDocuments(ctx, results)
+	domainNames := f.loadDomainNames(ctx, results, documents)
+	now := f.now()
+
+	all := make([]map[string][]facetValue, len(results))
+	for i, r := range results {
+		values := map[string][]facetValue{
+			FacetSourceType: {{value: r.Type}},
+		}
+		domainID := metadataUint(r.Metadata, "domain_id")
+		if docID := resultDocumentID(r); docID != "" {
+			values[FacetDocument] = []facetValue{{value: docID}}
+			if doc, ok := documents[docID]; ok {
+				values[FacetDocument][0].label = doc.Title
+				domainID = doc.DomainID
+				if doc.ContentType != "" {
+					values[FacetContentType] = []facetValue{{value: doc.ContentType}}
+				}
+				for _, tag := range mergeLabels(nil, doc.Tags) {
+					values[FacetTag] = append(values[FacetTag], facetValue{value: tag})
+				}
+			}
+		}
+		if domainID != 0 {
+			values[FacetDomain] = []facetValue{{value: strconv.FormatUint(domainID, 10), label: domainNames[domainID]}}
+		}
+		if bucket, label := timeBucket(r.CreatedAt, now); bucket != "" {
+			values[FacetTime] = []facetValue{{value: bucket, label: label}}
+		}
+		all[i] = values
+	}
+	return all
+}
+
+// resultDocumentID 结果所属的文档，分块取元数据中的文档ID，文档结果取自身ID
+func resultDocumentID(r models.SearchResult) string {
+	if docID, ok := r.Metadata["document_id"].(string); ok && docID != "" {
+		return docID
+	}
+	if r.Type == "document" {
+		return r.ID
+	}
+	return ""
+}
+
+// loadDocuments 批量加载结果所属的文档
+func (f *Faceter) loadDocuments(ctx context.Context, results []models.SearchResult) map[string]*models.Document {
+	var ids []string
+	for _, r := range results {
+		if docID := resultDocumentID(r); docID != "" {
+			ids = append(ids, docID)
+		}
+	}
+	documents := make(map[string]*models.Document)
+	if len(ids) == 0 || f.documents == nil {
+		return documents
+	}
+	loaded, err := f.documents.GetByDocumentIDs(ctx, mergeLabels(nil, ids))
+	if err != nil {
+		log.Printf("Warning: failed to load documents for facets: %v", err)
+		return documents
+	}
+	for _, doc := range loaded {
+		documents[doc.DocumentID] = doc
+	}
+	return documents
+}
+
+// loadDomainNames 加载结果涉及的知识域名称，文档已预加载知识域时直接使用
+func (f *Faceter) loadDomainNames(ctx context.Context, results []models.SearchResult, documents map[string]*models.Document) map[uint64]string {
+	names := make(map[uint64]string)
+	for _, doc := range documents {
+		if doc.Domain != nil {
+			names[doc.DomainID] = doc.Domain.DomainName
+		}
+	}
+	for _, r := range results {
+		id := metadataUint(r.Metadata, "domain_id")
+		if _, ok := names[id]; ok || id == 0 || f.domains == nil {
+			continue
+		}
+		names[id] = ""
+		if domain, err := f.domains.GetByID(ctx, id); err == nil {
+			names[id] = domain.DomainName
+		}
+	}
+	return names
+}
+
+// metadataUint 读取无符号整数元数据
+func metadataUint(metadata map[string]interface{}, key string) uint64 {
+	switch v := metadata[key].(type) {
+	case uint64:
+		return v
+	case int:
+		if v > 0 {
+			return uint64(v)
+		}
+	case float64:
+		if v > 0 {
+			return uint64(v)
+		}
+	}
+	return 0
+}
+
+// timeBucket 创建时间所在的区间，时间未知时返回空
+func timeBucket(createdAt, now time.Time) (string, string) {
+	if createdAt.IsZero() {
+		return "", ""
+	}
+	age := now.Sub(createdAt)
+	for _, b := range timeBuckets {
+		if age <= b.age {
+			return b.value, b.label
+		}
+	}
+	return timeBucketOlder, "更早"
+}
+
+// count 统计分面各取值的命中数。时间分面按区间顺序返回，其他分面按命中数降序并截断
+func (f *Faceter) count(facet string, values []map[string][]facetValue) []models.FacetBucket {
+	index := make(map[string]int)
+	buckets := []models.FacetBucket{}
+	for _, v := range values {
+		for _, fv := range v[facet] {
+			i, ok := index[fv.value]
+			if !ok {
+				i = len(buckets)
+				index[fv.value] = i
+				buckets = append(buckets, models.FacetBucket{Value: fv.value, Label: fv.label})
+			}
+			buckets[i].Count++
+		}
+	}
+
+	if facet == FacetTime {
+		order := map[string]int{timeBucketOlder: len(timeBuckets)}
+		for i, b := range timeBuckets {
+			order[b.value] = i
+		}
+		sort.Slice(buckets, func(i, j int) bool { return order[buckets[i].Value] < order[buckets[j].Value] })
+		return buckets
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Value < buckets[j].Value
+	})
+	if f.opts.MaxBuckets > 0 && len(buckets) > f.opts.MaxBuckets {
+		buckets = buckets[:f.opts.MaxBuckets]
+	}
+	return buckets
+}
@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestPushdownWindow(t *testing.T) {
+	f := NewFaceter(nil, nil, DefaultFacetOptions())
+	cases := []struct {
+		name    string
+		filters map[string]interface{}
+		pushed  bool
+		window  int
+	}{
+		{"no filters", nil, false, 0},
+		{"narrowing only", map[string]interface{}{FacetTag: "go"}, true, 0},
+		{"facets requested", map[string]interface{}{facetsFilterKey: []string{FacetTag}}, false, 100},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, window, err := f.Pushdown(tc.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (filter != nil) != tc.pushed || window != tc.window {
+				t.Fatalf("filter = %+v, window = %d", filter, window)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// FeedbackCollector 检索反馈收集服务。反馈必须引用已记录的检索，
+// 同一用户对同一检索只保留一条反馈，重复提交时覆盖。
+type FeedbackCollector struct {
+	feedback   repository.FeedbackRepository
+	searchLogs repository.SearchLogRepository
+	users      repository.UserRepository
+}
+
+// NewFeedbackCollector 创建反馈收集服务
+func NewFeedbackCollector(feedback repository.FeedbackRepository, searchLogs repository.SearchLogRepository, users repository.UserRepository) *FeedbackCollector {
+	return &FeedbackCollector{feedback: feedback, searchLogs: searchLogs, users: users}
+}
+
+// Collect 写入反馈
+func (s *FeedbackCollector) Collect(ctx context.Context, req *models.CollectFeedbackRequest) (*models.CollectFeedbackResult, error) {
+	if !models.ValidFeedbackType(req.FeedbackType) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFeedbackType, req.FeedbackType)
+	}
+	if _, err := s.searchLogs.GetByQueryID(ctx, req.QueryID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrSearchLogNotFound, req.QueryID)
+		}
+		return nil, err
+	}
+	if _, err := s.users.GetByID(ctx, req.UserID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	existing, err := s.feedback.GetByQueryAndUser(ctx, req.QueryID, req.UserID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		existing.FeedbackType = req.FeedbackType
+		existing.Rating = req.Rating
+		existing.Comment = req.Comment
+		existing.Context = req.Context
+		if err := s.feedback.Update(ctx, existing); err != nil {
+			return nil, fmt.Errorf("failed to update feedback: %w", err)
+		}
+		return &models.CollectFeedbackResult{Feedback: existing.ToResponse()}, nil
+	}
+
+	feedback := &models.Feedback{
+		QueryID:      req.QueryID,
+		UserID:       req.UserID,
+		FeedbackType: req.FeedbackType,
+		Rating:       req.Rating,
+		Comment:      req.Comment,
+		Context:      req.Context,
+	}
+	if err := s.feedback.Create(ctx, feedback); err != nil {
+		return nil, fmt.Errorf("failed to save feedback: %w", err)
+	}
+	return &models.CollectFeedbackResult{Feedback: feedback.ToResponse(), Created: true}, nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// GlobalSearchOptions 全局检索参数
+type GlobalSearchOptions struct {
+	MaxCommunities int // 参与map阶段的社区摘要数
+	MapConcurrency int // map阶段并发数
+	MaxPoints      int // reduce阶段使用的要点数
+}
+
+// DefaultGlobalSearchOptions 默认全局检索参数
+func DefaultGlobalSearchOptions() GlobalSearchOptions {
+	return GlobalSearchOptions{
+		MaxCommunities: 20,
+		MapConcurrency: 4,
+		MaxPoints:      20,
+	}
+}
+
+// GlobalSearcher 基于社区摘要的全局检索，对摘要做map-reduce回答宽泛问题
+type GlobalSearcher struct {
+	summaries repository.CommunitySummaryRepository
+	vector    repository.VectorRepository
+	llm       ChatModel
+	embedder  Embedder
+	opts      GlobalSearchOptions
+}
+
+// NewGlobalSearcher 创建全局检索器
+func NewGlobalSearcher(summaries repository.CommunitySummaryRepository, vector repository.VectorRepository, llm ChatModel, embedder Embedder, opts GlobalSearchOptions) *GlobalSearcher {
+	return &GlobalSearcher{
+		summaries: summaries,
+		vector:    vector,
+		llm:       llm,
+		embedder:  embedder,
+		opts:      opts,
+	}
+}
+
+// GlobalAnswer 全局检索结果
+type GlobalAnswer struct {
+	Answer  string
+	Results []models.SearchResult // 贡献了要点的社区摘要
+}
+
+// globalPoint map阶段产出的要点
+type globalPoint struct {
+	Description string  `json:"description"`
+	Score       float64 `json:"score"` // 0-100
+	community   *models.CommunitySummary
+}
+
+const globalMapPrompt = `你是知识分析助手。根据给出的社区报告，提取与用户问题相关的要点，并给每个要点打0-100的有用度分数。
+报告与问题无关时返回空列表。只输出JSON：{"points": [{"description": "要点", "score": 80}]}`
+
+const globalReducePrompt = `你是知识分析助手。下面是从多个知识社区报告中提取的要点（按有用度降序），请综合这些要点回答用户问题。
+归纳共性，去除重复，不要编造要点之外的信息。`
+
+// Search 执行全局检索
+func (g *GlobalSearcher) Search(ctx context.Context, query string, domainID uint64) (*GlobalAnswer, error) {
+	if g.llm == nil {
+		return nil, ErrLLMUnavailable
+	}
+	if domainID == 0 {
+		return nil, ErrDomainRequired
+	}
+
+	summaries, err := g.selectSummaries(ctx, query, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return &GlobalAnswer{}, nil
+	}
+
+	points := g.mapSummaries(ctx, query, summaries)
+	if len(points) == 0 {
+		return &GlobalAnswer{}, nil
+	}
+
+	answer, err := g.reduce(ctx, query, points)
+	if err != nil {
+		return nil, err
+	}
+	return &GlobalAnswer{Answer: answer, Results: pointResults(points)}, nil
+}
+
+// selectSummaries 选取参与map的社区摘要，有向量模型时按相似度选取，否则按规模选取。
+// 首次生成摘要前摘要集合不存在，此时没有可选的摘要
+func (g *GlobalSearcher) selectSummaries(ctx context.Context, query string, domainID uint64) ([]*models.CommunitySummary, error) {
+	if g.embedder == nil {
+		return g.summaries.ListByDomain(ctx, domainID, 0, g.opts.MaxCommunities)
+	}
+	exists, err := g.vector.HasCollection(ctx, CommunitySummaryCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check community summary collection: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	vector, err := embedOne(ctx, g.embedder, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	hits, err := g.vector.Search(ctx, CommunitySummaryCollection, [][]float32{vector}, g.opts.MaxCommunities, domainFilter(domainID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search community summaries: %w", err)
+	}
+
+	communityIDs := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		if id, ok := hit.Metadata["community_id"].(string); ok {
+			communityIDs = append(communityIDs, id)
+		}
+	}
+	return g.summaries.ListByCommunityIDs(ctx, domainID, communityIDs)
+}
+
+// mapSummaries 并发地从每个社区摘要中提取要点，单个摘要失败时跳过
+func (g *GlobalSearcher) mapSummaries(ctx context.Context, query string, summaries []*models.CommunitySummary) []globalPoint {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		points []globalPoint
+		sem    = make(chan struct{}, g.opts.MapConcurrency)
+	)
+
+	for _, summary := range summaries {
+		wg.Add(1)
+		go func(summary *models.CommunitySummary) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			report := fmt.Sprintf("社区报告：%s\n%s\n\n用户问题：%s", summary.Title, summary.Summary, query)
+			output, err := complete(ctx, g.llm, globalMapPrompt, report)
+			if err != nil {
+				log.Printf("Warning: global map failed for community %s: %v", summary.CommunityID, err)
+				return
+			}
+
+			var parsed struct {
+				Points []globalPoint `json:"points"`
+			}
+			if err := parseJSONResponse(output, &parsed); err != nil {
+				log.Printf("Warning: global map output invalid for community %s: %v", summary.CommunityID, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range parsed.Points {
+				if p.Score > 0 && p.Description != "" {
+					p.community = summary
+					points = append(points, p)
+				}
+			}
+		}(summary)
+	}
+	wg.Wait()
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Score > points[j].Score })
+	if len(points) > g.opts.MaxPoints {
+		points = points[:g.opts.MaxPoints]
+	}
+	return points
+}
+
+// reduce 汇总要点生成最终回答
+func (g *GlobalSearcher) reduce(ctx context.Context, query string, points []globalPoint) (string, error) {
+	var b strings.Builder
+	for i, p := range points {
+		fmt.Fprintf(&b, "%d. [%s, 有用度%.0f] %s\n", i+1, p.community.Title, p.Score, p.Description)
+	}
+	fmt.Fprintf(&b, "\n用户问题：%s", query)
+
+	answer, err := complete(ctx, g.llm, globalReducePrompt, b.String())
+	if err != nil {
+		return "", fmt.Errorf("global reduce failed: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// pointResults 将贡献要点的社区摘要转换为搜索结果，分数取其最高要点分
+func pointResults(points []globalPoint) []models.SearchResult {
+	index := make(map[uint64]int)
+	var results []models.SearchResult
+	for _, p := range points {
+		if _, ok := index[p.community.ID]; ok {
+			continue
+		}
+		index[p.community.ID] = len(results)
+
+		c := p.community
+		results = append(results, models.SearchResult{
+			ID:      c.VectorID(),
+			Type:    "community",
+			Title:   c.Title,
+			Content: c.Summary,
+			Source:  c.CommunityID,
+			Score:   p.Score / 100,
+			Metadata: map[string]interface{}{
+				"domain_id":    c.DomainID,
+				"community_id": c.CommunityID,
+				"size":         c.Size,
+			},
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	return results
+}
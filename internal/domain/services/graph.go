@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// graphPageSize 分页加载实体的批大小
+const graphPageSize = 500
+
+// LoadDomainGraph 加载知识域下的全部实体和关系，domainID为0时加载全图。
+// 只保留两端实体都在该知识域内的关系；includeHistory为false时只保留当前成立的关系。
+func LoadDomainGraph(ctx context.Context, graph repository.GraphRepository, domainID uint64, includeHistory bool) (*models.KnowledgeGraph, error) {
+	if graph == nil {
+		return nil, ErrGraphUnavailable
+	}
+
+	kg := &models.KnowledgeGraph{}
+	inDomain := make(map[string]bool)
+	for offset := 0; ; offset += graphPageSize {
+		entities, err := graph.ListEntities(ctx, "", offset, graphPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entities {
+			if domainID != 0 && e.DomainID != domainID {
+				continue
+			}
+			inDomain[e.ID] = true
+			kg.Entities = append(kg.Entities, *e)
+		}
+		if len(entities) < graphPageSize {
+			break
+		}
+	}
+
+	relations, err := graph.ListRelations(ctx, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range relations {
+		if !includeHistory && !r.IsCurrent() {
+			continue
+		}
+		if inDomain[r.FromEntity] && inDomain[r.ToEntity] {
+			kg.Relations = append(kg.Relations, *r)
+		}
+	}
+
+	kg.Metadata = buildGraphMetadata(domainID, kg)
+	return kg, nil
+}
+
+// buildGraphMetadata 汇总图谱元数据
+func buildGraphMetadata(domainID uint64, kg *models.KnowledgeGraph) models.GraphMetadata {
+	meta := models.GraphMetadata{
+		DomainID:       domainID,
+		TotalEntities:  len(kg.Entities),
+		TotalRelations: len(kg.Relations),
+	}
+
+	entityTypes := make(map[string]bool)
+	for _, e := range kg.Entities {
+		if !entityTypes[e.Type] {
+			entityTypes[e.Type] = true
+			meta.EntityTypes = append(meta.EntityTypes, e.Type)
+		}
+		if meta.CreatedAt.IsZero() || e.CreatedAt.Before(meta.CreatedAt) {
+			meta.CreatedAt = e.CreatedAt
+		}
+		if e.UpdatedAt.After(meta.UpdatedAt) {
+			meta.UpdatedAt = e.UpdatedAt
+		}
+	}
+
+	relationTypes := make(map[string]bool)
+	for _, r := range kg.Relations {
+		if !relationTypes[r.Type] {
+			relationTypes[r.Type] = true
+			meta.RelationTypes = append(meta.RelationTypes, r.Type)
+		}
+		if r.UpdatedAt.After(meta.UpdatedAt) {
+			meta.UpdatedAt = r.UpdatedAt
+		}
+	}
+
+	return meta
+}
+
+// TraverseGraph 图遍历，深度限制在知识域配置的MaxDepth内。AsOf为空时取当前时间，
+// 剔除包含在该时间点不成立的关系的路径；仓储在查询中按AsOf过滤时结果不受影响
+func TraverseGraph(ctx context.Context, graph repository.GraphRepository, domains repository.DomainRepository, cfg *models.GraphTraversal) (*models.GraphTraversalResult, error) {
+	if graph == nil {
+		return nil, ErrGraphUnavailable
+	}
+
+	domainID := cfg.DomainID
+	if domainID == 0 {
+		start, err := graph.GetEntity(ctx, cfg.StartEntity)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && start == nil) {
+			return nil, fmt.Errorf("%w: start entity %s", ErrEntityNotFound, cfg.StartEntity)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get start entity %s: %w", cfg.StartEntity, err)
+		}
+		domainID = start.DomainID
+	}
+	schema, err := LoadGraphSchema(ctx, domains, domainID)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxDepth = schema.ClampDepth(cfg.MaxDepth)
+	if cfg.AsOf == nil {
+		now := time.Now()
+		cfg.AsOf = &now
+	}
+
+	result, err := graph.TraverseGraph(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	paths := result.Paths[:0]
+	totalLength := 0
+	for _, path := range result.Paths {
+		if pathValidAt(path, *cfg.AsOf) {
+			paths = append(paths, path)
+			totalLength += path.Length
+		}
+	}
+	result.Paths = paths
+	result.Stats.TotalPaths = len(paths)
+	result.Stats.AvgPathLength = 0
+	if len(paths) > 0 {
+		result.Stats.AvgPathLength = float64(totalLength) / float64(len(paths))
+	}
+	return result, nil
+}
+
+// pathValidAt 路径上的关系在指定时间是否全部成立
+func pathValidAt(path models.GraphPath, t time.Time) bool {
+	for i := range path.Relations {
+		if !path.Relations[i].ValidAt(t) {
+			return false
+		}
+	}
+	return true
+}
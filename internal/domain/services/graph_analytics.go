@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// GraphAnalyticsOptions 图分析参数
+type GraphAnalyticsOptions struct {
+	Damping          float64 // PageRank阻尼系数
+	MaxIterations    int     // PageRank最大迭代次数
+	Tolerance        float64 // PageRank收敛阈值(L1)
+	LabelIterations  int     // 标签传播最大迭代次数
+	TopEntities      int     // 结果中返回的中心实体数
+	MaxCommunityList int     // 结果中返回的社区数
+}
+
+// DefaultGraphAnalyticsOptions 默认图分析参数
+func DefaultGraphAnalyticsOptions() GraphAnalyticsOptions {
+	return GraphAnalyticsOptions{
+		Damping:          0.85,
+		MaxIterations:    100,
+		Tolerance:        1e-6,
+		LabelIterations:  20,
+		TopEntities:      20,
+		MaxCommunityList: 50,
+	}
+}
+
+// GraphAnalyzer 图分析服务，计算图统计、PageRank中心度和社区划分
+type GraphAnalyzer struct {
+	graph repository.GraphRepository
+	opts  GraphAnalyticsOptions
+}
+
+// NewGraphAnalyzer 创建图分析服务
+func NewGraphAnalyzer(graph repository.GraphRepository, opts GraphAnalyticsOptions) *GraphAnalyzer {
+	return &GraphAnalyzer{graph: graph, opts: opts}
+}
+
+// graphAnalysis 单次分析的中间结果
+type graphAnalysis struct {
+	kg          *models.KnowledgeGraph
+	pagerank    []float64
+	centrality  []float64
+	degree      []int
+	communities []string
+	result      *models.GraphAnalyticsResult
+}
+
+// Analyze 分析知识域图谱，不写回实体
+func (a *GraphAnalyzer) Analyze(ctx context.Context, domainID uint64) (*models.GraphAnalyticsResult, error) {
+	analysis, err := a.analyze(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	return analysis.result, nil
+}
+
+// Run 分析知识域图谱，并将PageRank、中心度、社区和度数写回实体属性
+func (a *GraphAnalyzer) Run(ctx context.Context, domainID uint64) (*models.GraphAnalyticsResult, error) {
+	analysis, err := a.analyze(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzedAt := analysis.result.AnalyzedAt.Format(time.RFC3339)
+	for i := range analysis.kg.Entities {
+		entity := &analysis.kg.Entities[i]
+		if entity.Properties == nil {
+			entity.Properties = make(map[string]interface{})
+		}
+		entity.Properties[models.EntityPropPageRank] = analysis.pagerank[i]
+		entity.Properties[models.EntityPropCentrality] = analysis.centrality[i]
+		entity.Properties[models.EntityPropCommunity] = analysis.communities[i]
+		entity.Properties[models.EntityPropDegree] = analysis.degree[i]
+		entity.Properties[models.EntityPropAnalyzedAt] = analyzedAt
+
+		if err := a.graph.UpdateEntity(ctx, entity); err != nil {
+			return nil, fmt.Errorf("failed to update entity %s: %w", entity.ID, err)
+		}
+	}
+
+	analysis.result.Persisted = true
+	log.Printf("Graph analytics for domain %d: %d entities, %d relations, %d communities",
+		domainID, analysis.result.Stats.TotalEntities, analysis.result.Stats.TotalRelations, len(analysis.result.Communities))
+	return analysis.result, nil
+}
+
+// analyze 执行图分析
+func (a *GraphAnalyzer) analyze(ctx context.Context, domainID uint64) (*graphAnalysis, error) {
+	start := time.Now()
+
+	kg, err := LoadDomainGraph(ctx, a.graph, domainID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newAdjacencyGraph(kg)
+	analysis := &graphAnalysis{
+		kg:          kg,
+		pagerank:    g.pageRank(a.opts.Damping, a.opts.MaxIterations, a.opts.Tolerance),
+		degree:      g.degree,
+		communities: g.labelPropagation(a.opts.LabelIterations),
+	}
+
+	maxPR := 0.0
+	for _, pr := range analysis.pagerank {
+		maxPR = math.Max(maxPR, pr)
+	}
+	analysis.centrality = make([]float64, len(analysis.pagerank))
+	for i, pr := range analysis.pagerank {
+		if maxPR > 0 {
+			analysis.centrality[i] = pr / maxPR
+		}
+	}
+
+	analysis.result = &models.GraphAnalyticsResult{
+		DomainID:    domainID,
+		Stats:       g.stats(kg),
+		TopEntities: a.topEntities(analysis),
+		Communities: communityList(analysis, a.opts.MaxCommunityList),
+		AnalyzedAt:  time.Now(),
+	}
+	analysis.result.ExecutionTime = int(time.Since(start).Milliseconds())
+	return analysis, nil
+}
+
+// topEntities 按PageRank排序的中心实体
+func (a *GraphAnalyzer) topEntities(analysis *graphAnalysis) []models.EntityRank {
+	order := rankOrder(analysis.pagerank)
+	if len(order) > a.opts.TopEntities {
+		order = order[:a.opts.TopEntities]
+	}
+
+	ranks := make([]models.EntityRank, 0, len(order))
+	for _, i := range order {
+		e := analysis.kg.Entities[i]
+		ranks = append(ranks, models.EntityRank{
+			EntityID:   e.ID,
+			Name:       e.Name,
+			Type:       e.Type,
+			PageRank:   analysis.pagerank[i],
+			Centrality: analysis.centrality[i],
+			Degree:     analysis.degree[i],
+			Community:  analysis.communities[i],
+		})
+	}
+	return ranks
+}
+
+// DetectCommunities 发现知识域的全部社区，返回图谱和按规模排序的社区
+func (a *GraphAnalyzer) DetectCommunities(ctx context.Context, domainID uint64) (*models.KnowledgeGraph, []models.GraphCommunity, error) {
+	analysis, err := a.analyze(ctx, domainID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return analysis.kg, communityList(analysis, 0), nil
+}
+
+// communityList 按规模排序的社区列表，成员按PageRank降序，limit为0时不限制
+func communityList(analysis *graphAnalysis, limit int) []models.GraphCommunity {
+	byID := make(map[string]*models.GraphCommunity)
+	var communities []*models.GraphCommunity
+	for _, i := range rankOrder(analysis.pagerank) {
+		id := analysis.communities[i]
+		c, ok := byID[id]
+		if !ok {
+			c = &models.GraphCommunity{ID: id}
+			byID[id] = c
+			communities = append(communities, c)
+		}
+		c.Size++
+		c.Members = append(c.Members, analysis.kg.Entities[i].ID)
+	}
+
+	sort.SliceStable(communities, func(i, j int) bool {
+		return communities[i].Size > communities[j].Size
+	})
+	if limit > 0 && len(communities) > limit {
+		communities = communities[:limit]
+	}
+
+	list := make([]models.GraphCommunity, len(communities))
+	for i, c := range communities {
+		list[i] = *c
+	}
+	return list
+}
+
+// rankOrder 返回按分数降序的下标
+func rankOrder(scores []float64) []int {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	return order
+}
+
+// adjacencyGraph 以下标表示的图结构
+type adjacencyGraph struct {
+	ids       []string
+	edges     []weightedEdge // 有向边，不含自环
+	neighbors [][]weightedEdge
+	degree    []int
+}
+
+// weightedEdge 带权边
+type weightedEdge struct {
+	from, to int
+	weight   float64
+}
+
+// newAdjacencyGraph 从知识图谱构建邻接结构
+func newAdjacencyGraph(kg *models.KnowledgeGraph) *adjacencyGraph {
+	n := len(kg.Entities)
+	g := &adjacencyGraph{
+		ids:       make([]string, n),
+		neighbors: make([][]weightedEdge, n),
+		degree:    make([]int, n),
+	}
+
+	index := make(map[string]int, n)
+	for i, e := range kg.Entities {
+		g.ids[i] = e.ID
+		index[e.ID] = i
+	}
+
+	for _, r := range kg.Relations {
+		from, okFrom := index[r.FromEntity]
+		to, okTo := index[r.ToEntity]
+		if !okFrom || !okTo || from == to {
+			continue
+		}
+		weight := r.Score
+		if weight <= 0 {
+			weight = 1
+		}
+		g.edges = append(g.edges, weightedEdge{from: from, to: to, weight: weight})
+		g.neighbors[from] = append(g.neighbors[from], weightedEdge{from: from, to: to, weight: weight})
+		g.neighbors[to] = append(g.neighbors[to], weightedEdge{from: to, to: from, weight: weight})
+		g.degree[from]++
+		g.degree[to]++
+	}
+	return g
+}
+
+// stats 计算图统计信息
+func (g *adjacencyGraph) stats(kg *models.KnowledgeGraph) models.GraphStats {
+	n := len(g.ids)
+	stats := models.GraphStats{
+		TotalEntities:       n,
+		TotalRelations:      len(kg.Relations),
+		EntityTypes:         countTypes(len(kg.Entities), func(i int) string { return kg.Entities[i].Type }),
+		RelationTypes:       countTypes(len(kg.Relations), func(i int) string { return kg.Relations[i].Type }),
+		ConnectedComponents: g.connectedComponents(),
+	}
+
+	if n > 1 {
+		stats.Density = float64(len(g.edges)) / float64(n*(n-1))
+	}
+	if n > 0 {
+		total := 0
+		for _, d := range g.degree {
+			total += d
+			if d > stats.MaxDegree {
+				stats.MaxDegree = d
+			}
+		}
+		stats.AvgDegree = float64(total) / float64(n)
+	}
+	return stats
+}
+
+// countTypes 统计类型分布，按数量降序
+func countTypes(n int, typeOf func(i int) string) []models.TypeCount {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[typeOf(i)]++
+	}
+
+	result := make([]models.TypeCount, 0, len(counts))
+	for t, c := range counts {
+		result = append(result, models.TypeCount{Type: t, Count: c})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// connectedComponents 计算弱连通分量数
+func (g *adjacencyGraph) connectedComponents() int {
+	parent := make([]int, len(g.ids))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(x int) int {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+
+	components := len(g.ids)
+	for _, e := range g.edges {
+		a, b := find(e.from), find(e.to)
+		if a != b {
+			parent[a] = b
+			components--
+		}
+	}
+	return components
+}
+
+// pageRank 计算带权PageRank，悬挂节点的分数均匀分配
+func (g *adjacencyGraph) pageRank(damping float64, maxIterations int, tolerance float64) []float64 {
+	n := len(g.ids)
+	if n == 0 {
+		return nil
+	}
+
+	outWeight := make([]float64, n)
+	for _, e := range g.edges {
+		outWeight[e.from] += e.weight
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	next := make([]float64, n)
+	for iter := 0; iter < maxIterations; iter++ {
+		dangling := 0.0
+		for i := range rank {
+			if outWeight[i] == 0 {
+				dangling += rank[i]
+			}
+		}
+
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for _, e := range g.edges {
+			next[e.to] += damping * rank[e.from] * e.weight / outWeight[e.from]
+		}
+
+		diff := 0.0
+		for i := range rank {
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < tolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// labelPropagation 基于无向带权图的标签传播社区发现。
+// 按实体ID顺序异步更新，平票取最小标签，保证结果稳定。
+func (g *adjacencyGraph) labelPropagation(maxIterations int) []string {
+	n := len(g.ids)
+	labels := make([]int, n)
+	for i := range labels {
+		labels[i] = i
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return g.ids[order[i]] < g.ids[order[j]] })
+
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := false
+		for _, i := range order {
+			if len(g.neighbors[i]) == 0 {
+				continue
+			}
+
+			weights := make(map[int]float64)
+			for _, e := range g.neighbors[i] {
+				weights[labels[e.to]] += e.weight
+			}
+
+			best, bestWeight := labels[i], -1.0
+			for label, w := range weights {
+				if w > bestWeight || (w == bestWeight && label < best) {
+					best, bestWeight = label, w
+				}
+			}
+			if best != labels[i] {
+				labels[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// 按社区规模重新编号
+	sizes := make(map[int]int)
+	for _, l := range labels {
+		sizes[l]++
+	}
+	distinct := make([]int, 0, len(sizes))
+	for l := range sizes {
+		distinct = append(distinct, l)
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		if sizes[distinct[i]] != sizes[distinct[j]] {
+			return sizes[distinct[i]] > sizes[distinct[j]]
+		}
+		return g.ids[distinct[i]] < g.ids[distinct[j]]
+	})
+	names := make(map[int]string, len(distinct))
+	for i, l := range distinct {
+		names[l] = fmt.Sprintf("c%d", i)
+	}
+
+	communities := make([]string, n)
+	for i, l := range labels {
+		communities[i] = names[l]
+	}
+	return communities
+}
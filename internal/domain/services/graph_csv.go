@@ -0,0 +1,74 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvWriter 带表头的CSV写入器
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.write(columns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) write(fields []string) error {
+	if err := cw.w.Write(fields); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+func (cw *csvWriter) flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// readCSV 逐行读取带表头的CSV，按列名回调每一行。列名大小写不敏感，列顺序任意。
+func readCSV(r io.Reader, name string, fn func(record string, fields map[string]string) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s header: %v", ErrInvalidGraphFile, name, err)
+	}
+	columns := make([]string, len(header))
+	for i, h := range header {
+		columns[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidGraphFile, name, err)
+		}
+
+		fields := make(map[string]string, len(columns))
+		for i, v := range row {
+			if i < len(columns) {
+				fields[columns[i]] = strings.TrimSpace(v)
+			}
+		}
+		line, _ := reader.FieldPos(0)
+		if err := fn(fmt.Sprintf("%s:%d", name, line), fields); err != nil {
+			return err
+		}
+	}
+}
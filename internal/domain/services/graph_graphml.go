@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// graphMLKey GraphML属性声明
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// graphMLData GraphML属性值
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// graphMLNode GraphML节点
+type graphMLNode struct {
+	XMLName xml.Name      `xml:"node"`
+	ID      string        `xml:"id,attr"`
+	Data    []graphMLData `xml:"data"`
+}
+
+// graphMLEdge GraphML边
+type graphMLEdge struct {
+	XMLName xml.Name      `xml:"edge"`
+	ID      string        `xml:"id,attr,omitempty"`
+	Source  string        `xml:"source,attr"`
+	Target  string        `xml:"target,attr"`
+	Data    []graphMLData `xml:"data"`
+}
+
+// graphMLWriter 流式写出GraphML文档，实体和关系的属性与CSV列一致
+type graphMLWriter struct {
+	enc *xml.Encoder
+}
+
+func newGraphMLWriter(w io.Writer, domainID uint64) (*graphMLWriter, error) {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "graphml"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: graphMLNamespace}},
+	}
+	if err := enc.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	for _, key := range graphMLKeys() {
+		if err := enc.EncodeElement(key, xml.StartElement{Name: xml.Name{Local: "key"}}); err != nil {
+			return nil, err
+		}
+	}
+
+	graph := xml.StartElement{
+		Name: xml.Name{Local: "graph"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "id"}, Value: "domain_" + strconv.FormatUint(domainID, 10)},
+			{Name: xml.Name{Local: "edgedefault"}, Value: "directed"},
+		},
+	}
+	if err := enc.EncodeToken(graph); err != nil {
+		return nil, err
+	}
+	return &graphMLWriter{enc: enc}, nil
+}
+
+// graphMLKeys 实体和关系的属性声明，键ID即属性名加上n_/e_前缀
+func graphMLKeys() []graphMLKey {
+	var keys []graphMLKey
+	for _, c := range entityColumns[1:] {
+		keys = append(keys, graphMLKey{ID: "n_" + c, For: "node", AttrName: c, AttrType: graphMLAttrType(c)})
+	}
+	for _, c := range relationColumns[1:] {
+		if c == "from" || c == "to" {
+			continue
+		}
+		keys = append(keys, graphMLKey{ID: "e_" + c, For: "edge", AttrName: c, AttrType: graphMLAttrType(c)})
+	}
+	return keys
+}
+
+func graphMLAttrType(column string) string {
+	if column == "score" {
+		return "double"
+	}
+	return "string"
+}
+
+// graphMLDataOf 将扁平字段转换为GraphML属性值，跳过空值和跳过的列
+func graphMLDataOf(prefix string, columns, fields []string, skip ...string) []graphMLData {
+	skipped := make(map[string]bool, len(skip))
+	for _, s := range skip {
+		skipped[s] = true
+	}
+	var data []graphMLData
+	for i, c := range columns {
+		if skipped[c] || fields[i] == "" {
+			continue
+		}
+		data = append(data, graphMLData{Key: prefix + c, Value: fields[i]})
+	}
+	return data
+}
+
+func (gw *graphMLWriter) WriteEntity(e *models.KnowledgeEntity) error {
+	return gw.enc.Encode(graphMLNode{
+		ID:   e.ID,
+		Data: graphMLDataOf("n_", entityColumns, entityFields(e), "id"),
+	})
+}
+
+func (gw *graphMLWriter) WriteRelation(r *models.KnowledgeRelation) error {
+	return gw.enc.Encode(graphMLEdge{
+		ID:     r.ID,
+		Source: r.FromEntity,
+		Target: r.ToEntity,
+		Data:   graphMLDataOf("e_", relationColumns, relationFields(r), "id", "from", "to"),
+	})
+}
+
+func (gw *graphMLWriter) Close() error {
+	if err := gw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "graph"}}); err != nil {
+		return err
+	}
+	if err := gw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "graphml"}}); err != nil {
+		return err
+	}
+	return gw.enc.Flush()
+}
+
+// readGraphML 流式读取GraphML文档，逐个解码node和edge元素。
+// 属性按<key>声明的attr.name映射，因此也能读取其他工具导出的文件。
+func readGraphML(r io.Reader, im *graphImporter) error {
+	dec := xml.NewDecoder(r)
+	keys := make(map[string]string) // 键ID -> 属性名
+	nodes, edges := 0, 0
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: graphml: %w", ErrInvalidGraphFile, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "key":
+			var key graphMLKey
+			if err := dec.DecodeElement(&key, &start); err != nil {
+				return fmt.Errorf("%w: graphml key: %w", ErrInvalidGraphFile, err)
+			}
+			keys[key.ID] = key.AttrName
+			if key.AttrName == "" {
+				keys[key.ID] = key.ID
+			}
+
+		case "node":
+			var node graphMLNode
+			if err := dec.DecodeElement(&node, &start); err != nil {
+				return fmt.Errorf("%w: graphml node %d: %w", ErrInvalidGraphFile, nodes, err)
+			}
+			fields := graphMLFields(keys, node.Data)
+			fields["id"] = node.ID
+			if err := im.entityFromFields(fmt.Sprintf("node[%d]", nodes), fields); err != nil {
+				return err
+			}
+			nodes++
+
+		case "edge":
+			var edge graphMLEdge
+			if err := dec.DecodeElement(&edge, &start); err != nil {
+				return fmt.Errorf("%w: graphml edge %d: %w", ErrInvalidGraphFile, edges, err)
+			}
+			fields := graphMLFields(keys, edge.Data)
+			fields["id"], fields["from"], fields["to"] = edge.ID, edge.Source, edge.Target
+			if err := im.relationFromFields(fmt.Sprintf("edge[%d]", edges), fields); err != nil {
+				return err
+			}
+			edges++
+		}
+	}
+}
+
+// graphMLFields 按键声明将属性值转换为扁平字段
+func graphMLFields(keys map[string]string, data []graphMLData) map[string]string {
+	fields := make(map[string]string, len(data)+3)
+	for _, d := range data {
+		name, ok := keys[d.Key]
+		if !ok {
+			name = d.Key
+		}
+		fields[name] = d.Value
+	}
+	return fields
+}
@@ -0,0 +1,568 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// defaultMaxImportErrors 导入结果中保留的错误条数
+const defaultMaxImportErrors = 100
+
+// GraphImportOptions 图谱导入参数
+type GraphImportOptions struct {
+	DryRun    bool // 只校验不写入
+	MaxErrors int  // 结果中保留的错误条数
+}
+
+// GraphTransfer 图谱导入导出服务。只依赖GraphRepository接口，适用于任意图后端；
+// 导出按页读取实体并边读边写，导入逐条解码记录，不会将整个文件加载到内存。
+type GraphTransfer struct {
+	graph   repository.GraphRepository
+	domains repository.DomainRepository
+	now     func() time.Time
+}
+
+// NewGraphTransfer 创建图谱导入导出服务
+func NewGraphTransfer(graph repository.GraphRepository, domains repository.DomainRepository) *GraphTransfer {
+	return &GraphTransfer{graph: graph, domains: domains, now: time.Now}
+}
+
+// graphRecordWriter 图谱导出格式的流式写入器
+type graphRecordWriter interface {
+	WriteEntity(e *models.KnowledgeEntity) error
+	WriteRelation(r *models.KnowledgeRelation) error
+	Close() error
+}
+
+// Export 以JSON-LD或GraphML格式导出知识域图谱（含历史关系）
+func (t *GraphTransfer) Export(ctx context.Context, domainID uint64, format string, w io.Writer) error {
+	var (
+		out graphRecordWriter
+		err error
+	)
+	switch format {
+	case models.GraphFormatJSONLD:
+		out, err = newJSONLDWriter(w, domainID)
+	case models.GraphFormatGraphML:
+		out, err = newGraphMLWriter(w, domainID)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := t.walk(ctx, domainID, out.WriteEntity, out.WriteRelation); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// CSV导出的文件名
+const (
+	GraphCSVNodesFile = "nodes.csv"
+	GraphCSVEdgesFile = "edges.csv"
+)
+
+// ExportCSV 导出知识域图谱为实体和关系两个CSV文件。create依次以GraphCSVNodesFile、
+// GraphCSVEdgesFile调用，前一个文件写完后才创建后一个，因此可以直接写入zip等顺序容器。
+func (t *GraphTransfer) ExportCSV(ctx context.Context, domainID uint64, create func(name string) (io.Writer, error)) error {
+	w, err := create(GraphCSVNodesFile)
+	if err != nil {
+		return err
+	}
+	nodeWriter, err := newCSVWriter(w, entityColumns)
+	if err != nil {
+		return err
+	}
+
+	var edgeWriter *csvWriter
+	startEdges := func() error {
+		if err := nodeWriter.flush(); err != nil {
+			return err
+		}
+		w, err := create(GraphCSVEdgesFile)
+		if err != nil {
+			return err
+		}
+		edgeWriter, err = newCSVWriter(w, relationColumns)
+		return err
+	}
+
+	err = t.walk(ctx, domainID,
+		func(e *models.KnowledgeEntity) error { return nodeWriter.write(entityFields(e)) },
+		func(r *models.KnowledgeRelation) error {
+			if edgeWriter == nil {
+				if err := startEdges(); err != nil {
+					return err
+				}
+			}
+			return edgeWriter.write(relationFields(r))
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if edgeWriter == nil {
+		if err := startEdges(); err != nil {
+			return err
+		}
+	}
+	return edgeWriter.flush()
+}
+
+// walk 按页遍历知识域的实体，再遍历两端都在知识域内的关系
+func (t *GraphTransfer) walk(ctx context.Context, domainID uint64, onEntity func(*models.KnowledgeEntity) error, onRelation func(*models.KnowledgeRelation) error) error {
+	if t.graph == nil {
+		return ErrGraphUnavailable
+	}
+
+	inDomain := make(map[string]bool)
+	for offset := 0; ; offset += graphPageSize {
+		entities, err := t.graph.ListEntities(ctx, "", offset, graphPageSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range entities {
+			if domainID != 0 && e.DomainID != domainID {
+				continue
+			}
+			inDomain[e.ID] = true
+			if err := onEntity(e); err != nil {
+				return err
+			}
+		}
+		if len(entities) < graphPageSize {
+			break
+		}
+	}
+
+	relations, err := t.graph.ListRelations(ctx, "", "", "")
+	if err != nil {
+		return err
+	}
+	for _, r := range relations {
+		if inDomain[r.FromEntity] && inDomain[r.ToEntity] {
+			if err := onRelation(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Import 导入JSON-LD或GraphML格式的图谱到知识域
+func (t *GraphTransfer) Import(ctx context.Context, domainID uint64, format string, r io.Reader, opts GraphImportOptions) (*models.GraphImportResult, error) {
+	var read func(io.Reader, *graphImporter) error
+	switch format {
+	case models.GraphFormatJSONLD:
+		read = readJSONLD
+	case models.GraphFormatGraphML:
+		read = readGraphML
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+
+	im, err := t.newImporter(ctx, domainID, format, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := read(r, im); err != nil {
+		return nil, err
+	}
+	return im.finish()
+}
+
+// ImportCSV 导入实体和关系CSV文件到知识域，edges可以为空
+func (t *GraphTransfer) ImportCSV(ctx context.Context, domainID uint64, nodes, edges io.Reader, opts GraphImportOptions) (*models.GraphImportResult, error) {
+	im, err := t.newImporter(ctx, domainID, models.GraphFormatCSV, opts)
+	if err != nil {
+		return nil, err
+	}
+	if nodes != nil {
+		if err := readCSV(nodes, GraphCSVNodesFile, im.entityFromFields); err != nil {
+			return nil, err
+		}
+	}
+	if edges != nil {
+		if err := readCSV(edges, GraphCSVEdgesFile, im.relationFromFields); err != nil {
+			return nil, err
+		}
+	}
+	return im.finish()
+}
+
+// graphImporter 单次导入的状态
+type graphImporter struct {
+	ctx      context.Context
+	t        *GraphTransfer
+	domainID uint64
+	schema   *GraphSchema
+	opts     GraphImportOptions
+	result   *models.GraphImportResult
+	known    map[string]bool // 已确认在知识域内的实体ID
+	pending  []pendingRelation
+}
+
+// pendingRelation 端点实体尚未出现的关系，在导入结束时重试
+type pendingRelation struct {
+	record   string
+	relation *models.KnowledgeRelation
+}
+
+func (t *GraphTransfer) newImporter(ctx context.Context, domainID uint64, format string, opts GraphImportOptions) (*graphImporter, error) {
+	if t.graph == nil {
+		return nil, ErrGraphUnavailable
+	}
+	if domainID == 0 {
+		return nil, ErrDomainRequired
+	}
+	schema, err := LoadGraphSchema(ctx, t.domains, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxErrors <= 0 {
+		opts.MaxErrors = defaultMaxImportErrors
+	}
+
+	return &graphImporter{
+		ctx:      ctx,
+		t:        t,
+		domainID: domainID,
+		schema:   schema,
+		opts:     opts,
+		result:   &models.GraphImportResult{DomainID: domainID, Format: format, DryRun: opts.DryRun},
+		known:    make(map[string]bool),
+	}, nil
+}
+
+// reject 记录被拒绝的记录
+func (im *graphImporter) reject(record, id string, err error) {
+	im.result.Rejected++
+	if len(im.result.Errors) < im.opts.MaxErrors {
+		im.result.Errors = append(im.result.Errors, models.GraphImportError{Record: record, ID: id, Message: err.Error()})
+	}
+}
+
+// entity 校验并写入实体。记录本身无效时拒绝该记录，仅在后端出错时返回错误。
+func (im *graphImporter) entity(record string, e *models.KnowledgeEntity) error {
+	if e.Name == "" {
+		im.reject(record, e.ID, fmt.Errorf("entity name is required"))
+		return nil
+	}
+	if err := im.schema.ValidateEntity(e); err != nil {
+		im.reject(record, e.ID, err)
+		return nil
+	}
+
+	now := im.t.now()
+	e.DomainID = im.domainID
+	if e.ID == "" {
+		e.ID = NewID("entity")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	if e.UpdatedAt.IsZero() {
+		e.UpdatedAt = now
+	}
+
+	existing, _ := im.t.graph.GetEntity(im.ctx, e.ID)
+	if existing != nil && existing.DomainID != im.domainID {
+		im.reject(record, e.ID, fmt.Errorf("entity belongs to domain %d", existing.DomainID))
+		return nil
+	}
+	im.known[e.ID] = true
+
+	if existing != nil {
+		im.result.EntitiesUpdated++
+		if im.opts.DryRun {
+			return nil
+		}
+		if err := im.t.graph.UpdateEntity(im.ctx, e); err != nil {
+			return fmt.Errorf("failed to update entity %s: %w", e.ID, err)
+		}
+		return nil
+	}
+
+	im.result.EntitiesCreated++
+	if im.opts.DryRun {
+		return nil
+	}
+	if err := im.t.graph.CreateEntity(im.ctx, e); err != nil {
+		return fmt.Errorf("failed to create entity %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// relation 校验并写入关系。端点实体尚未出现的关系延后到导入结束时处理。
+// 导入按原样保留关系的有效期，不做取代判断。
+func (im *graphImporter) relation(record string, r *models.KnowledgeRelation) error {
+	if r.FromEntity == "" || r.ToEntity == "" {
+		im.reject(record, r.ID, fmt.Errorf("relation endpoints are required"))
+		return nil
+	}
+	if err := im.schema.ValidateRelation(r); err != nil {
+		im.reject(record, r.ID, err)
+		return nil
+	}
+	if !im.resolve(r.FromEntity) || !im.resolve(r.ToEntity) {
+		im.pending = append(im.pending, pendingRelation{record: record, relation: r})
+		return nil
+	}
+	return im.writeRelation(record, r)
+}
+
+// resolve 端点实体是否在知识域内，已存在于图谱中的实体也可被引用
+func (im *graphImporter) resolve(id string) bool {
+	if im.known[id] {
+		return true
+	}
+	if e, err := im.t.graph.GetEntity(im.ctx, id); err == nil && e != nil && e.DomainID == im.domainID {
+		im.known[id] = true
+		return true
+	}
+	return false
+}
+
+func (im *graphImporter) writeRelation(record string, r *models.KnowledgeRelation) error {
+	now := im.t.now()
+	r.DomainID = im.domainID
+	if r.ID == "" {
+		r.ID = NewID("rel")
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = now
+	}
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = now
+	}
+	if r.ValidFrom.IsZero() {
+		r.ValidFrom = r.CreatedAt
+	}
+	if r.ValidTo != nil && r.ValidTo.Before(r.ValidFrom) {
+		im.reject(record, r.ID, fmt.Errorf("valid_to is before valid_from"))
+		return nil
+	}
+
+	existing, _ := im.t.graph.GetRelation(im.ctx, r.ID)
+	if existing != nil {
+		im.result.RelationsUpdated++
+		if im.opts.DryRun {
+			return nil
+		}
+		if err := im.t.graph.UpdateRelation(im.ctx, r); err != nil {
+			return fmt.Errorf("failed to update relation %s: %w", r.ID, err)
+		}
+		return nil
+	}
+
+	im.result.RelationsCreated++
+	if im.opts.DryRun {
+		return nil
+	}
+	if err := im.t.graph.CreateRelation(im.ctx, r); err != nil {
+		return fmt.Errorf("failed to create relation %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+// finish 处理延后的关系并返回导入结果
+func (im *graphImporter) finish() (*models.GraphImportResult, error) {
+	for _, p := range im.pending {
+		r := p.relation
+		if !im.resolve(r.FromEntity) {
+			im.reject(p.record, r.ID, fmt.Errorf("unknown entity %s", r.FromEntity))
+			continue
+		}
+		if !im.resolve(r.ToEntity) {
+			im.reject(p.record, r.ID, fmt.Errorf("unknown entity %s", r.ToEntity))
+			continue
+		}
+		if err := im.writeRelation(p.record, r); err != nil {
+			return nil, err
+		}
+	}
+	im.pending = nil
+	return im.result, nil
+}
+
+// CSV和GraphML共用的扁平字段
+var (
+	entityColumns   = []string{"id", "type", "name", "labels", "score", "source", "properties", "created_at", "updated_at"}
+	relationColumns = []string{"id", "type", "from", "to", "score", "source", "properties", "valid_from", "valid_to", "superseded_by", "created_at", "updated_at"}
+)
+
+// labelSeparator 扁平格式中多个标签的分隔符
+const labelSeparator = "|"
+
+// entityFields 按entityColumns顺序展开实体
+func entityFields(e *models.KnowledgeEntity) []string {
+	return []string{
+		e.ID, e.Type, e.Name,
+		strings.Join(e.Labels, labelSeparator),
+		formatScore(e.Score), e.Source,
+		formatProperties(e.Properties),
+		formatTime(e.CreatedAt), formatTime(e.UpdatedAt),
+	}
+}
+
+// relationFields 按relationColumns顺序展开关系
+func relationFields(r *models.KnowledgeRelation) []string {
+	validTo := ""
+	if r.ValidTo != nil {
+		validTo = formatTime(*r.ValidTo)
+	}
+	return []string{
+		r.ID, r.Type, r.FromEntity, r.ToEntity,
+		formatScore(r.Score), r.Source,
+		formatProperties(r.Properties),
+		formatTime(r.ValidFrom), validTo, r.SupersededBy,
+		formatTime(r.CreatedAt), formatTime(r.UpdatedAt),
+	}
+}
+
+// entityFromFields 从扁平字段还原实体，未知字段作为属性保留
+func (im *graphImporter) entityFromFields(record string, fields map[string]string) error {
+	e := &models.KnowledgeEntity{
+		ID:     fields["id"],
+		Type:   fields["type"],
+		Name:   fields["name"],
+		Source: fields["source"],
+	}
+	if e.Name == "" {
+		e.Name = fields["label"] // 常见图工具的显示名字段
+	}
+	if v := fields["labels"]; v != "" {
+		e.Labels = strings.Split(v, labelSeparator)
+	}
+
+	var p fieldParser
+	e.Score = p.score(fields["score"])
+	e.Properties = p.properties(fields["properties"])
+	e.CreatedAt = p.time(fields["created_at"])
+	e.UpdatedAt = p.time(fields["updated_at"])
+	if p.err != nil {
+		im.reject(record, e.ID, p.err)
+		return nil
+	}
+	e.Properties = extraProperties(e.Properties, fields, entityColumns, "label")
+	return im.entity(record, e)
+}
+
+// relationFromFields 从扁平字段还原关系，未知字段作为属性保留
+func (im *graphImporter) relationFromFields(record string, fields map[string]string) error {
+	r := &models.KnowledgeRelation{
+		ID:           fields["id"],
+		Type:         fields["type"],
+		FromEntity:   fields["from"],
+		ToEntity:     fields["to"],
+		Source:       fields["source"],
+		SupersededBy: fields["superseded_by"],
+	}
+
+	var p fieldParser
+	r.Score = p.score(fields["score"])
+	r.Properties = p.properties(fields["properties"])
+	r.ValidFrom = p.time(fields["valid_from"])
+	validTo := p.time(fields["valid_to"])
+	r.CreatedAt = p.time(fields["created_at"])
+	r.UpdatedAt = p.time(fields["updated_at"])
+	if p.err != nil {
+		im.reject(record, r.ID, p.err)
+		return nil
+	}
+	if !validTo.IsZero() {
+		r.ValidTo = &validTo
+	}
+	r.Properties = extraProperties(r.Properties, fields, relationColumns)
+	return im.relation(record, r)
+}
+
+// extraProperties 将非标准字段合并到属性中
+func extraProperties(props map[string]interface{}, fields map[string]string, columns []string, ignore ...string) map[string]interface{} {
+	standard := make(map[string]bool, len(columns)+len(ignore))
+	for _, c := range append(append([]string{}, columns...), ignore...) {
+		standard[c] = true
+	}
+	for k, v := range fields {
+		if standard[k] || v == "" {
+			continue
+		}
+		if props == nil {
+			props = make(map[string]interface{})
+		}
+		if _, ok := props[k]; !ok {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+func formatProperties(props map[string]interface{}) string {
+	if len(props) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// fieldParser 解析扁平字段，记录第一个错误，空值解析为零值
+type fieldParser struct {
+	err error
+}
+
+func (p *fieldParser) score(v string) float64 {
+	if v == "" || p.err != nil {
+		return 0
+	}
+	score, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		p.err = fmt.Errorf("invalid score %q", v)
+	}
+	return score
+}
+
+func (p *fieldParser) properties(v string) map[string]interface{} {
+	if v == "" || p.err != nil {
+		return nil
+	}
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(v), &props); err != nil {
+		p.err = fmt.Errorf("invalid properties: %w", err)
+	}
+	return props
+}
+
+func (p *fieldParser) time(v string) time.Time {
+	if v == "" || p.err != nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		p.err = fmt.Errorf("invalid time %q", v)
+	}
+	return t
+}
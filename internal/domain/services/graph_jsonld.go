@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+)
+
+// JSON-LD中实体和关系节点的类型
+const (
+	jsonldEntityType   = "Entity"
+	jsonldRelationType = "Relation"
+)
+
+// jsonldContext 导出文档的@context，关系以带from/to引用的节点表示
+var jsonldContext = map[string]interface{}{
+	"@vocab":       "urn:ino:schema#",
+	"@base":        "urn:ino:node:",
+	"xsd":          "http://www.w3.org/2001/XMLSchema#",
+	"from":         map[string]string{"@type": "@id"},
+	"to":           map[string]string{"@type": "@id"},
+	"supersededBy": map[string]string{"@type": "@id"},
+	"labels":       map[string]string{"@container": "@set"},
+	"properties":   map[string]string{"@type": "@json"},
+	"score":        map[string]string{"@type": "xsd:double"},
+	"validFrom":    map[string]string{"@type": "xsd:dateTime"},
+	"validTo":      map[string]string{"@type": "xsd:dateTime"},
+	"createdAt":    map[string]string{"@type": "xsd:dateTime"},
+	"updatedAt":    map[string]string{"@type": "xsd:dateTime"},
+}
+
+// jsonldNode @graph中的一个节点
+type jsonldNode struct {
+	ID           string                 `json:"@id,omitempty"`
+	Type         string                 `json:"@type"`
+	EntityType   string                 `json:"entityType,omitempty"`
+	RelationType string                 `json:"relationType,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	From         string                 `json:"from,omitempty"`
+	To           string                 `json:"to,omitempty"`
+	Labels       []string               `json:"labels,omitempty"`
+	Properties   map[string]interface{} `json:"properties,omitempty"`
+	Source       string                 `json:"source,omitempty"`
+	Score        float64                `json:"score"`
+	ValidFrom    *time.Time             `json:"validFrom,omitempty"`
+	ValidTo      *time.Time             `json:"validTo,omitempty"`
+	SupersededBy string                 `json:"supersededBy,omitempty"`
+	CreatedAt    *time.Time             `json:"createdAt,omitempty"`
+	UpdatedAt    *time.Time             `json:"updatedAt,omitempty"`
+}
+
+// jsonldWriter 流式写出JSON-LD文档，每个节点单独编码
+type jsonldWriter struct {
+	w     *bufio.Writer
+	first bool
+}
+
+func newJSONLDWriter(w io.Writer, domainID uint64) (*jsonldWriter, error) {
+	bw := bufio.NewWriter(w)
+	ctx, err := json.Marshal(jsonldContext)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(bw, "{\"@context\":%s,\"domainId\":%d,\"@graph\":[\n", ctx, domainID); err != nil {
+		return nil, err
+	}
+	return &jsonldWriter{w: bw, first: true}, nil
+}
+
+func (jw *jsonldWriter) write(node *jsonldNode) error {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	if !jw.first {
+		if _, err := jw.w.WriteString(",\n"); err != nil {
+			return err
+		}
+	}
+	jw.first = false
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonldWriter) WriteEntity(e *models.KnowledgeEntity) error {
+	return jw.write(&jsonldNode{
+		ID:         e.ID,
+		Type:       jsonldEntityType,
+		EntityType: e.Type,
+		Name:       e.Name,
+		Labels:     e.Labels,
+		Properties: e.Properties,
+		Source:     e.Source,
+		Score:      e.Score,
+		CreatedAt:  optionalTime(e.CreatedAt),
+		UpdatedAt:  optionalTime(e.UpdatedAt),
+	})
+}
+
+func (jw *jsonldWriter) WriteRelation(r *models.KnowledgeRelation) error {
+	return jw.write(&jsonldNode{
+		ID:           r.ID,
+		Type:         jsonldRelationType,
+		RelationType: r.Type,
+		From:         r.FromEntity,
+		To:           r.ToEntity,
+		Properties:   r.Properties,
+		Source:       r.Source,
+		Score:        r.Score,
+		ValidFrom:    optionalTime(r.ValidFrom),
+		ValidTo:      r.ValidTo,
+		SupersededBy: r.SupersededBy,
+		CreatedAt:    optionalTime(r.CreatedAt),
+		UpdatedAt:    optionalTime(r.UpdatedAt),
+	})
+}
+
+func (jw *jsonldWriter) Close() error {
+	if _, err := jw.w.WriteString("\n]}\n"); err != nil {
+		return err
+	}
+	return jw.w.Flush()
+}
+
+// optionalTime 零值时间编码为缺省
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// readJSONLD 流式读取JSON-LD文档的@graph数组，逐个解码节点；其他顶层字段被跳过
+func readJSONLD(r io.Reader, im *graphImporter) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("%w: json-ld: %w", ErrInvalidGraphFile, err)
+		}
+		if key, _ := tok.(string); key != "@graph" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("%w: json-ld: %w", ErrInvalidGraphFile, err)
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for i := 0; dec.More(); i++ {
+			var node jsonldNode
+			if err := dec.Decode(&node); err != nil {
+				return fmt.Errorf("%w: json-ld node %d: %w", ErrInvalidGraphFile, i, err)
+			}
+			if err := im.importJSONLDNode(fmt.Sprintf("@graph[%d]", i), &node); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expectDelim 读取下一个JSON分隔符
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("%w: json-ld: %w", ErrInvalidGraphFile, err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("%w: json-ld: expected %q", ErrInvalidGraphFile, want)
+	}
+	return nil
+}
+
+// importJSONLDNode 将JSON-LD节点转换为实体或关系
+func (im *graphImporter) importJSONLDNode(record string, node *jsonldNode) error {
+	switch node.Type {
+	case jsonldEntityType:
+		e := &models.KnowledgeEntity{
+			ID:         node.ID,
+			Type:       node.EntityType,
+			Name:       node.Name,
+			Labels:     node.Labels,
+			Properties: node.Properties,
+			Source:     node.Source,
+			Score:      node.Score,
+		}
+		if node.CreatedAt != nil {
+			e.CreatedAt = *node.CreatedAt
+		}
+		if node.UpdatedAt != nil {
+			e.UpdatedAt = *node.UpdatedAt
+		}
+		return im.entity(record, e)
+
+	case jsonldRelationType:
+		r := &models.KnowledgeRelation{
+			ID:           node.ID,
+			Type:         node.RelationType,
+			FromEntity:   node.From,
+			ToEntity:     node.To,
+			Properties:   node.Properties,
+			Source:       node.Source,
+			Score:        node.Score,
+			ValidTo:      node.ValidTo,
+			SupersededBy: node.SupersededBy,
+		}
+		if node.ValidFrom != nil {
+			r.ValidFrom = *node.ValidFrom
+		}
+		if node.CreatedAt != nil {
+			r.CreatedAt = *node.CreatedAt
+		}
+		if node.UpdatedAt != nil {
+			r.UpdatedAt = *node.UpdatedAt
+		}
+		return im.relation(record, r)
+
+	default:
+		im.reject(record, node.ID, fmt.Errorf("unknown node type %q", node.Type))
+		return nil
+	}
+}
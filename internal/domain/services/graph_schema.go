@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// GraphSchema 知识域的图谱模式。未声明类型时不限制类型；
+// 声明了类型时，只接受声明的类型及其同义词，同义词映射为规范类型。
+type GraphSchema struct {
+	nodeTypes     map[string]string // 小写类型或同义词 -> 规范类型
+	relationTypes map[string]string
+	strictNodes   bool
+	strictRels    bool
+	exclusive     map[string]bool // 小写关系类型
+	maxDepth      int
+	minScore      float64
+}
+
+// NewGraphSchema 根据知识域图配置创建图谱模式
+func NewGraphSchema(cfg models.GraphConfig) *GraphSchema {
+	s := &GraphSchema{
+		nodeTypes:     canonicalTypes(cfg.NodeTypes, cfg.NodeTypeSynonyms),
+		relationTypes: canonicalTypes(cfg.RelationTypes, cfg.RelationTypeSynonyms),
+		strictNodes:   len(cfg.NodeTypes) > 0,
+		strictRels:    len(cfg.RelationTypes) > 0,
+		exclusive:     make(map[string]bool, len(cfg.ExclusiveRelationTypes)),
+		maxDepth:      cfg.MaxDepth,
+		minScore:      cfg.MinScore,
+	}
+	for _, t := range cfg.ExclusiveRelationTypes {
+		s.exclusive[strings.ToLower(t)] = true
+	}
+	return s
+}
+
+// canonicalTypes 构建大小写不敏感的类型表，同义词指向规范类型（按声明的写法）
+func canonicalTypes(types []string, synonyms map[string]string) map[string]string {
+	declared := make(map[string]string, len(types))
+	for _, t := range types {
+		declared[strings.ToLower(t)] = t
+	}
+	m := make(map[string]string, len(types)+len(synonyms))
+	for synonym, t := range synonyms {
+		if canonical, ok := declared[strings.ToLower(t)]; ok {
+			t = canonical
+		}
+		m[strings.ToLower(synonym)] = t
+	}
+	// 规范类型优先于同名同义词
+	for k, t := range declared {
+		m[k] = t
+	}
+	return m
+}
+
+// NodeType 返回规范的实体类型，类型不在模式中时ok为false
+func (s *GraphSchema) NodeType(t string) (string, bool) {
+	return lookupType(s.nodeTypes, s.strictNodes, t)
+}
+
+// RelationType 返回规范的关系类型，类型不在模式中时ok为false
+func (s *GraphSchema) RelationType(t string) (string, bool) {
+	return lookupType(s.relationTypes, s.strictRels, t)
+}
+
+// lookupType 查找规范类型。非严格模式下未知类型原样接受，但同义词仍会被映射。
+func lookupType(types map[string]string, strict bool, t string) (string, bool) {
+	if canonical, ok := types[strings.ToLower(strings.TrimSpace(t))]; ok {
+		return canonical, true
+	}
+	return t, !strict
+}
+
+// IsExclusive 关系类型是否为排他关系
+func (s *GraphSchema) IsExclusive(relationType string) bool {
+	return s.exclusive[strings.ToLower(relationType)]
+}
+
+// ClampDepth 将遍历深度限制在知识域的MaxDepth内，未指定深度时取MaxDepth
+func (s *GraphSchema) ClampDepth(depth int) int {
+	if s.maxDepth <= 0 {
+		return depth
+	}
+	if depth <= 0 || depth > s.maxDepth {
+		return s.maxDepth
+	}
+	return depth
+}
+
+// AcceptScore 置信度是否达到知识域的MinScore
+func (s *GraphSchema) AcceptScore(score float64) bool {
+	return score >= s.minScore
+}
+
+// ValidateEntity 校验并规范化实体类型
+func (s *GraphSchema) ValidateEntity(e *models.KnowledgeEntity) error {
+	t, ok := s.NodeType(e.Type)
+	if !ok {
+		return fmt.Errorf("%w: entity type %q is not allowed", ErrSchemaViolation, e.Type)
+	}
+	e.Type = t
+	return nil
+}
+
+// ValidateRelation 校验并规范化关系类型
+func (s *GraphSchema) ValidateRelation(r *models.KnowledgeRelation) error {
+	t, ok := s.RelationType(r.Type)
+	if !ok {
+		return fmt.Errorf("%w: relation type %q is not allowed", ErrSchemaViolation, r.Type)
+	}
+	r.Type = t
+	return nil
+}
+
+// LoadGraphSchema 加载知识域的图谱模式，domainID为0时返回不做限制的模式
+func LoadGraphSchema(ctx context.Context, domains repository.DomainRepository, domainID uint64) (*GraphSchema, error) {
+	if domainID == 0 || domains == nil {
+		return NewGraphSchema(models.GraphConfig{}), nil
+	}
+	domain, err := domains.GetByID(ctx, domainID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrDomainNotFound, domainID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain %d: %w", domainID, err)
+	}
+	cfg, err := domain.ParseConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewGraphSchema(cfg.GraphConfig), nil
+}
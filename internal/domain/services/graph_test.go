@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// stubGraph 返回固定遍历结果的图仓储，不在查询中按时间过滤
+type stubGraph struct {
+	repository.GraphRepository
+	paths []models.GraphPath
+}
+
+func (g *stubGraph) TraverseGraph(ctx context.Context, config *models.GraphTraversal) (*models.GraphTraversalResult, error) {
+	result := &models.GraphTraversalResult{Paths: append([]models.GraphPath(nil), g.paths...)}
+	result.Stats.TotalPaths = len(g.paths)
+	return result, nil
+}
+
+func TestTraverseGraphAsOf(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	path := func(id string, length int, from time.Time, to *time.Time) models.GraphPath {
+		return models.GraphPath{
+			Relations: []models.KnowledgeRelation{{ID: id, ValidFrom: from, ValidTo: to}},
+			Length:    length,
+		}
+	}
+	graph := &stubGraph{paths: []models.GraphPath{
+		path("ended", 1, jan, &jun),  // 1月到6月成立
+		path("current", 3, jun, nil), // 6月起成立
+	}}
+
+	cases := []struct {
+		name string
+		asOf *time.Time
+		want []string
+		avg  float64
+	}{
+		{"before june", ptrTime(jan.AddDate(0, 2, 0)), []string{"ended"}, 1},
+		{"at june", &jun, []string{"current"}, 3},
+		{"december", &dec, []string{"current"}, 3},
+		{"now", nil, []string{"current"}, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &models.GraphTraversal{StartEntity: "e1", DomainID: 1, AsOf: tc.asOf}
+			result, err := TraverseGraph(context.Background(), graph, nil, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range result.Paths {
+				got = append(got, p.Relations[0].ID)
+			}
+			if len(got) != len(tc.want) || got[0] != tc.want[0] {
+				t.Fatalf("paths = %v, want %v", got, tc.want)
+			}
+			if result.Stats.TotalPaths != len(tc.want) || result.Stats.AvgPathLength != tc.avg {
+				t.Fatalf("stats = %+v", result.Stats)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// GraphWriter 图谱写入服务。关系按时间版本化：冲突的新事实取代旧关系而不是覆盖它，
+// 旧关系保留并记录失效时间，便于按时间点查询。
+type GraphWriter struct {
+	graph   repository.GraphRepository
+	domains repository.DomainRepository
+	now     func() time.Time
+}
+
+// NewGraphWriter 创建图谱写入服务
+func NewGraphWriter(graph repository.GraphRepository, domains repository.DomainRepository) *GraphWriter {
+	return &GraphWriter{graph: graph, domains: domains, now: time.Now}
+}
+
+// ApplyExtraction 将实体关系抽取结果写入图谱。关系两端可以引用抽取结果中的实体ID或实体名称。
+// 低于知识域MinScore的实体和关系被过滤，类型不符合知识域模式的被拒绝，均不影响其余结果写入。
+func (w *GraphWriter) ApplyExtraction(ctx context.Context, domainID uint64, source string, extraction *models.EntityExtractionResponse) (*models.GraphWriteResult, error) {
+	if w.graph == nil {
+		return nil, ErrGraphUnavailable
+	}
+	schema, err := LoadGraphSchema(ctx, w.domains, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.GraphWriteResult{}
+	refs := make(map[string]string) // 抽取结果中的ID或名称 -> 图谱实体ID
+	for i := range extraction.Entities {
+		entity := extraction.Entities[i]
+		if !schema.AcceptScore(entity.Score) {
+			result.Filtered++
+			continue
+		}
+		if err := schema.ValidateEntity(&entity); err != nil {
+			result.EntitiesRejected++
+			continue
+		}
+		// 抽取结果中的ID只在本次抽取内有效，不能用来匹配图谱中的实体
+		extractedID := entity.ID
+		entity.ID = ""
+		entity.DomainID = domainID
+		if entity.Source == "" {
+			entity.Source = source
+		}
+
+		stored, merged, err := w.upsertEntity(ctx, &entity)
+		if err != nil {
+			return nil, err
+		}
+		if merged {
+			result.EntitiesMerged++
+		} else {
+			result.EntitiesCreated++
+		}
+		result.EntityIDs = append(result.EntityIDs, stored.ID)
+		if extractedID != "" {
+			refs[extractedID] = stored.ID
+		}
+		refs[strings.ToLower(entity.Name)] = stored.ID
+	}
+
+	for i := range extraction.Relations {
+		relation := extraction.Relations[i]
+		if !schema.AcceptScore(relation.Score) {
+			result.Filtered++
+			continue
+		}
+		from, okFrom := resolveEntityRef(refs, relation.FromEntity)
+		to, okTo := resolveEntityRef(refs, relation.ToEntity)
+		if !okFrom || !okTo {
+			result.RelationsRejected++
+			continue
+		}
+		if err := schema.ValidateRelation(&relation); err != nil {
+			result.RelationsRejected++
+			continue
+		}
+		relation.ID = ""
+		relation.FromEntity, relation.ToEntity = from, to
+		relation.DomainID = domainID
+		if relation.Source == "" {
+			relation.Source = source
+		}
+
+		change, err := w.addRelation(ctx, schema, &relation)
+		if err != nil {
+			return nil, err
+		}
+		result.RelationChanges = append(result.RelationChanges, *change)
+	}
+	return result, nil
+}
+
+// resolveEntityRef 按ID或名称解析实体引用
+func resolveEntityRef(refs map[string]string, ref string) (string, bool) {
+	if id, ok := refs[ref]; ok {
+		return id, true
+	}
+	id, ok := refs[strings.ToLower(ref)]
+	return id, ok
+}
+
+// upsertEntity 写入已通过模式校验的实体，同一知识域内同名同类型的实体会被合并。
+// 返回图谱中的实体及是否发生合并
+func (w *GraphWriter) upsertEntity(ctx context.Context, entity *models.KnowledgeEntity) (*models.KnowledgeEntity, bool, error) {
+	now := w.now()
+	existing, err := w.findEntity(ctx, entity)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		entity.ID = NewID("entity")
+		entity.CreatedAt, entity.UpdatedAt = now, now
+		if err := w.graph.CreateEntity(ctx, entity); err != nil {
+			return nil, false, fmt.Errorf("failed to create entity %s: %w", entity.Name, err)
+		}
+		return entity, false, nil
+	}
+
+	existing.Labels = mergeLabels(existing.Labels, entity.Labels)
+	if existing.Properties == nil {
+		existing.Properties = make(map[string]interface{})
+	}
+	for k, v := range entity.Properties {
+		existing.Properties[k] = v
+	}
+	if entity.Score > existing.Score {
+		existing.Score = entity.Score
+	}
+	existing.UpdatedAt = now
+	if err := w.graph.UpdateEntity(ctx, existing); err != nil {
+		return nil, false, fmt.Errorf("failed to update entity %s: %w", existing.ID, err)
+	}
+	return existing, true, nil
+}
+
+// findEntity 按同知识域内的同名同类型查找已有实体
+func (w *GraphWriter) findEntity(ctx context.Context, entity *models.KnowledgeEntity) (*models.KnowledgeEntity, error) {
+	var types []string
+	if entity.Type != "" {
+		types = []string{entity.Type}
+	}
+	candidates, err := w.graph.SearchEntities(ctx, entity.Name, []uint64{entity.DomainID}, types, 10)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search entities: %w", err)
+	}
+	for _, c := range candidates {
+		if strings.EqualFold(c.Name, entity.Name) {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// addRelation 写入已通过模式校验的关系并处理与已有关系的冲突：
+//   - 已有相同的当前关系：更新置信度（reinforced）
+//   - 否定性关系：使相同的当前关系在新关系生效时失效，否定关系本身不写入（invalidated）
+//   - 排他关系类型指向不同目标：旧关系在新关系生效时失效（superseded）；
+//     若新关系生效时间早于已有关系，则作为已失效的历史关系写入（historical）
+func (w *GraphWriter) addRelation(ctx context.Context, schema *GraphSchema, relation *models.KnowledgeRelation) (*models.RelationChange, error) {
+	now := w.now()
+	if relation.ID == "" {
+		relation.ID = NewID("rel")
+	}
+	if relation.ValidFrom.IsZero() {
+		relation.ValidFrom = now
+	}
+	relation.CreatedAt, relation.UpdatedAt = now, now
+	change := &models.RelationChange{Relation: relation, DetectedAt: now}
+
+	existing, err := w.graph.ListRelations(ctx, relation.FromEntity, "", relation.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relations: %w", err)
+	}
+	var open []*models.KnowledgeRelation
+	for _, r := range existing {
+		if r.ValidTo == nil && r.DomainID == relation.DomainID {
+			open = append(open, r)
+		}
+	}
+
+	if negated, _ := relation.Properties[models.RelationPropNegated].(bool); negated {
+		change.Action = models.RelationActionInvalidated
+		for _, r := range open {
+			if r.ToEntity != relation.ToEntity || !r.ValidFrom.Before(relation.ValidFrom) {
+				continue
+			}
+			if err := w.closeRelation(ctx, r, relation.ValidFrom, "", relation.Source); err != nil {
+				return nil, err
+			}
+			change.Conflicts = append(change.Conflicts, r.ID)
+		}
+		return change, nil
+	}
+
+	for _, r := range open {
+		if r.ToEntity != relation.ToEntity {
+			continue
+		}
+		if relation.Score > r.Score {
+			r.Score = relation.Score
+		}
+		if relation.ValidFrom.Before(r.ValidFrom) {
+			r.ValidFrom = relation.ValidFrom
+		}
+		r.UpdatedAt = now
+		if err := w.graph.UpdateRelation(ctx, r); err != nil {
+			return nil, fmt.Errorf("failed to update relation %s: %w", r.ID, err)
+		}
+		change.Action = models.RelationActionReinforced
+		change.Relation = r
+		return change, nil
+	}
+
+	change.Action = models.RelationActionCreated
+	if schema.IsExclusive(relation.Type) {
+		var older, newer []*models.KnowledgeRelation
+		for _, r := range open {
+			if r.ValidFrom.Before(relation.ValidFrom) {
+				older = append(older, r)
+			} else {
+				newer = append(newer, r)
+			}
+		}
+
+		if len(newer) > 0 {
+			// 迟到的旧事实：在最早的已有关系生效时失效
+			sort.Slice(newer, func(i, j int) bool { return newer[i].ValidFrom.Before(newer[j].ValidFrom) })
+			validTo := newer[0].ValidFrom
+			relation.ValidTo = &validTo
+			relation.SupersededBy = newer[0].ID
+			change.Action = models.RelationActionHistorical
+			change.Conflicts = append(change.Conflicts, newer[0].ID)
+		} else {
+			for _, r := range older {
+				if err := w.closeRelation(ctx, r, relation.ValidFrom, relation.ID, relation.Source); err != nil {
+					return nil, err
+				}
+				change.Action = models.RelationActionSuperseded
+				change.Conflicts = append(change.Conflicts, r.ID)
+			}
+		}
+	}
+
+	if err := w.graph.CreateRelation(ctx, relation); err != nil {
+		return nil, fmt.Errorf("failed to create relation: %w", err)
+	}
+	return change, nil
+}
+
+// closeRelation 使关系在指定时间失效
+func (w *GraphWriter) closeRelation(ctx context.Context, r *models.KnowledgeRelation, at time.Time, supersededBy, source string) error {
+	r.ValidTo = &at
+	r.SupersededBy = supersededBy
+	r.UpdatedAt = w.now()
+	if r.Properties == nil {
+		r.Properties = make(map[string]interface{})
+	}
+	r.Properties["invalidated_by_source"] = source
+	if err := w.graph.UpdateRelation(ctx, r); err != nil {
+		return fmt.Errorf("failed to close relation %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+// RelationHistory 返回两个实体之间关系的全部版本，按生效时间排序
+func (w *GraphWriter) RelationHistory(ctx context.Context, fromEntity, toEntity, relationType string) ([]*models.KnowledgeRelation, error) {
+	if w.graph == nil {
+		return nil, ErrGraphUnavailable
+	}
+	relations, err := w.graph.ListRelations(ctx, fromEntity, toEntity, relationType)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(relations, func(i, j int) bool {
+		return relations[i].ValidFrom.Before(relations[j].ValidFrom)
+	})
+	return relations, nil
+}
+
+// mergeLabels 合并标签并去重
+func mergeLabels(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	merged := make([]string, 0, len(a)+len(b))
+	for _, l := range append(append([]string{}, a...), b...) {
+		if !seen[l] {
+			seen[l] = true
+			merged = append(merged, l)
+		}
+	}
+	return merged
+}
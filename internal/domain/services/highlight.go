@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// HighlightOptions 高亮参数
+type HighlightOptions struct {
+	SnippetChars      int     // 片段长度（字符）
+	MaxSnippets       int     // 每个结果的片段数上限
+	PreTag            string  // 高亮起始标记
+	PostTag           string  // 高亮结束标记
+	SemanticThreshold float64 // 没有词命中时，句子与查询的相似度达到该值才作为片段
+	MaxSentences      int     // 单次检索参与语义匹配的句子数上限
+}
+
+// DefaultHighlightOptions 默认高亮参数
+func DefaultHighlightOptions() HighlightOptions {
+	return HighlightOptions{
+		SnippetChars:      160,
+		MaxSnippets:       2,
+		PreTag:            "<em>",
+		PostTag:           "</em>",
+		SemanticThreshold: 0.5,
+		MaxSentences:      200,
+	}
+}
+
+// Highlighter 为检索结果生成与查询相关的片段：选取查询词覆盖最多的窗口并标记命中部分，
+// 中日韩文字按二元组匹配，不依赖空格分词。没有词命中的结果按句子向量与查询的相似度选取片段。
+type Highlighter struct {
+	embedder Embedder
+	opts     HighlightOptions
+}
+
+// NewHighlighter 创建高亮服务，embedder为nil时只做词匹配
+func NewHighlighter(embedder Embedder, opts HighlightOptions) *Highlighter {
+	return &Highlighter{embedder: embedder, opts: opts}
+}
+
+// Apply 填充结果的Highlights，结构化的片段和位置记录在元数据highlights中
+func (h *Highlighter) Apply(ctx context.Context, query string, results []models.SearchResult) {
+	if h == nil {
+		return
+	}
+	terms := queryTerms(query)
+	var pending []int
+	for i := range results {
+		highlights := h.lexical(results[i].Content, terms)
+		if len(highlights) == 0 {
+			pending = append(pending, i)
+			continue
+		}
+		h.set(&results[i], highlights)
+	}
+	if len(pending) > 0 && h.embedder != nil {
+		h.semantic(ctx, query, results, pending)
+	}
+}
+
+// set 写入结果，位置换算到原文档
+func (h *Highlighter) set(r *models.SearchResult, highlights []models.Highlight) {
+	base := 0
+	if r.Type == "chunk" {
+		base = metadataInt(r.Metadata, "start_pos")
+	}
+	r.Highlights = make([]string, len(highlights))
+	for i := range highlights {
+		r.Highlights[i] = highlights[i].Snippet
+		highlights[i].Start += base
+		highlights[i].End += base
+		for j := range highlights[i].Matches {
+			highlights[i].Matches[j].Start += base
+			highlights[i].Matches[j].End += base
+		}
+	}
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]interface{})
+	}
+	r.Metadata["highlights"] = highlights
+}
+
+// queryTerms 查询词：字母数字按词，中日韩文字按二元组，去重
+func queryTerms(query string) [][]rune {
+	var terms [][]rune
+	for t := range textutil.TokenSet(query) {
+		terms = append(terms, []rune(t))
+	}
+	// 固定顺序，保证结果稳定
+	sort.Slice(terms, func(i, j int) bool { return string(terms[i]) < string(terms[j]) })
+	return terms
+}
+
+// termMatch 查询词在内容中的一次命中
+type termMatch struct {
+	term int
+	span models.TextSpan
+}
+
+// findMatches 查找查询词在内容中的全部命中。字母数字词要求词边界，避免go匹配到google
+func findMatches(content []rune, terms [][]rune) []termMatch {
+	var matches []termMatch
+	for ti, term := range terms {
+		wordTerm := !textutil.IsCJK(term[0])
+		for i := 0; i+len(term) <= len(content); i++ {
+			if !equalRunes(content[i:i+len(term)], term) {
+				continue
+			}
+			end := i + len(term)
+			if wordTerm && (i > 0 && isWordRune(content[i-1]) || end < len(content) && isWordRune(content[end])) {
+				continue
+			}
+			matches = append(matches, termMatch{term: ti, span: models.TextSpan{Start: i, End: end}})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].span.Start < matches[j].span.Start })
+	return matches
+}
+
+// equalRunes 忽略大小写比较，term已是小写
+func equalRunes(a, term []rune) bool {
+	for i := range term {
+		if unicode.ToLower(a[i]) != term[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !textutil.IsCJK(r)
+}
+
+// lexical 词匹配高亮：以每个命中为锚点取窗口，按覆盖的不同查询词数（命中次数为次要）评分，
+// 贪心选取互不重叠的最优窗口
+func (h *Highlighter) lexical(content string, terms [][]rune) []models.Highlight {
+	runes := []rune(content)
+	matches := findMatches(runes, terms)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	type window struct {
+		span  models.TextSpan
+		score float64
+		terms int
+	}
+	windows := make([]window, 0, len(matches))
+	for _, m := range matches {
+		span := h.window(runes, m.span.Start)
+		covered := make(map[int]bool)
+		count := 0
+		for _, other := range matches {
+			if other.span.Start >= span.Start && other.span.End <= span.End {
+				covered[other.term] = true
+				count++
+			}
+		}
+		windows = append(windows, window{span: span, score: float64(len(covered)) + 0.1*float64(count), terms: len(covered)})
+	}
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].score > windows[j].score })
+
+	var highlights []models.Highlight
+	var chosen []models.TextSpan
+	for _, w := range windows {
+		if len(highlights) >= h.opts.MaxSnippets {
+			break
+		}
+		overlaps := false
+		for _, c := range chosen {
+			if w.span.Start < c.End && c.Start < w.span.End {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		chosen = append(chosen, w.span)
+
+		var spans []models.TextSpan
+		for _, m := range matches {
+			if m.span.Start >= w.span.Start && m.span.End <= w.span.End {
+				spans = append(spans, m.span)
+			}
+		}
+		spans = mergeSpans(spans)
+		highlights = append(highlights, models.Highlight{
+			Snippet: h.snippet(runes, w.span, spans),
+			Start:   w.span.Start,
+			End:     w.span.End,
+			Matches: spans,
+			Score:   float64(w.terms),
+		})
+	}
+	return highlights
+}
+
+// window 以命中位置为锚点的片段窗口：锚点前保留四分之一窗口的上文，从上文中最近的句子边界之后开始
+func (h *Highlighter) window(runes []rune, anchor int) models.TextSpan {
+	size := h.opts.SnippetChars
+	start := anchor - size/4
+	if start < 0 {
+		start = 0
+	}
+	for i := anchor - 1; i >= start; i-- {
+		if isSentenceEnd(runes[i]) {
+			start = i + 1
+			break
+		}
+	}
+	// 不从英文单词中间开始
+	for start > 0 && start < anchor && isWordRune(runes[start-1]) {
+		start++
+	}
+	end := start + size
+	if end > len(runes) {
+		end = len(runes)
+		if start = end - size; start < 0 {
+			start = 0
+		}
+	}
+	return models.TextSpan{Start: start, End: end}
+}
+
+// mergeSpans 合并重叠或相接的区间，中文二元组的连续命中合并为一个短语
+func mergeSpans(spans []models.TextSpan) []models.TextSpan {
+	var merged []models.TextSpan
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.Start <= merged[n-1].End {
+			if s.End > merged[n-1].End {
+				merged[n-1].End = s.End
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// snippet 截取窗口并插入高亮标记，窗口不在内容首尾时加省略号
+func (h *Highlighter) snippet(runes []rune, window models.TextSpan, spans []models.TextSpan) string {
+	var b strings.Builder
+	if window.Start > 0 {
+		b.WriteString("…")
+	}
+	pos := window.Start
+	for _, s := range spans {
+		b.WriteString(string(runes[pos:s.Start]))
+		b.WriteString(h.opts.PreTag)
+		b.WriteString(string(runes[s.Start:s.End]))
+		b.WriteString(h.opts.PostTag)
+		pos = s.End
+	}
+	b.WriteString(string(runes[pos:window.End]))
+	if window.End < len(runes) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// isSentenceEnd 是否为句子结束符
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '。', '！', '？', '；', '.', '!', '?', ';', '\n':
+		return true
+	}
+	return false
+}
+
+// splitSentences 按句子结束符切分，返回去除首尾空白后的句子区间
+func splitSentences(runes []rune) []models.TextSpan {
+	var sentences []models.TextSpan
+	add := func(start, end int) {
+		for start < end && unicode.IsSpace(runes[start]) {
+			start++
+		}
+		for end > start && unicode.IsSpace(runes[end-1]) {
+			end--
+		}
+		if end > start {
+			sentences = append(sentences, models.TextSpan{Start: start, End: end})
+		}
+	}
+	start := 0
+	for i, r := range runes {
+		if isSentenceEnd(r) {
+			add(start, i+1)
+			start = i + 1
+		}
+	}
+	add(start, len(runes))
+	return sentences
+}
+
+// semanticCandidate 参与语义匹配的句子
+type semanticCandidate struct {
+	result   int
+	sentence models.TextSpan
+}
+
+// semantic 对没有词命中的结果按句子语义相似度选取片段，所有句子一次批量向量化，失败时不填充
+func (h *Highlighter) semantic(ctx context.Context, query string, results []models.SearchResult, pending []int) {
+	texts := []string{query}
+	var candidates []semanticCandidate
+	contents := make(map[int][]rune, len(pending))
+	for _, i := range pending {
+		runes := []rune(results[i].Content)
+		contents[i] = runes
+		for _, s := range splitSentences(runes) {
+			if len(candidates) >= h.opts.MaxSentences {
+				break
+			}
+			candidates = append(candidates, semanticCandidate{result: i, sentence: s})
+			texts = append(texts, string(runes[s.Start:s.End]))
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	vectors, err := h.embedder.Embed(ctx, texts)
+	if err != nil || len(vectors) != len(texts) {
+		log.Printf("Warning: failed to embed sentences for highlighting: %v", err)
+		return
+	}
+	best := make(map[int]semanticCandidate)
+	bestScore := make(map[int]float64)
+	for k, c := range candidates {
+		score := cosineSimilarity(vectors[0], vectors[k+1])
+		if score >= h.opts.SemanticThreshold && score > bestScore[c.result] {
+			best[c.result], bestScore[c.result] = c, score
+		}
+	}
+
+	for i, c := range best {
+		runes := contents[i]
+		span := c.sentence
+		if span.End-span.Start > h.opts.SnippetChars {
+			span.End = span.Start + h.opts.SnippetChars
+		}
+		h.set(&results[i], []models.Highlight{{
+			Snippet:  h.snippet(runes, span, []models.TextSpan{span}),
+			Start:    span.Start,
+			End:      span.End,
+			Matches:  []models.TextSpan{span},
+			Score:    bestScore[i],
+			Semantic: true,
+		}})
+	}
+}
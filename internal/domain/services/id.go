@@ -0,0 +1,18 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// NewID 生成带前缀的唯一ID，如 query_1a2b3c...
+func NewID(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		// 随机源不可用时退化为时间戳
+		return fmt.Sprintf("%s_%x", prefix, time.Now().UnixNano())
+	}
+	return prefix + "_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ChatMessage 大模型对话消息
+type ChatMessage struct {
+	Role    string `json:"role"` // system, user, assistant
+	Content string `json:"content"`
+}
+
+// ChatModel 大模型对话接口
+type ChatModel interface {
+	Chat(ctx context.Context, messages []ChatMessage) (string, error)
+}
+
+// Embedder 文本向量化接口
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// complete 以单轮system+user提示调用大模型
+func complete(ctx context.Context, llm ChatModel, system, user string) (string, error) {
+	if llm == nil {
+		return "", ErrLLMUnavailable
+	}
+	return llm.Chat(ctx, []ChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: user},
+	})
+}
+
+// embedOne 向量化单条文本
+func embedOne(ctx context.Context, embedder Embedder, text string) ([]float32, error) {
+	if embedder == nil {
+		return nil, ErrEmbedderUnavailable
+	}
+	vectors, err := embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// queryEmbeddingKey 上下文中已计算的查询向量的键
+type queryEmbeddingKey struct{}
+
+// queryEmbedding 规范化查询及其向量
+type queryEmbedding struct {
+	query  string
+	vector []float32
+}
+
+// withQueryEmbedding 在上下文中记录规范化查询的向量，同一次检索中不再重复向量化
+func withQueryEmbedding(ctx context.Context, query string, vector []float32) context.Context {
+	return context.WithValue(ctx, queryEmbeddingKey{}, queryEmbedding{query: query, vector: vector})
+}
+
+// embedQuery 向量化查询，上下文中已有同一规范化查询的向量时直接复用
+func embedQuery(ctx context.Context, embedder Embedder, query string) ([]float32, error) {
+	if e, ok := ctx.Value(queryEmbeddingKey{}).(queryEmbedding); ok && e.query == NormalizeQuery(query) {
+		return e.vector, nil
+	}
+	return embedOne(ctx, embedder, query)
+}
+
+// parseJSONResponse 从大模型输出中解析JSON，兼容```json代码块和前后说明文字
+func parseJSONResponse(text string, v interface{}) error {
+	text = strings.TrimSpace(text)
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return errors.New("no JSON found in model output")
+	}
+	closing := byte('}')
+	if text[start] == '[' {
+		closing = ']'
+	}
+	end := strings.LastIndexByte(text, closing)
+	if end < start {
+		return errors.New("unterminated JSON in model output")
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), v); err != nil {
+		return fmt.Errorf("failed to parse model output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// MemoryConsolidatorOptions 记忆整理参数，Policy为知识域未配置时的默认策略
+type MemoryConsolidatorOptions struct {
+	Policy          models.MemoryConfig
+	MaxPerUser      int // 每个用户参与两两比较的记忆上限，按创建时间取最新的
+	MaxLLMPairs     int // 每个用户每次交给大模型判断的候选对上限
+	EmbedBatchSize  int
+	PageSize        int
+	MinImportance   float64 // 衰减后的重要度下限，避免浮点数无限趋近0
+	AccessHalfLifeK float64 // 访问次数对半衰期的放大系数：halfLife * (1 + K*ln(1+accessCount))
+}
+
+// DefaultMemoryConsolidatorOptions 默认记忆整理参数
+func DefaultMemoryConsolidatorOptions() MemoryConsolidatorOptions {
+	return MemoryConsolidatorOptions{
+		Policy: models.MemoryConfig{
+			MergeThreshold:         0.92,
+			ContradictionThreshold: 0.8,
+			DecayHalfLifeDays:      90,
+			ForgetThreshold:        0.05,
+			ForgetAction:           models.MemoryForgetArchive,
+		},
+		MaxPerUser:      500,
+		MaxLLMPairs:     20,
+		EmbedBatchSize:  64,
+		PageSize:        100,
+		MinImportance:   0.001,
+		AccessHalfLifeK: 1,
+	}
+}
+
+// MemoryConsolidator 定期整理用户记忆：合并近似重复的记忆，用更新的陈述取代矛盾的旧记忆（旧记忆归档保留），
+// 按访问情况衰减重要度，并遗忘重要度过低的记忆。用户固定的记忆不会被合并掉、取代、衰减或遗忘。
+type MemoryConsolidator struct {
+	memories repository.MemoryRepository
+	audit    repository.MemoryAuditRepository
+	vector   repository.VectorRepository
+	llm      ChatModel
+	embedder Embedder
+	opts     MemoryConsolidatorOptions
+	now      func() time.Time
+}
+
+// NewMemoryConsolidator 创建记忆整理服务。llm为nil时只合并相似度达到合并阈值的记忆，不判断矛盾
+func NewMemoryConsolidator(memories repository.MemoryRepository, audit repository.MemoryAuditRepository, vector repository.VectorRepository, llm ChatModel, embedder Embedder, opts MemoryConsolidatorOptions) *MemoryConsolidator {
+	return &MemoryConsolidator{
+		memories: memories,
+		audit:    audit,
+		vector:   vector,
+		llm:      llm,
+		embedder: embedder,
+		opts:     opts,
+		now:      time.Now,
+	}
+}
+
+// memoryPolicy 知识域配置覆盖默认策略
+func (c *MemoryConsolidator) memoryPolicy(domain *models.Domain) (models.MemoryConfig, error) {
+	policy := c.opts.Policy
+	cfg, err := domain.ParseConfig()
+	if err != nil {
+		return policy, err
+	}
+	custom := cfg.MemoryConfig
+	policy.Disabled = custom.Disabled
+	if custom.MergeThreshold != 0 {
+		policy.MergeThreshold = custom.MergeThreshold
+	}
+	if custom.ContradictionThreshold != 0 {
+		policy.ContradictionThreshold = custom.ContradictionThreshold
+	}
+	if custom.DecayHalfLifeDays != 0 {
+		policy.DecayHalfLifeDays = custom.DecayHalfLifeDays
+	}
+	if custom.ForgetThreshold != 0 {
+		policy.ForgetThreshold = custom.ForgetThreshold
+	}
+	switch custom.ForgetAction {
+	case "":
+	case models.MemoryForgetArchive, models.MemoryForgetDelete:
+		policy.ForgetAction = custom.ForgetAction
+	default:
+		return policy, fmt.Errorf("invalid memory forget_action %q of domain %s", custom.ForgetAction, domain.DomainName)
+	}
+	return policy, nil
+}
+
+// Consolidate 整理知识域内所有用户的记忆，单个用户失败时记录日志并继续
+func (c *MemoryConsolidator) Consolidate(ctx context.Context, domain *models.Domain) (*models.MemoryConsolidateResult, error) {
+	result := &models.MemoryConsolidateResult{DomainID: domain.ID}
+	policy, err := c.memoryPolicy(domain)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Disabled {
+		return result, nil
+	}
+
+	userIDs, err := c.memories.ListUserIDs(ctx, domain.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := c.consolidateUser(ctx, policy, userID, domain.ID, result); err != nil {
+			log.Printf("Warning: failed to consolidate memories of user %d in domain %s: %v", userID, domain.DomainName, err)
+			continue
+		}
+		result.Users++
+	}
+	return result, nil
+}
+
+// consolidateUser 整理单个用户在知识域中的记忆：先合并和取代，再对留下的记忆衰减和遗忘
+func (c *MemoryConsolidator) consolidateUser(ctx context.Context, policy models.MemoryConfig, userID, domainID uint64, result *models.MemoryConsolidateResult) error {
+	memories, err := c.listActive(ctx, userID, domainID)
+	if err != nil {
+		return err
+	}
+	now := c.now()
+
+	retired := make(map[string]bool)
+	if c.embedder != nil && len(memories) > 1 {
+		if err := c.resolveOverlaps(ctx, policy, memories, now, retired, result); err != nil {
+			return err
+		}
+	}
+
+	var forgotten []*models.Memory
+	for _, m := range memories {
+		if retired[m.MemoryID] || m.Pinned {
+			continue
+		}
+		if c.decay(policy, m, now) {
+			result.Decayed++
+		}
+		if policy.ForgetThreshold >= 0 && m.ImportanceScore < policy.ForgetThreshold {
+			forgotten = append(forgotten, m)
+			continue
+		}
+		if m.DecayedAt == nil {
+			continue
+		}
+		if err := c.memories.UpdateDecay(ctx, m.MemoryID, m.ImportanceScore, *m.DecayedAt); err != nil {
+			return fmt.Errorf("failed to update memory %s: %w", m.MemoryID, err)
+		}
+	}
+	return c.forget(ctx, policy, forgotten, now, result)
+}
+
+// listActive 分页读取用户的未归档记忆，最多MaxPerUser条
+func (c *MemoryConsolidator) listActive(ctx context.Context, userID, domainID uint64) ([]*models.Memory, error) {
+	var all []*models.Memory
+	for offset := 0; len(all) < c.opts.MaxPerUser; offset += c.opts.PageSize {
+		page, err := c.memories.ListByUser(ctx, userID, domainID, offset, c.opts.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < c.opts.PageSize {
+			break
+		}
+	}
+	if len(all) > c.opts.MaxPerUser {
+		all = all[:c.opts.MaxPerUser]
+	}
+	return all, nil
+}
+
+// memoryPair 相似的同类记忆对，older创建时间不晚于newer
+type memoryPair struct {
+	older, newer *models.Memory
+	similarity   float64
+}
+
+// resolveOverlaps 两两比较同类记忆：相似度达到合并阈值的直接合并；
+// 达到矛盾阈值的交给大模型判断是重复、矛盾还是无关。被合并或取代的记忆记入retired。
+func (c *MemoryConsolidator) resolveOverlaps(ctx context.Context, policy models.MemoryConfig, memories []*models.Memory, now time.Time, retired map[string]bool, result *models.MemoryConsolidateResult) error {
+	vectors, err := c.embed(ctx, memories)
+	if err != nil {
+		return err
+	}
+
+	threshold := policy.MergeThreshold
+	if c.llm != nil && policy.ContradictionThreshold > 0 && policy.ContradictionThreshold < threshold {
+		threshold = policy.ContradictionThreshold
+	}
+	var pairs []memoryPair
+	for i := range memories {
+		for j := i + 1; j < len(memories); j++ {
+			if memories[i].MemoryType != memories[j].MemoryType {
+				continue
+			}
+			similarity := cosineSimilarity(vectors[i], vectors[j])
+			if similarity < threshold {
+				continue
+			}
+			older, newer := memories[i], memories[j]
+			if newer.CreatedAt.Before(older.CreatedAt) {
+				older, newer = newer, older
+			}
+			pairs = append(pairs, memoryPair{older: older, newer: newer, similarity: similarity})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].similarity > pairs[j].similarity })
+
+	var duplicates, candidates []memoryPair
+	for _, p := range pairs {
+		if p.similarity >= policy.MergeThreshold {
+			duplicates = append(duplicates, p)
+		} else if len(candidates) < c.opts.MaxLLMPairs {
+			candidates = append(candidates, p)
+		}
+	}
+	var contradictions []memoryPair
+	if len(candidates) > 0 {
+		judged, err := c.judge(ctx, candidates)
+		if err != nil {
+			// 大模型判断失败不影响确定的合并
+			log.Printf("Warning: failed to judge memory pairs: %v", err)
+		}
+		for i, relation := range judged {
+			switch relation {
+			case "duplicate":
+				duplicates = append(duplicates, candidates[i])
+			case "contradiction":
+				contradictions = append(contradictions, candidates[i])
+			}
+		}
+	}
+
+	// 矛盾优先于重复处理：被取代的旧记忆不应再吸收其他记忆
+	for _, p := range contradictions {
+		if retired[p.older.MemoryID] || retired[p.newer.MemoryID] || p.older.Pinned {
+			continue
+		}
+		if err := c.supersede(ctx, p.older, p.newer, now); err != nil {
+			return err
+		}
+		retired[p.older.MemoryID] = true
+		result.Superseded++
+	}
+	for _, p := range duplicates {
+		if retired[p.older.MemoryID] || retired[p.newer.MemoryID] {
+			continue
+		}
+		// 固定的记忆保留，另一条合并进来
+		keep, drop := p.older, p.newer
+		if drop.Pinned {
+			keep, drop = drop, keep
+		}
+		if drop.Pinned {
+			continue
+		}
+		if err := c.merge(ctx, keep, drop); err != nil {
+			return err
+		}
+		retired[drop.MemoryID] = true
+		result.Merged++
+	}
+	return nil
+}
+
+// embed 批量向量化记忆内容。向量库不支持按ID读取向量，因此重新计算
+func (c *MemoryConsolidator) embed(ctx context.Context, memories []*models.Memory) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(memories))
+	for start := 0; start < len(memories); start += c.opts.EmbedBatchSize {
+		end := start + c.opts.EmbedBatchSize
+		if end > len(memories) {
+			end = len(memories)
+		}
+		texts := make([]string, 0, end-start)
+		for _, m := range memories[start:end] {
+			texts = append(texts, m.Content)
+		}
+		batch, err := c.embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed memories: %w", err)
+		}
+		if len(batch) != len(texts) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(batch))
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+const memoryJudgePrompt = `你是记忆整理助手。下面每一组是同一用户的两条记忆，A较早，B较新。判断每组的关系：
+- duplicate：表达的是同一件事
+- contradiction：B更新或否定了A（例如偏好改变、决定被推翻、事实已变化）
+- unrelated：可以同时成立的不同信息
+只输出JSON：{"pairs": [{"index": 0, "relation": "duplicate"}]}`
+
+// judge 调用大模型判断候选记忆对的关系，返回与candidates等长的关系列表，未判断的为空串
+func (c *MemoryConsolidator) judge(ctx context.Context, candidates []memoryPair) ([]string, error) {
+	var b strings.Builder
+	for i, p := range candidates {
+		fmt.Fprintf(&b, "[%d]\nA (%s): %s\nB (%s): %s\n\n",
+			i, p.older.CreatedAt.Format("2006-01-02"), p.older.Content,
+			p.newer.CreatedAt.Format("2006-01-02"), p.newer.Content)
+	}
+	output, err := complete(ctx, c.llm, memoryJudgePrompt, b.String())
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Pairs []struct {
+			Index    int    `json:"index"`
+			Relation string `json:"relation"`
+		} `json:"pairs"`
+	}
+	if err := parseJSONResponse(output, &parsed); err != nil {
+		return nil, err
+	}
+
+	relations := make([]string, len(candidates))
+	for _, p := range parsed.Pairs {
+		if p.Index >= 0 && p.Index < len(relations) {
+			relations[p.Index] = strings.ToLower(strings.TrimSpace(p.Relation))
+		}
+	}
+	return relations, nil
+}
+
+// merge 将newer合并进older：保留older的记忆ID和内容，累加访问次数，合并来源和关联实体，然后删除newer
+func (c *MemoryConsolidator) merge(ctx context.Context, older, newer *models.Memory) error {
+	older.AccessCount += newer.AccessCount
+	if newer.LastAccessed != nil && (older.LastAccessed == nil || newer.LastAccessed.After(*older.LastAccessed)) {
+		older.LastAccessed = newer.LastAccessed
+	}
+	older.ImportanceScore = math.Max(older.ImportanceScore, newer.ImportanceScore)
+	older.EntityIDs = mergeLabels(older.EntityIDs, newer.EntityIDs)
+	older.SourceMessageIDs = mergeLabels(older.SourceMessageIDs, newer.SourceMessageIDs)
+
+	if err := c.memories.Update(ctx, older); err != nil {
+		return fmt.Errorf("failed to update memory %s: %w", older.MemoryID, err)
+	}
+	if err := c.vector.Delete(ctx, MemoryCollection, []string{newer.MemoryID}); err != nil {
+		return fmt.Errorf("failed to delete memory vector %s: %w", newer.MemoryID, err)
+	}
+	if err := c.memories.Delete(ctx, newer.ID); err != nil {
+		return fmt.Errorf("failed to delete memory %s: %w", newer.MemoryID, err)
+	}
+	return c.audit.Create(ctx, c.auditLog(newer, models.MemoryAuditMerge, map[string]interface{}{"merged_into": older.MemoryID}))
+}
+
+// supersede 用newer取代older：older归档并指向newer，保留历史但不再参与检索
+func (c *MemoryConsolidator) supersede(ctx context.Context, older, newer *models.Memory, now time.Time) error {
+	newer.EntityIDs = mergeLabels(newer.EntityIDs, older.EntityIDs)
+	if err := c.memories.Update(ctx, newer); err != nil {
+		return fmt.Errorf("failed to update memory %s: %w", newer.MemoryID, err)
+	}
+	return c.archive(ctx, older, newer.MemoryID, now)
+}
+
+// archive 归档记忆：先删除向量再更新记录，失败时下次整理会重试
+func (c *MemoryConsolidator) archive(ctx context.Context, m *models.Memory, supersededBy string, now time.Time) error {
+	if err := c.vector.Delete(ctx, MemoryCollection, []string{m.MemoryID}); err != nil {
+		return fmt.Errorf("failed to delete memory vector %s: %w", m.MemoryID, err)
+	}
+	m.SupersededBy = supersededBy
+	m.ArchivedAt = &now
+	if err := c.memories.Update(ctx, m); err != nil {
+		return fmt.Errorf("failed to archive memory %s: %w", m.MemoryID, err)
+	}
+	return nil
+}
+
+// decay 按上次衰减（或访问、创建）以来经过的时间衰减重要度，访问越多衰减越慢。返回是否发生了衰减
+func (c *MemoryConsolidator) decay(policy models.MemoryConfig, m *models.Memory, now time.Time) bool {
+	if policy.DecayHalfLifeDays <= 0 {
+		return false
+	}
+	since := m.CreatedAt
+	for _, t := range []*time.Time{m.LastAccessed, m.DecayedAt} {
+		if t != nil && t.After(since) {
+			since = *t
+		}
+	}
+	m.DecayedAt = &now
+	elapsed := now.Sub(since)
+	if elapsed <= 0 || m.ImportanceScore <= c.opts.MinImportance {
+		return false
+	}
+
+	halfLife := policy.DecayHalfLifeDays * 24 * (1 + c.opts.AccessHalfLifeK*math.Log1p(float64(m.AccessCount)))
+	decayed := m.ImportanceScore * math.Exp(-math.Ln2*elapsed.Hours()/halfLife)
+	m.ImportanceScore = math.Max(decayed, c.opts.MinImportance)
+	return true
+}
+
+// forget 按策略归档或删除重要度过低的记忆
+func (c *MemoryConsolidator) forget(ctx context.Context, policy models.MemoryConfig, memories []*models.Memory, now time.Time, result *models.MemoryConsolidateResult) error {
+	if len(memories) == 0 {
+		return nil
+	}
+	if policy.ForgetAction != models.MemoryForgetDelete {
+		for _, m := range memories {
+			if err := c.archive(ctx, m, "", now); err != nil {
+				return err
+			}
+			result.Archived++
+		}
+		return nil
+	}
+
+	ids := make([]string, len(memories))
+	for i, m := range memories {
+		ids[i] = m.MemoryID
+	}
+	if err := c.vector.Delete(ctx, MemoryCollection, ids); err != nil {
+		return fmt.Errorf("failed to delete memory vectors: %w", err)
+	}
+	var logs []*models.MemoryAuditLog
+	for _, m := range memories {
+		if err := c.memories.Delete(ctx, m.ID); err != nil {
+			return fmt.Errorf("failed to delete memory %s: %w", m.MemoryID, err)
+		}
+		result.Deleted++
+		logs = append(logs, c.auditLog(m, models.MemoryAuditExpire, map[string]interface{}{"importance_score": m.ImportanceScore}))
+	}
+	return c.audit.Create(ctx, logs...)
+}
+
+// auditLog 整理任务删除记忆的审计日志
+func (c *MemoryConsolidator) auditLog(m *models.Memory, action string, detail map[string]interface{}) *models.MemoryAuditLog {
+	return &models.MemoryAuditLog{
+		UserID:     m.UserID,
+		Action:     action,
+		TargetType: models.MemoryAuditTargetMemory,
+		TargetID:   m.MemoryID,
+		Actor:      "memory-consolidate",
+		Detail:     detail,
+	}
+}
+
+// cosineSimilarity 余弦相似度，维度不一致或零向量时返回0
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
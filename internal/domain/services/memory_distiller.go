@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// MemoryCollection 对话记忆向量集合，向量ID为记忆ID
+const MemoryCollection = "conversation_memories"
+
+// MemoryDistillerOptions 记忆提炼参数
+type MemoryDistillerOptions struct {
+	BatchSize          int     // 每次处理的对话数
+	MaxMessages        int     // 每个对话送入大模型的最近消息数
+	MaxMessageChars    int     // 单条消息截断长度
+	DuplicateThreshold float64 // 与已有记忆的向量相似度达到该值视为重复
+	MaxAttempts        int     // 单个对话的最大提炼次数，用尽后不再重试，直到对话追加新消息
+}
+
+// DefaultMemoryDistillerOptions 默认记忆提炼参数
+func DefaultMemoryDistillerOptions() MemoryDistillerOptions {
+	return MemoryDistillerOptions{
+		BatchSize:          20,
+		MaxMessages:        50,
+		MaxMessageChars:    2000,
+		DuplicateThreshold: 0.95,
+		MaxAttempts:        5,
+	}
+}
+
+// MemoryDistiller 将对话提炼为用户长期记忆：用大模型抽取事实、偏好、决定和纠正，
+// 向量化写入记忆集合，关联图谱实体，最后标记对话已处理。
+type MemoryDistiller struct {
+	conversations repository.ConversationRepository
+	memories      repository.MemoryRepository
+	vector        repository.VectorRepository
+	writer        *GraphWriter
+	corrections   *CorrectionLearner
+	llm           ChatModel
+	embedder      Embedder
+	opts          MemoryDistillerOptions
+	now           func() time.Time
+}
+
+// NewMemoryDistiller 创建记忆提炼服务，writer为nil时不关联图谱实体，corrections为nil时不从对话中学习纠正
+func NewMemoryDistiller(conversations repository.ConversationRepository, memories repository.MemoryRepository, vector repository.VectorRepository, writer *GraphWriter, corrections *CorrectionLearner, llm ChatModel, embedder Embedder, opts MemoryDistillerOptions) *MemoryDistiller {
+	return &MemoryDistiller{
+		conversations: conversations,
+		memories:      memories,
+		vector:        vector,
+		writer:        writer,
+		corrections:   corrections,
+		llm:           llm,
+		embedder:      embedder,
+		opts:          opts,
+		now:           time.Now,
+	}
+}
+
+// extractedMemory 大模型抽取的记忆
+type extractedMemory struct {
+	Type       string   `json:"type"`
+	Content    string   `json:"content"`
+	Importance float64  `json:"importance"`
+	MessageIDs []string `json:"message_ids"`
+	Entities   []struct {
+		Name       string  `json:"name"`
+		Type       string  `json:"type"`
+		Confidence float64 `json:"confidence"` // 抽取置信度，按知识域的MinScore过滤
+	} `json:"entities"`
+	Relations []struct {
+		From       string  `json:"from"`
+		Type       string  `json:"type"`
+		To         string  `json:"to"`
+		Negated    bool    `json:"negated"`
+		Confidence float64 `json:"confidence"`
+	} `json:"relations"`
+}
+
+const memoryDistillPrompt = `你是记忆提炼助手。从用户与助手的对话中提取值得长期记住的关于用户的信息，只提取以下类型：
+- fact：关于用户本人、团队或工作环境的事实
+- preference：用户的偏好、习惯和约定
+- decision：用户做出的决定
+- correction：用户对助手错误的纠正
+每条记忆用一句不依赖上下文的完整陈述表达，给出0-1的重要度，列出来源消息ID、其中提到的实体（名称和类型）
+以及实体之间的关系（两端用实体名，事实不再成立时negated为true），实体和关系各给出0-1的抽取置信度。
+闲聊、一次性的问题和已有记忆中包含的信息不要提取。没有值得记住的内容时返回空列表。
+只输出JSON：{"memories": [{"type": "preference", "content": "记忆内容", "importance": 0.8, "message_ids": ["消息ID"], "entities": [{"name": "实体名", "type": "实体类型", "confidence": 0.9}], "relations": [{"from": "实体名", "type": "关系类型", "to": "实体名", "negated": false, "confidence": 0.9}]}]}`
+
+// ProcessPending 处理一批未提炼的对话。单个对话失败时记录日志和失败次数并在下次重试，
+// 用尽重试次数的对话不再占用批次；提炼期间对话被修改时不标记已处理，下次重新提炼
+func (d *MemoryDistiller) ProcessPending(ctx context.Context) (int, error) {
+	if d.llm == nil {
+		return 0, ErrLLMUnavailable
+	}
+	if d.embedder == nil {
+		return 0, ErrEmbedderUnavailable
+	}
+
+	conversations, err := d.conversations.ListUnprocessed(ctx, d.opts.MaxAttempts, d.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	processed := 0
+	for _, conversation := range conversations {
+		_, err := d.Process(ctx, conversation)
+		if errors.Is(err, repository.ErrStaleVersion) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: failed to distill conversation %s: %v", conversation.ConversationID, err)
+			if err := d.conversations.RecordDistillFailure(ctx, conversation.ID); err != nil {
+				log.Printf("Warning: failed to record distill failure of conversation %s: %v", conversation.ConversationID, err)
+			}
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// Process 提炼单个对话的记忆并标记对话已处理，同时学习对话中用户对助手的纠正。
+// 匿名对话无法归属到用户，不提炼记忆。只有对话在提炼期间未被修改时才标记已处理，
+// 否则返回ErrStaleVersion，追加的消息在下次提炼时处理。
+func (d *MemoryDistiller) Process(ctx context.Context, conversation *models.Conversation) (*models.MemoryDistillResult, error) {
+	if d.llm == nil {
+		return nil, ErrLLMUnavailable
+	}
+	if d.embedder == nil {
+		return nil, ErrEmbedderUnavailable
+	}
+
+	result := &models.MemoryDistillResult{ConversationID: conversation.ConversationID}
+	transcript := d.transcript(conversation.Content.Messages)
+	if conversation.UserID != 0 && transcript != "" {
+		existing, err := d.memories.ListByConversation(ctx, conversation.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		extracted, err := d.extract(ctx, transcript, existing)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.store(ctx, conversation, extracted, existing, result); err != nil {
+			return nil, err
+		}
+	}
+	if d.corrections != nil {
+		learned, err := d.corrections.FromConversation(ctx, conversation)
+		if err != nil {
+			log.Printf("Warning: failed to learn corrections from conversation %s: %v", conversation.ConversationID, err)
+		} else {
+			result.Corrections = learned.Created
+		}
+	}
+
+	processedAt := d.now()
+	if err := d.conversations.MarkProcessed(ctx, conversation.ID, conversation.Version, processedAt); err != nil {
+		return nil, fmt.Errorf("failed to mark conversation processed: %w", err)
+	}
+	conversation.ProcessedAt = &processedAt
+	conversation.Version++
+	return result, nil
+}
+
+// transcript 格式化最近的对话消息，系统消息不参与提炼
+func (d *MemoryDistiller) transcript(messages []models.Message) string {
+	if len(messages) > d.opts.MaxMessages {
+		messages = messages[len(messages)-d.opts.MaxMessages:]
+	}
+
+	var b strings.Builder
+	for _, m := range messages {
+		if m.Role == "system" || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s] %s: %s\n", m.ID, m.Role, textutil.Truncate(m.Content, d.opts.MaxMessageChars))
+	}
+	return b.String()
+}
+
+// extract 调用大模型抽取记忆，已有记忆作为上下文避免重复
+func (d *MemoryDistiller) extract(ctx context.Context, transcript string, existing []*models.Memory) ([]extractedMemory, error) {
+	var b strings.Builder
+	if len(existing) > 0 {
+		b.WriteString("已有记忆：\n")
+		for _, m := range existing {
+			fmt.Fprintf(&b, "- (%s) %s\n", m.MemoryType, m.Content)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("对话：\n")
+	b.WriteString(transcript)
+
+	output, err := complete(ctx, d.llm, memoryDistillPrompt, b.String())
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Memories []extractedMemory `json:"memories"`
+	}
+	if err := parseJSONResponse(output, &parsed); err != nil {
+		return nil, err
+	}
+
+	valid := parsed.Memories[:0]
+	for _, m := range parsed.Memories {
+		m.Content = strings.TrimSpace(m.Content)
+		if m.Content == "" || !validMemoryType(models.MemoryType(m.Type)) {
+			continue
+		}
+		m.Importance = clamp01(m.Importance)
+		valid = append(valid, m)
+	}
+	return valid, nil
+}
+
+// store 去重后写入记忆和向量，并关联图谱实体
+func (d *MemoryDistiller) store(ctx context.Context, conversation *models.Conversation, extracted []extractedMemory, existing []*models.Memory, result *models.MemoryDistillResult) error {
+	seen := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		seen[normalizeMemoryContent(m.Content)] = true
+	}
+	var fresh []extractedMemory
+	for _, m := range extracted {
+		key := normalizeMemoryContent(m.Content)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+		fresh = append(fresh, m)
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(fresh))
+	for i, m := range fresh {
+		texts[i] = m.Content
+	}
+	vectors, err := d.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed memories: %w", err)
+	}
+	if len(vectors) != len(fresh) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(fresh), len(vectors))
+	}
+	if err := EnsureCollection(ctx, d.vector, MemoryCollection, len(vectors[0])); err != nil {
+		return err
+	}
+
+	// 先写向量再写记录：向量写入失败时不会留下重试时被当作已有记忆的记录
+	now := d.now()
+	var (
+		created []*models.Memory
+		data    []repository.VectorData
+	)
+	for i, m := range fresh {
+		duplicate, err := d.isDuplicate(ctx, conversation, vectors[i])
+		if err != nil {
+			return err
+		}
+		if duplicate {
+			result.Duplicates++
+			continue
+		}
+
+		memory := &models.Memory{
+			MemoryID:         NewID("mem"),
+			UserID:           conversation.UserID,
+			DomainID:         conversation.DomainID,
+			ConversationID:   conversation.ConversationID,
+			MemoryType:       models.MemoryType(m.Type),
+			Content:          m.Content,
+			ImportanceScore:  m.Importance,
+			SourceMessageIDs: m.MessageIDs,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+		memory.EntityIDs = d.linkEntities(ctx, conversation, m)
+		result.LinkedEntities += len(memory.EntityIDs)
+
+		created = append(created, memory)
+		data = append(data, repository.VectorData{
+			ID:       memory.MemoryID,
+			Vector:   vectors[i],
+			Metadata: memoryVectorMetadata(memory),
+		})
+	}
+	if len(created) == 0 {
+		return nil
+	}
+
+	if err := d.vector.Insert(ctx, MemoryCollection, data); err != nil {
+		return fmt.Errorf("failed to insert memory vectors: %w", err)
+	}
+	for _, memory := range created {
+		if err := d.memories.Create(ctx, memory); err != nil {
+			return fmt.Errorf("failed to save memory: %w", err)
+		}
+		result.Created = append(result.Created, memory.MemoryID)
+	}
+	return nil
+}
+
+// isDuplicate 用户在该知识域中是否已有语义相同的记忆
+func (d *MemoryDistiller) isDuplicate(ctx context.Context, conversation *models.Conversation, vector []float32) (bool, error) {
+	hits, err := d.vector.Search(ctx, MemoryCollection, [][]float32{vector}, 1, memoryFilter(conversation.UserID, conversation.DomainID))
+	if err != nil {
+		return false, fmt.Errorf("failed to search memories: %w", err)
+	}
+	return len(hits) > 0 && hits[0].Score >= d.opts.DuplicateThreshold, nil
+}
+
+// linkEntities 将记忆中提到的实体和关系作为一次抽取结果写入图谱，返回实体ID。
+// 同名实体合并，关系按时间版本化，与已有关系冲突时取代旧关系；
+// 不符合知识域图谱模式或抽取置信度低于MinScore的实体和关系被忽略，关联失败不影响记忆写入。
+func (d *MemoryDistiller) linkEntities(ctx context.Context, conversation *models.Conversation, m extractedMemory) []string {
+	if d.writer == nil || d.writer.graph == nil {
+		return nil
+	}
+
+	extraction := &models.EntityExtractionResponse{}
+	for _, ref := range m.Entities {
+		name := strings.TrimSpace(ref.Name)
+		if name == "" {
+			continue
+		}
+		extraction.Entities = append(extraction.Entities, models.KnowledgeEntity{
+			Type:  ref.Type,
+			Name:  name,
+			Score: clamp01(ref.Confidence),
+		})
+	}
+	if len(extraction.Entities) == 0 {
+		return nil
+	}
+	for _, ref := range m.Relations {
+		relation := models.KnowledgeRelation{
+			Type:       ref.Type,
+			FromEntity: strings.TrimSpace(ref.From),
+			ToEntity:   strings.TrimSpace(ref.To),
+			Score:      clamp01(ref.Confidence),
+		}
+		if ref.Negated {
+			relation.Properties = map[string]interface{}{models.RelationPropNegated: true}
+		}
+		extraction.Relations = append(extraction.Relations, relation)
+	}
+
+	result, err := d.writer.ApplyExtraction(ctx, conversation.DomainID, "conversation:"+conversation.ConversationID, extraction)
+	if err != nil {
+		log.Printf("Warning: failed to link entities for conversation %s: %v", conversation.ConversationID, err)
+		return nil
+	}
+	return mergeLabels(nil, result.EntityIDs)
+}
+
+// memoryVectorMetadata 记忆向量的元数据
+func memoryVectorMetadata(m *models.Memory) map[string]interface{} {
+	return map[string]interface{}{
+		"memory_id":        m.MemoryID,
+		"user_id":          m.UserID,
+		"domain_id":        m.DomainID,
+		"memory_type":      string(m.MemoryType),
+		"importance_score": m.ImportanceScore,
+		"created_at":       m.CreatedAt.Unix(),
+	}
+}
+
+// memoryFilter 按用户和知识域过滤记忆向量，domainID为0时不限知识域
+func memoryFilter(userID, domainID uint64) map[string]interface{} {
+	expr := fmt.Sprintf(`metadata["user_id"] == %d`, userID)
+	if domainID != 0 {
+		expr += fmt.Sprintf(` && metadata["domain_id"] == %d`, domainID)
+	}
+	return map[string]interface{}{"expr": expr}
+}
+
+// validMemoryType 是否为支持的记忆类型
+func validMemoryType(t models.MemoryType) bool {
+	switch t {
+	case models.MemoryTypeFact, models.MemoryTypePreference, models.MemoryTypeDecision, models.MemoryTypeCorrection:
+		return true
+	}
+	return false
+}
+
+// normalizeMemoryContent 用于精确去重的规范化内容
+func normalizeMemoryContent(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// clamp01 将分数限制在[0,1]
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// memoryAuditActorUser 用户通过接口发起的操作
+const memoryAuditActorUser = "user"
+
+// MemoryManagerOptions 记忆管理参数
+type MemoryManagerOptions struct {
+	ForgetSimilarity float64 // 与主题的向量相似度达到该值的记忆被遗忘
+	ForgetCandidates int     // 遗忘主题时向量召回的记忆数
+	PageSize         int     // 扫描用户对话的分页大小
+}
+
+// DefaultMemoryManagerOptions 默认记忆管理参数
+func DefaultMemoryManagerOptions() MemoryManagerOptions {
+	return MemoryManagerOptions{
+		ForgetSimilarity: 0.75,
+		ForgetCandidates: 100,
+		PageSize:         100,
+	}
+}
+
+// MemoryManager 用户对自身记忆的查看和控制：列表、检索、编辑、固定、删除和遗忘主题。
+// 所有删除都写入审计日志。
+type MemoryManager struct {
+	users         repository.UserRepository
+	memories      repository.MemoryRepository
+	conversations repository.ConversationRepository
+	audit         repository.MemoryAuditRepository
+	vector        repository.VectorRepository
+	graph         repository.GraphRepository
+	embedder      Embedder
+	searcher      *MemorySearcher
+	opts          MemoryManagerOptions
+}
+
+// NewMemoryManager 创建记忆管理服务，graph为nil时遗忘主题不处理图谱
+func NewMemoryManager(users repository.UserRepository, memories repository.MemoryRepository, conversations repository.ConversationRepository, audit repository.MemoryAuditRepository, vector repository.VectorRepository, graph repository.GraphRepository, embedder Embedder, searcher *MemorySearcher, opts MemoryManagerOptions) *MemoryManager {
+	return &MemoryManager{
+		users:         users,
+		memories:      memories,
+		conversations: conversations,
+		audit:         audit,
+		vector:        vector,
+		graph:         graph,
+		embedder:      embedder,
+		searcher:      searcher,
+		opts:          opts,
+	}
+}
+
+// List 分页获取用户未归档的记忆
+func (m *MemoryManager) List(ctx context.Context, userID, domainID uint64, offset, limit int) (*models.MemoryListResponse, error) {
+	if err := m.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	memories, err := m.memories.ListByUser(ctx, userID, domainID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := m.memories.CountByUser(ctx, userID, domainID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MemoryListResponse{Memories: memories, Total: total, Offset: offset, Limit: limit}, nil
+}
+
+// Search 检索用户的记忆
+func (m *MemoryManager) Search(ctx context.Context, req *models.MemoryQueryRequest) (*models.MemoryQueryResponse, error) {
+	if err := m.ensureUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+	return m.searcher.Search(ctx, req)
+}
+
+// Update 编辑记忆。内容或类型变化时重新向量化，已归档的记忆只更新记录
+func (m *MemoryManager) Update(ctx context.Context, userID uint64, memoryID string, req *models.UpdateMemoryRequest) (*models.Memory, error) {
+	memory, err := m.getOwned(ctx, userID, memoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	reembed := false
+	if req.Content != nil {
+		content := strings.TrimSpace(*req.Content)
+		if content == "" {
+			return nil, fmt.Errorf("%w: content must not be empty", ErrInvalidMemoryRequest)
+		}
+		reembed = reembed || content != memory.Content
+		memory.Content = content
+	}
+	if req.MemoryType != nil {
+		if !validMemoryType(*req.MemoryType) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMemoryType, *req.MemoryType)
+		}
+		reembed = reembed || *req.MemoryType != memory.MemoryType
+		memory.MemoryType = *req.MemoryType
+	}
+	if req.ImportanceScore != nil {
+		memory.ImportanceScore = clamp01(*req.ImportanceScore)
+	}
+
+	if reembed && memory.ArchivedAt == nil {
+		vector, err := embedOne(ctx, m.embedder, memory.Content)
+		if err != nil {
+			return nil, err
+		}
+		data := []repository.VectorData{{ID: memory.MemoryID, Vector: vector, Metadata: memoryVectorMetadata(memory)}}
+		if err := m.vector.Update(ctx, MemoryCollection, data); err != nil {
+			return nil, fmt.Errorf("failed to update memory vector: %w", err)
+		}
+	}
+	if err := m.memories.Update(ctx, memory); err != nil {
+		return nil, fmt.Errorf("failed to update memory: %w", err)
+	}
+	return memory, nil
+}
+
+// SetPinned 固定或取消固定记忆
+func (m *MemoryManager) SetPinned(ctx context.Context, userID uint64, memoryID string, pinned bool) (*models.Memory, error) {
+	memory, err := m.getOwned(ctx, userID, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	if memory.Pinned == pinned {
+		return memory, nil
+	}
+	memory.Pinned = pinned
+	if err := m.memories.Update(ctx, memory); err != nil {
+		return nil, fmt.Errorf("failed to update memory: %w", err)
+	}
+	return memory, nil
+}
+
+// Delete 删除记忆及其向量
+func (m *MemoryManager) Delete(ctx context.Context, userID uint64, memoryID string) error {
+	memory, err := m.getOwned(ctx, userID, memoryID)
+	if err != nil {
+		return err
+	}
+	// 先写审计日志，删除失败时审计中仍有记录，重试会再次记录
+	if err := m.audit.Create(ctx, &models.MemoryAuditLog{
+		UserID:     userID,
+		Action:     models.MemoryAuditDelete,
+		TargetType: models.MemoryAuditTargetMemory,
+		TargetID:   memory.MemoryID,
+		Actor:      memoryAuditActorUser,
+	}); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return m.deleteMemories(ctx, []*models.Memory{memory})
+}
+
+// ForgetTopic 遗忘与主题相关的一切：内容提到主题或与主题语义相近的记忆（含已归档的历史）、
+// 从用户对话中派生的相关图谱实体及其关系，以及这些记忆的来源消息。
+// 每类数据都先写审计日志再删除，删除失败时已删除的部分都有审计记录。
+func (m *MemoryManager) ForgetTopic(ctx context.Context, userID uint64, req *models.ForgetTopicRequest) (*models.ForgetTopicResult, error) {
+	if err := m.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	topic := strings.TrimSpace(req.Topic)
+	if topic == "" {
+		return nil, fmt.Errorf("%w: topic must not be empty", ErrInvalidMemoryRequest)
+	}
+
+	memories, err := m.matchMemories(ctx, userID, req.DomainID, topic)
+	if err != nil {
+		return nil, err
+	}
+	conversations, err := m.userConversations(ctx, userID, req.DomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ForgetTopicResult{Memories: []string{}, Entities: []string{}}
+	detail := map[string]interface{}{"operation": "forget_topic"}
+	var logs []*models.MemoryAuditLog
+	for _, memory := range memories {
+		result.Memories = append(result.Memories, memory.MemoryID)
+		logs = append(logs, m.auditLog(userID, models.MemoryAuditForget, models.MemoryAuditTargetMemory, memory.MemoryID, detail))
+	}
+	if err := m.audit.Create(ctx, logs...); err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := m.deleteMemories(ctx, memories); err != nil {
+		return nil, err
+	}
+
+	if m.graph != nil {
+		if err := m.forgetEntities(ctx, userID, req.DomainID, topic, memories, conversations, detail, result); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.forgetMessages(ctx, userID, memories, conversations, detail, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AuditLog 分页获取用户的记忆审计日志
+func (m *MemoryManager) AuditLog(ctx context.Context, userID uint64, offset, limit int) ([]*models.MemoryAuditLog, int64, error) {
+	if err := m.ensureUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	logs, err := m.audit.ListByUser(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := m.audit.CountByUser(ctx, userID)
+	return logs, total, err
+}
+
+// matchMemories 查找与主题相关的记忆：关键词匹配覆盖已归档的历史，向量匹配覆盖换了说法的记忆
+func (m *MemoryManager) matchMemories(ctx context.Context, userID, domainID uint64, topic string) ([]*models.Memory, error) {
+	matched, err := m.memories.ListByKeyword(ctx, userID, domainID, topic)
+	if err != nil {
+		return nil, err
+	}
+	if m.embedder == nil {
+		return matched, nil
+	}
+
+	vector, err := embedOne(ctx, m.embedder, topic)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := m.vector.Search(ctx, MemoryCollection, [][]float32{vector}, m.opts.ForgetCandidates, memoryFilter(userID, domainID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories: %w", err)
+	}
+	seen := make(map[string]bool, len(matched))
+	for _, memory := range matched {
+		seen[memory.MemoryID] = true
+	}
+	var ids []string
+	for _, hit := range hits {
+		if hit.Score >= m.opts.ForgetSimilarity && !seen[hit.ID] {
+			ids = append(ids, hit.ID)
+		}
+	}
+	similar, err := m.memories.GetByMemoryIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, memory := range similar {
+		if memory.UserID == userID {
+			matched = append(matched, memory)
+		}
+	}
+	return matched, nil
+}
+
+// userConversations 获取用户的全部对话，domainID不为0时只取该知识域的对话
+func (m *MemoryManager) userConversations(ctx context.Context, userID, domainID uint64) (map[string]*models.Conversation, error) {
+	conversations := make(map[string]*models.Conversation)
+	for offset := 0; ; offset += m.opts.PageSize {
+		page, err := m.conversations.ListByUser(ctx, userID, offset, m.opts.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, conversation := range page {
+			if domainID == 0 || conversation.DomainID == domainID {
+				conversations[conversation.ConversationID] = conversation
+			}
+		}
+		if len(page) < m.opts.PageSize {
+			return conversations, nil
+		}
+	}
+}
+
+// forgetEntities 删除从用户对话中派生的相关实体及其关系。
+// 来源不是该用户对话的实体是共享知识，即使被记忆引用也不删除。
+func (m *MemoryManager) forgetEntities(ctx context.Context, userID, domainID uint64, topic string, memories []*models.Memory, conversations map[string]*models.Conversation, detail map[string]interface{}, result *models.ForgetTopicResult) error {
+	candidates := make(map[string]*models.KnowledgeEntity)
+	for _, memory := range memories {
+		for _, id := range memory.EntityIDs {
+			if _, ok := candidates[id]; ok {
+				continue
+			}
+			entity, err := m.graph.GetEntity(ctx, id)
+			if err != nil || entity == nil {
+				continue
+			}
+			candidates[id] = entity
+		}
+	}
+	domains, _ := entityDomains(domainID, nil)
+	found, err := m.graph.SearchEntities(ctx, topic, domains, nil, m.opts.ForgetCandidates)
+	if err != nil {
+		return fmt.Errorf("failed to search entities: %w", err)
+	}
+	for _, entity := range found {
+		candidates[entity.ID] = entity
+	}
+
+	var (
+		entities  []string
+		relations []string
+		logs      []*models.MemoryAuditLog
+	)
+	seenRelations := make(map[string]bool)
+	for id, entity := range candidates {
+		conversationID, derived := strings.CutPrefix(entity.Source, "conversation:")
+		if !derived || conversations[conversationID] == nil {
+			continue
+		}
+		if domainID != 0 && entity.DomainID != domainID {
+			continue
+		}
+		for _, ends := range [][2]string{{id, ""}, {"", id}} {
+			rels, err := m.graph.ListRelations(ctx, ends[0], ends[1], "")
+			if err != nil {
+				return fmt.Errorf("failed to list relations of entity %s: %w", id, err)
+			}
+			for _, relation := range rels {
+				if !seenRelations[relation.ID] {
+					seenRelations[relation.ID] = true
+					relations = append(relations, relation.ID)
+					logs = append(logs, m.auditLog(userID, models.MemoryAuditForget, models.MemoryAuditTargetRelation, relation.ID, detail))
+				}
+			}
+		}
+		entities = append(entities, id)
+		logs = append(logs, m.auditLog(userID, models.MemoryAuditForget, models.MemoryAuditTargetEntity, id, detail))
+	}
+	if err := m.audit.Create(ctx, logs...); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	for _, id := range relations {
+		if err := m.graph.DeleteRelation(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete relation %s: %w", id, err)
+		}
+		result.Relations++
+	}
+	for _, id := range entities {
+		if err := m.graph.DeleteEntity(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete entity %s: %w", id, err)
+		}
+		result.Entities = append(result.Entities, id)
+	}
+	return nil
+}
+
+// forgetMessages 从用户对话中删除被遗忘记忆的来源消息，其他消息即使提到主题也保留。
+// 只更新对话内容并以版本号做乐观锁，与收集和提炼并发时重新读取后重试；
+// 不重置对话的提炼状态，避免删除后又从剩余消息重新提炼。
+func (m *MemoryManager) forgetMessages(ctx context.Context, userID uint64, memories []*models.Memory, conversations map[string]*models.Conversation, detail map[string]interface{}, result *models.ForgetTopicResult) error {
+	sources := make(map[string]map[string]bool)
+	for _, memory := range memories {
+		if conversations[memory.ConversationID] == nil || len(memory.SourceMessageIDs) == 0 {
+			continue
+		}
+		if sources[memory.ConversationID] == nil {
+			sources[memory.ConversationID] = make(map[string]bool)
+		}
+		for _, id := range memory.SourceMessageIDs {
+			sources[memory.ConversationID][id] = true
+		}
+	}
+
+	for conversationID, remove := range sources {
+		conversation := conversations[conversationID]
+		var logs []*models.MemoryAuditLog
+		for _, msg := range conversation.Content.Messages {
+			if remove[msg.ID] {
+				logs = append(logs, m.auditLog(userID, models.MemoryAuditForget, models.MemoryAuditTargetMessage,
+					conversationID+"/"+msg.ID, detail))
+			}
+		}
+		if len(logs) == 0 {
+			continue
+		}
+		if err := m.audit.Create(ctx, logs...); err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		if err := m.removeMessages(ctx, conversation, remove); err != nil {
+			return fmt.Errorf("failed to update conversation %s: %w", conversationID, err)
+		}
+		result.Messages += len(logs)
+	}
+	return nil
+}
+
+// removeMessages 从对话中删除指定消息，版本冲突时重新读取对话后重试
+func (m *MemoryManager) removeMessages(ctx context.Context, conversation *models.Conversation, remove map[string]bool) error {
+	for attempt := 0; ; attempt++ {
+		kept := make([]models.Message, 0, len(conversation.Content.Messages))
+		for _, msg := range conversation.Content.Messages {
+			if !remove[msg.ID] {
+				kept = append(kept, msg)
+			}
+		}
+		if len(kept) == len(conversation.Content.Messages) {
+			return nil
+		}
+		conversation.Content.Messages = kept
+		err := m.conversations.UpdateContent(ctx, conversation)
+		if !errors.Is(err, repository.ErrStaleVersion) || attempt >= collectMaxRetries {
+			return err
+		}
+		if conversation, err = m.conversations.GetByConversationID(ctx, conversation.ConversationID); err != nil {
+			return err
+		}
+	}
+}
+
+// deleteMemories 删除记忆：先删向量再删记录，失败重试时仍能找到记录
+func (m *MemoryManager) deleteMemories(ctx context.Context, memories []*models.Memory) error {
+	if len(memories) == 0 {
+		return nil
+	}
+	ids := make([]string, len(memories))
+	for i, memory := range memories {
+		ids[i] = memory.MemoryID
+	}
+	if err := m.vector.Delete(ctx, MemoryCollection, ids); err != nil {
+		return fmt.Errorf("failed to delete memory vectors: %w", err)
+	}
+	for _, memory := range memories {
+		if err := m.memories.Delete(ctx, memory.ID); err != nil {
+			return fmt.Errorf("failed to delete memory %s: %w", memory.MemoryID, err)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryManager) auditLog(userID uint64, action, targetType, targetID string, detail map[string]interface{}) *models.MemoryAuditLog {
+	return &models.MemoryAuditLog{
+		UserID:     userID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Actor:      memoryAuditActorUser,
+		Detail:     detail,
+	}
+}
+
+// ensureUser 检查用户存在
+func (m *MemoryManager) ensureUser(ctx context.Context, userID uint64) error {
+	if _, err := m.users.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// getOwned 获取属于用户的记忆
+func (m *MemoryManager) getOwned(ctx context.Context, userID uint64, memoryID string) (*models.Memory, error) {
+	if err := m.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	memory, err := m.memories.GetByMemoryID(ctx, memoryID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && memory.UserID != userID) {
+		return nil, ErrMemoryNotFound
+	}
+	return memory, err
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// MemorySearchOptions 记忆检索参数，三个权重之和通常为1
+type MemorySearchOptions struct {
+	RelevanceWeight  float64       // 向量相似度权重
+	RecencyWeight    float64       // 时间衰减权重
+	ImportanceWeight float64       // 重要度权重
+	RecencyHalfLife  time.Duration // 距最近访问（或创建）经过该时长时时间分衰减为0.5
+	CandidateFactor  int           // 向量召回数为返回数的倍数，用于重排
+	DefaultLimit     int
+	MaxLimit         int
+}
+
+// DefaultMemorySearchOptions 默认记忆检索参数
+func DefaultMemorySearchOptions() MemorySearchOptions {
+	return MemorySearchOptions{
+		RelevanceWeight:  0.6,
+		RecencyWeight:    0.2,
+		ImportanceWeight: 0.2,
+		RecencyHalfLife:  30 * 24 * time.Hour,
+		CandidateFactor:  4,
+		DefaultLimit:     10,
+		MaxLimit:         50,
+	}
+}
+
+// MemorySearcher 用户记忆检索：按用户和时间窗口过滤的向量召回，
+// 再按相关度、时间衰减和重要度的加权和排序，命中的记忆记录访问。
+type MemorySearcher struct {
+	memories repository.MemoryRepository
+	vector   repository.VectorRepository
+	embedder Embedder
+	opts     MemorySearchOptions
+	now      func() time.Time
+}
+
+// NewMemorySearcher 创建记忆检索服务
+func NewMemorySearcher(memories repository.MemoryRepository, vector repository.VectorRepository, embedder Embedder, opts MemorySearchOptions) *MemorySearcher {
+	return &MemorySearcher{
+		memories: memories,
+		vector:   vector,
+		embedder: embedder,
+		opts:     opts,
+		now:      time.Now,
+	}
+}
+
+// Search 检索用户记忆
+func (s *MemorySearcher) Search(ctx context.Context, req *models.MemoryQueryRequest) (*models.MemoryQueryResponse, error) {
+	start := s.now()
+	for _, t := range req.MemoryTypes {
+		if !validMemoryType(t) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMemoryType, t)
+		}
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = s.opts.DefaultLimit
+	}
+	if limit > s.opts.MaxLimit {
+		limit = s.opts.MaxLimit
+	}
+
+	vector, err := embedOne(ctx, s.embedder, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.vector.Search(ctx, MemoryCollection, [][]float32{vector}, limit*s.opts.CandidateFactor, memoryQueryFilter(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories: %w", err)
+	}
+
+	relevance := make(map[string]float64, len(hits))
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		relevance[hit.ID] = hit.Score
+		ids = append(ids, hit.ID)
+	}
+	memories, err := s.memories.GetByMemoryIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	results := make([]models.MemoryHit, 0, len(memories))
+	for _, m := range memories {
+		// 向量元数据可能滞后于记录，以MySQL中的记录为准再过滤一次
+		if m.UserID != req.UserID || m.ArchivedAt != nil || !inTimeRange(m.CreatedAt, req.TimeRange) {
+			continue
+		}
+		hit := models.MemoryHit{
+			Memory:    *m,
+			Relevance: relevance[m.MemoryID],
+			Recency:   s.recency(m, now),
+		}
+		hit.Score = s.opts.RelevanceWeight*hit.Relevance +
+			s.opts.RecencyWeight*hit.Recency +
+			s.opts.ImportanceWeight*m.ImportanceScore
+		results = append(results, hit)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	accessed := make([]string, len(results))
+	for i := range results {
+		accessed[i] = results[i].MemoryID
+		results[i].AccessCount++
+		results[i].LastAccessed = &now
+	}
+	if err := s.memories.RecordAccess(ctx, accessed, now); err != nil {
+		log.Printf("Warning: failed to record memory access: %v", err)
+	}
+
+	return &models.MemoryQueryResponse{
+		Memories:  results,
+		Total:     len(results),
+		QueryTime: int(s.now().Sub(start).Milliseconds()),
+	}, nil
+}
+
+// recency 按半衰期计算时间衰减分，从最近一次访问（未访问过时从创建）开始计算
+func (s *MemorySearcher) recency(m *models.Memory, now time.Time) float64 {
+	last := m.CreatedAt
+	if m.LastAccessed != nil && m.LastAccessed.After(last) {
+		last = *m.LastAccessed
+	}
+	age := now.Sub(last)
+	if age <= 0 || s.opts.RecencyHalfLife <= 0 {
+		return 1
+	}
+	return math.Exp(-math.Ln2 * age.Hours() / s.opts.RecencyHalfLife.Hours())
+}
+
+// memoryQueryFilter 构建用户、知识域、时间窗口和记忆类型的向量过滤表达式
+func memoryQueryFilter(req *models.MemoryQueryRequest) map[string]interface{} {
+	params := memoryFilter(req.UserID, req.DomainID)
+	expr := params["expr"].(string)
+	if req.TimeRange != nil {
+		if req.TimeRange.Start != nil {
+			expr += fmt.Sprintf(` && metadata["created_at"] >= %d`, req.TimeRange.Start.Unix())
+		}
+		if req.TimeRange.End != nil {
+			expr += fmt.Sprintf(` && metadata["created_at"] <= %d`, req.TimeRange.End.Unix())
+		}
+	}
+	if len(req.MemoryTypes) > 0 {
+		quoted := make([]string, len(req.MemoryTypes))
+		for i, t := range req.MemoryTypes {
+			quoted[i] = fmt.Sprintf("%q", string(t))
+		}
+		expr += fmt.Sprintf(` && metadata["memory_type"] in [%s]`, strings.Join(quoted, ", "))
+	}
+	params["expr"] = expr
+	return params
+}
+
+// inTimeRange 时间是否在范围内
+func inTimeRange(t time.Time, r *models.TimeRange) bool {
+	if r == nil {
+		return true
+	}
+	if r.Start != nil && t.Before(*r.Start) {
+		return false
+	}
+	if r.End != nil && t.After(*r.End) {
+		return false
+	}
+	return true
+}
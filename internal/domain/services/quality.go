@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// QualityPriorOptions 反馈学习参数
+type QualityPriorOptions struct {
+	Window         time.Duration // 只使用该时间窗口内的反馈
+	HalfLife       time.Duration // 反馈信号的衰减半衰期
+	Shrinkage      float64       // 先验收缩强度：Score = Σ用户贡献 / (用户数 + Shrinkage)，单个用户的影响不超过1/(1+Shrinkage)
+	ShownResults   int           // 没有点击记录时，反馈作用于前N个展示的结果
+	SourceSignal   float64       // 消息级反馈作用于回答每个检索来源的比例
+	ClickSignal    float64       // 点击但未表态时的正向信号
+	DocumentWeight float64       // 分块信号传导到所属文档的比例
+	BoostWeight    float64       // 排序时先验的最大影响：分数乘以 1 + BoostWeight*先验
+	BatchSize      int
+}
+
+// DefaultQualityPriorOptions 默认反馈学习参数
+func DefaultQualityPriorOptions() QualityPriorOptions {
+	return QualityPriorOptions{
+		Window:         180 * 24 * time.Hour,
+		HalfLife:       30 * 24 * time.Hour,
+		Shrinkage:      3,
+		ShownResults:   3,
+		SourceSignal:   1,
+		ClickSignal:    0.2,
+		DocumentWeight: 0.5,
+		BoostWeight:    0.5,
+		BatchSize:      500,
+	}
+}
+
+// QualityPriors 从检索反馈学习分块和文档的质量先验，并在排序时加权或降权。
+// 信号随时间衰减；每个用户对同一目标的贡献被限制在[-1,1]，且先验按独立用户数收缩，
+// 单个用户无法通过反复反馈操纵排序。对话中的消息级反馈作用于被评价回答的检索来源。
+type QualityPriors struct {
+	feedback   repository.FeedbackRepository
+	searchLogs repository.SearchLogRepository
+	chunks     repository.DocumentChunkRepository
+	priors     repository.QualityPriorRepository
+	opts       QualityPriorOptions
+	now        func() time.Time
+
+	mu     sync.RWMutex
+	scores map[string]float64 // 目标类型:目标ID -> 先验分数
+	loaded bool
+}
+
+// NewQualityPriors 创建质量先验服务
+func NewQualityPriors(feedback repository.FeedbackRepository, searchLogs repository.SearchLogRepository, chunks repository.DocumentChunkRepository, priors repository.QualityPriorRepository, opts QualityPriorOptions) *QualityPriors {
+	return &QualityPriors{
+		feedback:   feedback,
+		searchLogs: searchLogs,
+		chunks:     chunks,
+		priors:     priors,
+		opts:       opts,
+		now:        time.Now,
+	}
+}
+
+// qualityKey 先验的键
+func qualityKey(targetType, targetID string) string {
+	return targetType + ":" + targetID
+}
+
+// qualitySignal 单个用户对单个目标的累积信号
+type qualitySignal struct {
+	targetType, targetID string
+	userID               uint64
+	value                float64
+}
+
+// Learn 根据时间窗口内的全部反馈重新计算先验，保存并替换内存中的先验
+func (q *QualityPriors) Learn(ctx context.Context) (*models.QualityLearnResult, error) {
+	now := q.now()
+	result := &models.QualityLearnResult{}
+	signals := make(map[string]*qualitySignal) // 用户ID:目标键 -> 信号
+
+	var afterID uint64
+	for {
+		batch, err := q.feedback.ListSince(ctx, now.Add(-q.opts.Window), afterID, q.opts.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		afterID = batch[len(batch)-1].ID
+		if err := q.collect(ctx, batch, now, signals); err != nil {
+			return nil, err
+		}
+		result.FeedbackProcessed += len(batch)
+		if len(batch) < q.opts.BatchSize {
+			break
+		}
+	}
+
+	priors := q.aggregate(signals, now)
+	if err := q.priors.ReplaceAll(ctx, priors); err != nil {
+		return nil, fmt.Errorf("failed to save quality priors: %w", err)
+	}
+	q.swap(priors)
+
+	result.Priors = len(priors)
+	for _, p := range priors {
+		if p.Score > 0 {
+			result.Boosted++
+		} else if p.Score < 0 {
+			result.Penalized++
+		}
+	}
+	return result, nil
+}
+
+// collect 将一批反馈转换为用户对分块和文档的信号
+func (q *QualityPriors) collect(ctx context.Context, batch []*models.Feedback, now time.Time, signals map[string]*qualitySignal) error {
+	contexts := make([]*models.FeedbackContext, len(batch))
+	queryIDs := make([]string, 0, len(batch))
+	var sources []string
+	for i, f := range batch {
+		fc, err := f.ParseContext()
+		if err != nil {
+			fc = &models.FeedbackContext{}
+		}
+		contexts[i] = fc
+		if fc.MessageID != "" {
+			sources = append(sources, fc.Sources...)
+		} else {
+			queryIDs = append(queryIDs, f.QueryID)
+		}
+	}
+	logs, err := q.searchLogs.GetByQueryIDs(ctx, queryIDs)
+	if err != nil {
+		return err
+	}
+	byQuery := make(map[string]*models.SearchLog, len(logs))
+	for _, l := range logs {
+		byQuery[l.QueryID] = l
+	}
+	resolved, err := q.resolveSources(ctx, sources)
+	if err != nil {
+		return err
+	}
+
+	for i, f := range batch {
+		fc := contexts[i]
+		var targets []feedbackTarget
+		if fc.MessageID != "" {
+			targets = q.sourceTargets(f, fc.Sources, resolved)
+		} else if searchLog := byQuery[f.QueryID]; searchLog != nil {
+			targets = q.targets(f, fc, searchLog.Results.Results)
+		}
+		decay := math.Exp(-math.Ln2 * now.Sub(f.CreatedAt).Hours() / q.opts.HalfLife.Hours())
+		for _, t := range targets {
+			q.addSignal(signals, f.UserID, t.result, t.weight*decay)
+		}
+	}
+	return nil
+}
+
+// resolveSources 将回答的检索来源解析为分块或文档。来源可以带chunk:或document:前缀，
+// 不带前缀时能匹配到分块ID的视为分块，否则视为文档ID。
+func (q *QualityPriors) resolveSources(ctx context.Context, sources []string) (map[string]models.SearchResult, error) {
+	resolved := make(map[string]models.SearchResult, len(sources))
+	var chunkIDs []string
+	for _, source := range sources {
+		if _, ok := resolved[source]; ok {
+			continue
+		}
+		if id, ok := strings.CutPrefix(source, models.QualityTargetDocument+":"); ok {
+			resolved[source] = models.SearchResult{ID: id, Type: models.QualityTargetDocument}
+			continue
+		}
+		if id, ok := strings.CutPrefix(source, models.QualityTargetChunk+":"); ok {
+			resolved[source] = models.SearchResult{ID: id, Type: models.QualityTargetChunk}
+			chunkIDs = append(chunkIDs, id)
+			continue
+		}
+		resolved[source] = models.SearchResult{ID: source, Type: models.QualityTargetDocument}
+		chunkIDs = append(chunkIDs, source)
+	}
+	if len(chunkIDs) == 0 {
+		return resolved, nil
+	}
+
+	chunks, err := q.chunks.GetByChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve feedback sources: %w", err)
+	}
+	byID := make(map[string]*models.DocumentChunk, len(chunks))
+	for _, c := range chunks {
+		byID[c.ChunkID] = c
+	}
+	for source, r := range resolved {
+		if c, ok := byID[r.ID]; ok && !strings.HasPrefix(source, models.QualityTargetDocument+":") {
+			resolved[source] = models.SearchResult{ID: c.ChunkID, Type: models.QualityTargetChunk, Source: c.DocumentID}
+		}
+	}
+	return resolved, nil
+}
+
+// sourceTargets 消息级反馈作用于被评价回答的全部检索来源
+func (q *QualityPriors) sourceTargets(f *models.Feedback, sources []string, resolved map[string]models.SearchResult) []feedbackTarget {
+	signal := feedbackSignal(f) * q.opts.SourceSignal
+	if signal == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(sources))
+	var targets []feedbackTarget
+	for _, source := range sources {
+		r, ok := resolved[source]
+		if !ok || seen[source] {
+			continue
+		}
+		seen[source] = true
+		targets = append(targets, feedbackTarget{result: r, weight: signal})
+	}
+	return targets
+}
+
+// feedbackTarget 反馈作用的结果及信号
+type feedbackTarget struct {
+	result models.SearchResult
+	weight float64
+}
+
+// targets 确定反馈作用的结果：有点击时作用于点击的结果，否则作用于前几个展示的结果，越靠前权重越大。
+// 点击但中性的反馈视为弱正向信号。
+func (q *QualityPriors) targets(f *models.Feedback, fc *models.FeedbackContext, shown []models.SearchResult) []feedbackTarget {
+	signal := feedbackSignal(f)
+	clicked := make(map[string]bool, len(fc.ClickedResults))
+	for _, id := range fc.ClickedResults {
+		clicked[id] = true
+	}
+
+	var targets []feedbackTarget
+	if len(clicked) > 0 {
+		if signal == 0 {
+			signal = q.opts.ClickSignal
+		}
+		for _, r := range shown {
+			if clicked[r.ID] {
+				targets = append(targets, feedbackTarget{result: r, weight: signal})
+			}
+		}
+		return targets
+	}
+
+	if signal == 0 {
+		return nil
+	}
+	n := q.opts.ShownResults
+	if fc.ResultsShown > 0 && fc.ResultsShown < n {
+		n = fc.ResultsShown
+	}
+	for rank, r := range shown {
+		if rank >= n {
+			break
+		}
+		targets = append(targets, feedbackTarget{result: r, weight: signal / float64(rank+1)})
+	}
+	return targets
+}
+
+// feedbackSignal 反馈类型和评分合成的信号，范围[-1,1]
+func feedbackSignal(f *models.Feedback) float64 {
+	var base float64
+	switch f.FeedbackType {
+	case models.FeedbackTypePositive:
+		base = 1
+	case models.FeedbackTypeNegative:
+		base = -1
+	}
+	if f.Rating < 1 || f.Rating > 5 {
+		return base
+	}
+	rating := float64(f.Rating-3) / 2
+	if base == 0 {
+		return rating / 2
+	}
+	return (base + rating) / 2
+}
+
+// addSignal 累加用户对结果的信号，分块的信号按比例传导到所属文档
+func (q *QualityPriors) addSignal(signals map[string]*qualitySignal, userID uint64, r models.SearchResult, value float64) {
+	add := func(targetType, targetID string, v float64) {
+		key := fmt.Sprintf("%d:%s", userID, qualityKey(targetType, targetID))
+		s, ok := signals[key]
+		if !ok {
+			s = &qualitySignal{targetType: targetType, targetID: targetID, userID: userID}
+			signals[key] = s
+		}
+		s.value += v
+	}
+
+	switch r.Type {
+	case models.QualityTargetChunk:
+		add(models.QualityTargetChunk, r.ID, value)
+		if r.Source != "" {
+			add(models.QualityTargetDocument, r.Source, value*q.opts.DocumentWeight)
+		}
+	case models.QualityTargetDocument:
+		add(models.QualityTargetDocument, r.ID, value)
+	}
+}
+
+// aggregate 每个用户的贡献限制在[-1,1]后按独立用户数收缩，得到目标的先验
+func (q *QualityPriors) aggregate(signals map[string]*qualitySignal, now time.Time) []*models.QualityPrior {
+	byTarget := make(map[string]*models.QualityPrior)
+	sums := make(map[string]float64)
+	for _, s := range signals {
+		key := qualityKey(s.targetType, s.targetID)
+		p, ok := byTarget[key]
+		if !ok {
+			p = &models.QualityPrior{TargetType: s.targetType, TargetID: s.targetID, UpdatedAt: now}
+			byTarget[key] = p
+		}
+		v := math.Max(-1, math.Min(1, s.value))
+		if v > 0 {
+			p.Positive += v
+		} else {
+			p.Negative -= v
+		}
+		p.Voters++
+		sums[key] += v
+	}
+
+	priors := make([]*models.QualityPrior, 0, len(byTarget))
+	for key, p := range byTarget {
+		p.Score = sums[key] / (float64(p.Voters) + q.opts.Shrinkage)
+		if p.Score == 0 {
+			continue
+		}
+		priors = append(priors, p)
+	}
+	sort.Slice(priors, func(i, j int) bool {
+		return qualityKey(priors[i].TargetType, priors[i].TargetID) < qualityKey(priors[j].TargetType, priors[j].TargetID)
+	})
+	return priors
+}
+
+// swap 替换内存中的先验
+func (q *QualityPriors) swap(priors []*models.QualityPrior) {
+	scores := make(map[string]float64, len(priors))
+	for _, p := range priors {
+		scores[qualityKey(p.TargetType, p.TargetID)] = p.Score
+	}
+	q.mu.Lock()
+	q.scores = scores
+	q.loaded = true
+	q.mu.Unlock()
+}
+
+// Load 从仓储加载先验
+func (q *QualityPriors) Load(ctx context.Context) error {
+	priors, err := q.priors.List(ctx)
+	if err != nil {
+		return err
+	}
+	q.swap(priors)
+	return nil
+}
+
+// ensureLoaded 首次使用时加载先验，失败时本次不加权
+func (q *QualityPriors) ensureLoaded(ctx context.Context) bool {
+	q.mu.RLock()
+	loaded := q.loaded
+	q.mu.RUnlock()
+	if loaded {
+		return true
+	}
+	if err := q.Load(ctx); err != nil {
+		log.Printf("Warning: failed to load quality priors: %v", err)
+		return false
+	}
+	return true
+}
+
+// Apply 按质量先验调整结果分数并重新排序，调整系数记录在结果元数据quality_prior中
+func (q *QualityPriors) Apply(ctx context.Context, results []models.SearchResult) []models.SearchResult {
+	if q == nil || len(results) == 0 || !q.ensureLoaded(ctx) {
+		return results
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if len(q.scores) == 0 {
+		return results
+	}
+	adjusted := false
+	for i := range results {
+		r := &results[i]
+		prior := q.scores[qualityKey(r.Type, r.ID)]
+		if r.Type == models.QualityTargetChunk && r.Source != "" {
+			prior += q.opts.DocumentWeight * q.scores[qualityKey(models.QualityTargetDocument, r.Source)]
+		}
+		if prior == 0 {
+			continue
+		}
+		prior = math.Max(-1, math.Min(1, prior))
+		r.Score *= 1 + q.opts.BoostWeight*prior
+		if r.Metadata == nil {
+			r.Metadata = make(map[string]interface{})
+		}
+		r.Metadata["quality_prior"] = prior
+		adjusted = true
+	}
+	if adjusted {
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	}
+	return results
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// QueryPlannerOptions 查询规划参数
+type QueryPlannerOptions struct {
+	Budget          time.Duration // 大模型规划的耗时上限，超时使用规则规划
+	HistoryMessages int           // 送入大模型的最近对话消息数
+	MaxMessageChars int           // 单条历史消息截断长度
+	MaxSubQueries   int           // 子查询数上限
+}
+
+// DefaultQueryPlannerOptions 默认查询规划参数
+func DefaultQueryPlannerOptions() QueryPlannerOptions {
+	return QueryPlannerOptions{
+		Budget:          300 * time.Millisecond,
+		HistoryMessages: 6,
+		MaxMessageChars: 500,
+		MaxSubQueries:   4,
+	}
+}
+
+// QueryPlanner 检索前的查询规划：结合上一轮查询和对话历史消解指代、改写查询，
+// 把多部分问题拆分为子查询，生成用于向量检索的假设答案（HyDE），并选择启用的检索器。
+// 默认先做规则规划，只有规则判断为多部分问题或追问时才调用大模型；请求可指定总是或从不调用大模型。
+// 大模型不可用、失败或超时时退回规则规划。
+type QueryPlanner struct {
+	conversations repository.ConversationRepository
+	llm           ChatModel
+	retrievers    []string
+	opts          QueryPlannerOptions
+}
+
+// NewQueryPlanner 创建查询规划服务，retrievers为可供选择的检索器名称
+func NewQueryPlanner(conversations repository.ConversationRepository, llm ChatModel, retrievers []string, opts QueryPlannerOptions) *QueryPlanner {
+	return &QueryPlanner{conversations: conversations, llm: llm, retrievers: retrievers, opts: opts}
+}
+
+const queryPlanPrompt = `你是检索查询规划助手。根据对话历史和上一轮查询，为当前查询制定检索计划：
+1. rewritten：消解代词和省略，改写为不依赖上下文、适合检索的完整查询；不需要改写时原样返回
+2. sub_queries：当前查询包含多个独立问题时拆分出的子查询，否则为空列表
+3. hypothetical_answer：用两三句话写出一段可能回答该查询的文档内容，用于语义检索；不确定时也给出合理的假设
+4. retrievers：从可用检索器中选择需要启用的检索器；vector检索文档分块，graph检索实体和关系，适合询问实体之间关系的问题
+只输出JSON：{"rewritten": "改写后的查询", "sub_queries": [], "hypothetical_answer": "假设答案", "retrievers": ["vector"], "reason": "简要说明"}`
+
+// Plan 为检索请求制定查询计划。规划器为nil时按原始查询检索全部检索器
+func (p *QueryPlanner) Plan(ctx context.Context, req *models.SearchRequest) *models.QueryPlan {
+	if p == nil {
+		return &models.QueryPlan{Original: req.Query, Rewritten: req.Query, Planner: models.QueryPlannerNone}
+	}
+	heuristic := p.heuristicPlan(req)
+	if p.llm == nil || !needsLLMPlan(req, heuristic) {
+		return heuristic
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Budget)
+	defer cancel()
+	plan, err := p.llmPlan(ctx, req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("planning exceeded budget %s", p.opts.Budget)
+		}
+		heuristic.Error = err.Error()
+		return heuristic
+	}
+	return plan
+}
+
+// needsLLMPlan 是否调用大模型规划：检索选项plan为llm时总是调用，为heuristic时不调用；
+// 默认只在规则拆出了子查询，或查询像是依赖上文的追问时调用
+func needsLLMPlan(req *models.SearchRequest, heuristic *models.QueryPlan) bool {
+	switch req.Options.Plan {
+	case models.QueryPlannerLLM:
+		return true
+	case models.QueryPlannerHeuristic:
+		return false
+	}
+	if len(heuristic.SubQueries) > 0 {
+		return true
+	}
+	hasContext := req.Context.PreviousQuery != "" || req.Context.ConversationID != ""
+	return hasContext && isFollowUp(req.Query)
+}
+
+// llmPlan 调用大模型规划
+func (p *QueryPlanner) llmPlan(ctx context.Context, req *models.SearchRequest) (*models.QueryPlan, error) {
+	var b strings.Builder
+	if history := p.history(ctx, req.Context.ConversationID); history != "" {
+		b.WriteString("对话历史：\n")
+		b.WriteString(history)
+		b.WriteString("\n")
+	}
+	if req.Context.PreviousQuery != "" {
+		fmt.Fprintf(&b, "上一轮查询：%s\n", req.Context.PreviousQuery)
+	}
+	fmt.Fprintf(&b, "可用检索器：%s\n当前查询：%s", strings.Join(p.retrievers, ", "), req.Query)
+
+	output, err := complete(ctx, p.llm, queryPlanPrompt, b.String())
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Rewritten          string   `json:"rewritten"`
+		SubQueries         []string `json:"sub_queries"`
+		HypotheticalAnswer string   `json:"hypothetical_answer"`
+		Retrievers         []string `json:"retrievers"`
+		Reason             string   `json:"reason"`
+	}
+	if err := parseJSONResponse(output, &parsed); err != nil {
+		return nil, err
+	}
+
+	plan := &models.QueryPlan{
+		Original:           req.Query,
+		Rewritten:          strings.TrimSpace(parsed.Rewritten),
+		SubQueries:         p.limitSubQueries(parsed.SubQueries),
+		HypotheticalAnswer: strings.TrimSpace(parsed.HypotheticalAnswer),
+		Retrievers:         p.validRetrievers(parsed.Retrievers),
+		Planner:            models.QueryPlannerLLM,
+		Reason:             parsed.Reason,
+	}
+	if plan.Rewritten == "" {
+		plan.Rewritten = req.Query
+	}
+	return plan, nil
+}
+
+// history 格式化对话的最近消息，对话不存在或读取失败时返回空
+func (p *QueryPlanner) history(ctx context.Context, conversationID string) string {
+	if conversationID == "" || p.conversations == nil {
+		return ""
+	}
+	conversation, err := p.conversations.GetByConversationID(ctx, conversationID)
+	if err != nil {
+		return ""
+	}
+	messages := conversation.Content.Messages
+	if len(messages) > p.opts.HistoryMessages {
+		messages = messages[len(messages)-p.opts.HistoryMessages:]
+	}
+	var b strings.Builder
+	for _, m := range messages {
+		if m.Role == "system" || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", m.Role, textutil.Truncate(m.Content, p.opts.MaxMessageChars))
+	}
+	return b.String()
+}
+
+// validRetrievers 过滤掉不可用的检索器，结果为空时表示启用全部检索器
+func (p *QueryPlanner) validRetrievers(names []string) []string {
+	available := make(map[string]bool, len(p.retrievers))
+	for _, name := range p.retrievers {
+		available[name] = true
+	}
+	var valid []string
+	for _, name := range names {
+		if available[name] {
+			valid = append(valid, name)
+		}
+	}
+	return mergeLabels(nil, valid)
+}
+
+// limitSubQueries 去除空白子查询并限制数量
+func (p *QueryPlanner) limitSubQueries(queries []string) []string {
+	var limited []string
+	for _, q := range queries {
+		if q = strings.TrimSpace(q); q != "" && len(limited) < p.opts.MaxSubQueries {
+			limited = append(limited, q)
+		}
+	}
+	return limited
+}
+
+// 提示查询依赖上文的代词和省略表达：英文代词按词匹配，中文和英文短语按子串匹配
+var (
+	anaphoraWords   = []string{"it", "its", "they", "them", "this", "that", "these", "those"}
+	anaphoraPhrases = []string{"它", "这个", "那个", "这些", "那些", "上述", "其中", "还有呢", "what about", "how about"}
+)
+
+// subQuerySeparators 多部分问题的分隔
+var subQuerySeparators = strings.NewReplacer("；", "\n", ";", "\n", "？", "？\n", "?", "?\n", "以及", "\n", "另外", "\n")
+
+// heuristicPlan 规则规划：查询像是追问时拼接上一轮查询，按问号、分号和连接词拆分子查询
+func (p *QueryPlanner) heuristicPlan(req *models.SearchRequest) *models.QueryPlan {
+	plan := &models.QueryPlan{Original: req.Query, Rewritten: req.Query, Planner: models.QueryPlannerHeuristic}
+	prefix := ""
+	if req.Context.PreviousQuery != "" && isFollowUp(req.Query) {
+		prefix = req.Context.PreviousQuery + " "
+		plan.Rewritten = prefix + req.Query
+		plan.Reason = "follow-up query merged with previous query"
+	}
+
+	var parts []string
+	for _, part := range strings.Split(subQuerySeparators.Replace(req.Query), "\n") {
+		if part = strings.TrimSpace(part); utf8.RuneCountInString(part) >= 2 {
+			parts = append(parts, prefix+part)
+		}
+	}
+	if len(parts) > 1 {
+		plan.SubQueries = p.limitSubQueries(parts)
+	}
+	return plan
+}
+
+// isFollowUp 查询是否依赖上文：包含代词或以连接词开头
+func isFollowUp(query string) bool {
+	lower := strings.ToLower(strings.TrimSpace(query))
+	for _, prefix := range []string{"那", "and ", "also "} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	for _, phrase := range anaphoraPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	words := strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+	for _, w := range words {
+		for _, a := range anaphoraWords {
+			if w == a {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,516 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// QuerySuggesterOptions 查询建议参数
+type QuerySuggesterOptions struct {
+	Window              time.Duration // 挖掘的检索日志时间范围
+	MaxLogs             int           // 单次挖掘扫描的日志数上限，超出时只扫描最近的日志
+	SessionGap          time.Duration // 没有会话ID时，同一用户相邻检索间隔超过该值视为新会话
+	CoOccurrenceSpan    int           // 会话中相距不超过该步数的查询视为共现
+	MinSupport          int           // 共现和改写至少出现在多少个会话中才保留
+	ClusterQueries      int           // 参与向量聚类的热门查询数
+	ClusterThreshold    float64       // 向量聚类的相似度阈值
+	MaxPerQuery         int           // 每个查询保留的建议数和相关查询数上限
+	DefaultLimit        int           // 列表和补全的默认返回数
+	MaxLimit            int           // 列表和补全的最大返回数
+	CompletionHistory   time.Duration // 自动补全参考的检索历史时间范围
+	ReformulationWeight float64       // 改写建议的分数为会话数乘以该权重
+}
+
+// DefaultQuerySuggesterOptions 默认查询建议参数
+func DefaultQuerySuggesterOptions() QuerySuggesterOptions {
+	return QuerySuggesterOptions{
+		Window:              30 * 24 * time.Hour,
+		MaxLogs:             50000,
+		SessionGap:          30 * time.Minute,
+		CoOccurrenceSpan:    2,
+		MinSupport:          2,
+		ClusterQueries:      500,
+		ClusterThreshold:    0.85,
+		MaxPerQuery:         5,
+		DefaultLimit:        10,
+		MaxLimit:            100,
+		CompletionHistory:   90 * 24 * time.Hour,
+		ReformulationWeight: 2,
+	}
+}
+
+// QuerySuggester 从检索日志挖掘查询建议和相关查询：同一会话中相继出现的查询互为相关查询，
+// 零结果查询之后改写出的有结果查询作为该查询的建议，向量相近的热门查询互为相关查询。
+// 同时提供人工维护建议和前缀自动补全。
+type QuerySuggester struct {
+	suggestions repository.QuerySuggestionRepository
+	searchLogs  repository.SearchLogRepository
+	embedder    Embedder
+	opts        QuerySuggesterOptions
+	now         func() time.Time
+}
+
+// NewQuerySuggester 创建查询建议服务，embedder为nil时不做向量聚类
+func NewQuerySuggester(suggestions repository.QuerySuggestionRepository, searchLogs repository.SearchLogRepository, embedder Embedder, opts QuerySuggesterOptions) *QuerySuggester {
+	return &QuerySuggester{suggestions: suggestions, searchLogs: searchLogs, embedder: embedder, opts: opts, now: time.Now}
+}
+
+// NormalizeQuery 归一化查询：转小写并合并空白
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Suggest 返回查询的建议和相关查询，读取失败时只记录警告
+func (s *QuerySuggester) Suggest(ctx context.Context, query string, domainID uint64) (suggestions, related []string) {
+	if s == nil {
+		return nil, nil
+	}
+	normalized := NormalizeQuery(query)
+	found, err := s.suggestions.ListActive(ctx, domainID, normalized, 4*s.opts.MaxPerQuery)
+	if err != nil {
+		log.Printf("Warning: failed to load query suggestions: %v", err)
+		return nil, nil
+	}
+	suggestions, related = []string{}, []string{}
+	seen := map[string]bool{normalized: true}
+	for _, sg := range found {
+		if seen[sg.Suggestion] {
+			continue
+		}
+		seen[sg.Suggestion] = true
+		switch {
+		case sg.Related() && len(related) < s.opts.MaxPerQuery:
+			related = append(related, sg.Suggestion)
+		case !sg.Related() && len(suggestions) < s.opts.MaxPerQuery:
+			suggestions = append(suggestions, sg.Suggestion)
+		}
+	}
+	return suggestions, related
+}
+
+// suggestionKey 挖掘出的建议，同一查询、建议和方式只保留一条
+type suggestionKey struct {
+	domainID   uint64
+	query      string
+	suggestion string
+	method     string
+}
+
+// searchEvent 会话中的一次检索
+type searchEvent struct {
+	domainID uint64
+	query    string
+	hits     int
+	at       time.Time
+}
+
+// Mine 挖掘查询建议并替换已有的自动建议，人工建议和已停用的建议不受影响
+func (s *QuerySuggester) Mine(ctx context.Context) (*models.SuggestionMineResult, error) {
+	result := &models.SuggestionMineResult{}
+	logs, err := s.loadLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.LogsScanned = len(logs)
+	sessions := groupSessions(logs, s.opts.SessionGap)
+	result.Sessions = len(sessions)
+
+	support := make(map[suggestionKey]int)
+	for _, session := range sessions {
+		seen := make(map[suggestionKey]bool)
+		count := func(key suggestionKey) {
+			if !seen[key] {
+				seen[key] = true
+				support[key]++
+			}
+		}
+		for i, e := range session {
+			for j := i + 1; j < len(session) && j <= i+s.opts.CoOccurrenceSpan; j++ {
+				// 只推荐有结果的查询
+				other := session[j]
+				if other.query == e.query {
+					continue
+				}
+				if other.hits > 0 {
+					count(suggestionKey{e.domainID, e.query, other.query, models.SuggestionMethodCoOccurrence})
+				}
+				if e.hits > 0 {
+					count(suggestionKey{e.domainID, other.query, e.query, models.SuggestionMethodCoOccurrence})
+				}
+			}
+			if e.hits > 0 {
+				continue
+			}
+			// 零结果查询之后第一个不同的查询有结果时视为改写
+			for _, next := range session[i+1:] {
+				if next.query != e.query {
+					if next.hits > 0 {
+						count(suggestionKey{e.domainID, e.query, next.query, models.SuggestionMethodReformulation})
+					}
+					break
+				}
+			}
+		}
+	}
+
+	var mined []*models.QuerySuggestion
+	for key, n := range support {
+		if n < s.opts.MinSupport {
+			continue
+		}
+		score := float64(n)
+		if key.method == models.SuggestionMethodReformulation {
+			score *= s.opts.ReformulationWeight
+			result.Reformulations++
+		} else {
+			result.CoOccurrences++
+		}
+		mined = append(mined, newAutoSuggestion(key, score, n))
+	}
+
+	clustered, err := s.cluster(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to cluster queries for suggestions: %v", err)
+	}
+	result.Clustered = len(clustered)
+	mined = append(mined, clustered...)
+
+	if err := s.suggestions.ReplaceAuto(ctx, s.limitPerQuery(mined)); err != nil {
+		return nil, fmt.Errorf("failed to save query suggestions: %w", err)
+	}
+	return result, nil
+}
+
+// newAutoSuggestion 创建自动挖掘的建议
+func newAutoSuggestion(key suggestionKey, score float64, support int) *models.QuerySuggestion {
+	return &models.QuerySuggestion{
+		DomainID:   key.domainID,
+		Query:      key.query,
+		Suggestion: key.suggestion,
+		Score:      score,
+		Source:     models.SuggestionSourceAuto,
+		Method:     key.method,
+		Support:    support,
+		Status:     models.SuggestionStatusActive,
+	}
+}
+
+// loadLogs 从最新的开始分批加载时间范围内的检索日志，超过MaxLogs时只保留最近的部分
+func (s *QuerySuggester) loadLogs(ctx context.Context) ([]*models.SearchLog, error) {
+	const batchSize = 1000
+	since := s.now().Add(-s.opts.Window)
+	var (
+		logs     []*models.SearchLog
+		beforeID uint64
+	)
+	for len(logs) < s.opts.MaxLogs {
+		batch, err := s.searchLogs.ListRecent(ctx, since, beforeID, batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load search logs: %w", err)
+		}
+		logs = append(logs, batch...)
+		if len(batch) < batchSize {
+			break
+		}
+		beforeID = batch[len(batch)-1].ID
+	}
+	if len(logs) > s.opts.MaxLogs {
+		logs = logs[:s.opts.MaxLogs]
+	}
+	return logs, nil
+}
+
+// groupSessions 按会话分组检索并按时间排序。检索上下文带会话ID时按会话ID分组，
+// 否则按用户分组并以SessionGap切分；既没有会话ID也没有用户的检索无法归组，被忽略
+func groupSessions(logs []*models.SearchLog, gap time.Duration) [][]searchEvent {
+	grouped := make(map[string][]searchEvent)
+	var keys []string
+	for _, l := range logs {
+		key := ""
+		if sc, ok := l.SearchConfig["context"].(map[string]interface{}); ok {
+			if id, _ := sc["session_id"].(string); id != "" {
+				key = "session:" + id
+			}
+		}
+		if key == "" && l.UserID != 0 {
+			key = fmt.Sprintf("user:%d", l.UserID)
+		}
+		query := NormalizeQuery(l.QueryText)
+		if key == "" || query == "" {
+			continue
+		}
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		grouped[key] = append(grouped[key], searchEvent{domainID: l.DomainID, query: query, hits: l.Results.TotalHits, at: l.CreatedAt})
+	}
+
+	var sessions [][]searchEvent
+	for _, key := range keys {
+		events := grouped[key]
+		sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+		start := 0
+		for i := 1; i <= len(events); i++ {
+			if i == len(events) || events[i].at.Sub(events[i-1].at) > gap {
+				if i-start > 1 {
+					sessions = append(sessions, events[start:i])
+				}
+				start = i
+			}
+		}
+	}
+	return sessions
+}
+
+// cluster 对热门查询做向量聚类，同一簇内的查询互为相关查询，分数为两者的相似度
+func (s *QuerySuggester) cluster(ctx context.Context) ([]*models.QuerySuggestion, error) {
+	if s.embedder == nil || s.opts.ClusterQueries <= 0 {
+		return nil, nil
+	}
+	since := s.now().Add(-s.opts.Window)
+	popular, err := s.searchLogs.PopularQueries(ctx, 0, "", models.TimeRange{Start: &since}, s.opts.ClusterQueries)
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]string, 0, len(popular))
+	for _, q := range popular {
+		if q := NormalizeQuery(q.Query); q != "" {
+			queries = append(queries, q)
+		}
+	}
+	queries = mergeLabels(nil, queries)
+	if len(queries) < 2 {
+		return nil, nil
+	}
+
+	const batchSize = 64
+	vectors := make([][]float32, 0, len(queries))
+	for start := 0; start < len(queries); start += batchSize {
+		end := start + batchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+		batch, err := s.embedder.Embed(ctx, queries[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(batch))
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	// 按热度顺序以首个成员为种子贪心聚类
+	var clusters [][]int
+	for i := range queries {
+		assigned := false
+		for c, members := range clusters {
+			if cosineSimilarity(vectors[i], vectors[members[0]]) >= s.opts.ClusterThreshold {
+				clusters[c] = append(clusters[c], i)
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			clusters = append(clusters, []int{i})
+		}
+	}
+
+	var suggestions []*models.QuerySuggestion
+	for _, members := range clusters {
+		for _, i := range members {
+			for _, j := range members {
+				if i == j {
+					continue
+				}
+				key := suggestionKey{query: queries[i], suggestion: queries[j], method: models.SuggestionMethodCluster}
+				suggestions = append(suggestions, newAutoSuggestion(key, cosineSimilarity(vectors[i], vectors[j]), 1))
+			}
+		}
+	}
+	return suggestions, nil
+}
+
+// limitPerQuery 每个查询的建议和相关查询各保留分数最高的MaxPerQuery条，同一建议只保留分数最高的方式
+func (s *QuerySuggester) limitPerQuery(mined []*models.QuerySuggestion) []*models.QuerySuggestion {
+	sort.SliceStable(mined, func(i, j int) bool { return mined[i].Score > mined[j].Score })
+	type pairKey struct {
+		domainID          uint64
+		query, suggestion string
+	}
+	type groupKey struct {
+		domainID uint64
+		query    string
+		related  bool
+	}
+	seen := make(map[pairKey]bool)
+	counts := make(map[groupKey]int)
+	var limited []*models.QuerySuggestion
+	for _, sg := range mined {
+		pk := pairKey{sg.DomainID, sg.Query, sg.Suggestion}
+		gk := groupKey{sg.DomainID, sg.Query, sg.Related()}
+		if seen[pk] || counts[gk] >= s.opts.MaxPerQuery {
+			continue
+		}
+		seen[pk] = true
+		counts[gk]++
+		limited = append(limited, sg)
+	}
+	return limited
+}
+
+// Autocomplete 前缀自动补全：先返回生效的人工和自动建议，再补充历史上有结果的热门查询
+func (s *QuerySuggester) Autocomplete(ctx context.Context, domainID uint64, prefix string, limit int) ([]models.QueryCompletion, error) {
+	prefix = NormalizeQuery(prefix)
+	limit = s.limit(limit)
+	completions := []models.QueryCompletion{}
+	if prefix == "" {
+		return completions, nil
+	}
+
+	seen := make(map[string]bool)
+	curated, err := s.suggestions.Complete(ctx, domainID, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, sg := range curated {
+		if !seen[sg.Suggestion] {
+			seen[sg.Suggestion] = true
+			completions = append(completions, models.QueryCompletion{Query: sg.Suggestion, Source: sg.Source})
+		}
+	}
+
+	since := s.now().Add(-s.opts.CompletionHistory)
+	popular, err := s.searchLogs.PopularQueries(ctx, domainID, prefix, models.TimeRange{Start: &since}, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range popular {
+		query := NormalizeQuery(q.Query)
+		if len(completions) >= limit {
+			break
+		}
+		if !seen[query] {
+			seen[query] = true
+			completions = append(completions, models.QueryCompletion{Query: query, Count: q.Count, Source: "history"})
+		}
+	}
+	if len(completions) > limit {
+		completions = completions[:limit]
+	}
+	return completions, nil
+}
+
+// limit 规范化返回数
+func (s *QuerySuggester) limit(limit int) int {
+	if limit <= 0 {
+		return s.opts.DefaultLimit
+	}
+	if limit > s.opts.MaxLimit {
+		return s.opts.MaxLimit
+	}
+	return limit
+}
+
+// List 分页获取查询建议
+func (s *QuerySuggester) List(ctx context.Context, filter models.SuggestionFilter) (*models.SuggestionListResponse, error) {
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Limit = s.limit(filter.Limit)
+	filter.Query = NormalizeQuery(filter.Query)
+	suggestions, total, err := s.suggestions.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SuggestionListResponse{Suggestions: suggestions, Total: total, Offset: filter.Offset, Limit: filter.Limit}, nil
+}
+
+// Create 人工添加查询建议
+func (s *QuerySuggester) Create(ctx context.Context, req *models.CreateSuggestionRequest) (*models.QuerySuggestion, error) {
+	status := req.Status
+	if status == "" {
+		status = models.SuggestionStatusActive
+	}
+	if err := validateSuggestionStatus(status); err != nil {
+		return nil, err
+	}
+	suggestion := &models.QuerySuggestion{
+		DomainID:   req.DomainID,
+		Query:      NormalizeQuery(req.Query),
+		Suggestion: strings.TrimSpace(req.Suggestion),
+		Score:      req.Score,
+		Source:     models.SuggestionSourceManual,
+		Status:     status,
+	}
+	if suggestion.Query == "" || suggestion.Suggestion == "" {
+		return nil, fmt.Errorf("%w: query and suggestion must not be blank", ErrInvalidSuggestion)
+	}
+	if err := s.suggestions.Create(ctx, suggestion); err != nil {
+		return nil, err
+	}
+	return suggestion, nil
+}
+
+// Update 编辑查询建议。修改了内容或分数的自动建议转为人工维护，不再被挖掘结果覆盖
+func (s *QuerySuggester) Update(ctx context.Context, id uint64, req *models.UpdateSuggestionRequest) (*models.QuerySuggestion, error) {
+	suggestion, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Suggestion != nil {
+		text := strings.TrimSpace(*req.Suggestion)
+		if text == "" {
+			return nil, fmt.Errorf("%w: suggestion must not be blank", ErrInvalidSuggestion)
+		}
+		suggestion.Suggestion = text
+		suggestion.Source = models.SuggestionSourceManual
+	}
+	if req.Score != nil {
+		suggestion.Score = *req.Score
+		suggestion.Source = models.SuggestionSourceManual
+	}
+	if req.Status != nil {
+		if err := validateSuggestionStatus(*req.Status); err != nil {
+			return nil, err
+		}
+		suggestion.Status = *req.Status
+	}
+	if err := s.suggestions.Update(ctx, suggestion); err != nil {
+		return nil, err
+	}
+	return suggestion, nil
+}
+
+// Delete 删除查询建议。自动建议删除后可能被再次挖掘出来，停用可以避免这种情况
+func (s *QuerySuggester) Delete(ctx context.Context, id uint64) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	return s.suggestions.Delete(ctx, id)
+}
+
+// get 获取查询建议，不存在时返回ErrSuggestionNotFound
+func (s *QuerySuggester) get(ctx context.Context, id uint64) (*models.QuerySuggestion, error) {
+	suggestion, err := s.suggestions.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSuggestionNotFound
+	}
+	return suggestion, err
+}
+
+// validateSuggestionStatus 校验建议状态
+func validateSuggestionStatus(status string) error {
+	if status != models.SuggestionStatusActive && status != models.SuggestionStatusInactive {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidSuggestion, status)
+	}
+	return nil
+}
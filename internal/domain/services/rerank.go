@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// 重排序器类型，对应配置search.rerank.provider
+const (
+	RerankerCrossEncoder = "cross_encoder" // 远程交叉编码器重排序接口
+	RerankerLLM          = "llm"           // 大模型列表式重排序
+	RerankerLexical      = "lexical"       // 词重叠重排序
+)
+
+// Reranker 重排序器，返回每个文档与查询的相关度，顺序与输入一致，分数越大越相关
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// RerankOptions 重排序参数
+type RerankOptions struct {
+	TopN     int           // 参与重排序的融合候选数
+	Budget   time.Duration // 重排序耗时上限，超时保持原顺序
+	MaxChars int           // 单个候选送入重排序器的截断长度
+}
+
+// DefaultRerankOptions 默认重排序参数
+func DefaultRerankOptions() RerankOptions {
+	return RerankOptions{
+		TopN:     50,
+		Budget:   800 * time.Millisecond,
+		MaxChars: 1000,
+	}
+}
+
+// RerankStage 检索的重排序阶段，只对融合后的前TopN个候选重排序
+type RerankStage struct {
+	reranker Reranker
+	opts     RerankOptions
+}
+
+// NewRerankStage 创建重排序阶段
+func NewRerankStage(reranker Reranker, opts RerankOptions) *RerankStage {
+	return &RerankStage{reranker: reranker, opts: opts}
+}
+
+// rerankOutcome 重排序器的返回
+type rerankOutcome struct {
+	scores []float64
+	err    error
+}
+
+// applies 是否会对结果重排序：未配置重排序器或候选不足两个时跳过
+func (s *RerankStage) applies(results []models.SearchResult) bool {
+	return s != nil && s.reranker != nil && len(results) >= 2
+}
+
+// Apply 对前TopN个候选重排序，相关度记录在结果元数据rerank_score中。
+// 重排序失败或超出耗时上限时保持原顺序，返回是否完成了重排序。
+func (s *RerankStage) Apply(ctx context.Context, query string, results []models.SearchResult) ([]models.SearchResult, bool) {
+	if !s.applies(results) {
+		return results, false
+	}
+	n := len(results)
+	if s.opts.TopN > 0 && n > s.opts.TopN {
+		n = s.opts.TopN
+	}
+	documents := make([]string, n)
+	for i, r := range results[:n] {
+		documents[i] = textutil.Truncate(strings.TrimSpace(r.Title+"\n"+r.Content), s.opts.MaxChars)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Budget)
+	defer cancel()
+	// 重排序器不一定响应取消，在独立协程中调用以保证耗时上限
+	done := make(chan rerankOutcome, 1)
+	go func() {
+		scores, err := s.reranker.Rerank(ctx, query, documents)
+		done <- rerankOutcome{scores: scores, err: err}
+	}()
+
+	var outcome rerankOutcome
+	select {
+	case <-ctx.Done():
+		log.Printf("Warning: rerank exceeded budget %s, keeping fused order", s.opts.Budget)
+		return results, false
+	case outcome = <-done:
+	}
+	if outcome.err == nil && len(outcome.scores) != n {
+		outcome.err = fmt.Errorf("expected %d rerank scores, got %d", n, len(outcome.scores))
+	}
+	if outcome.err != nil {
+		log.Printf("Warning: rerank failed, keeping fused order: %v", outcome.err)
+		return results, false
+	}
+
+	head := make([]models.SearchResult, n)
+	copy(head, results[:n])
+	for i := range head {
+		if head[i].Metadata == nil {
+			head[i].Metadata = make(map[string]interface{})
+		}
+		head[i].Metadata["rerank_score"] = outcome.scores[i]
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return outcome.scores[order[a]] > outcome.scores[order[b]] })
+	for i, j := range order {
+		results[i] = head[j]
+	}
+	return results, true
+}
+
+// LexicalReranker 词重叠重排序器：相关度为查询词在文档中出现的比例，
+// 以Jaccard相似度区分覆盖率相同的文档。不依赖外部服务，适合作为兜底。
+type LexicalReranker struct{}
+
+// NewLexicalReranker 创建词重叠重排序器
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+// Rerank 计算查询词覆盖率
+func (r *LexicalReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	terms := textutil.TokenSet(query)
+	scores := make([]float64, len(documents))
+	if len(terms) == 0 {
+		return scores, nil
+	}
+	for i, doc := range documents {
+		tokens := textutil.TokenSet(doc)
+		matched := 0
+		for t := range terms {
+			if tokens[t] {
+				matched++
+			}
+		}
+		scores[i] = float64(matched)/float64(len(terms)) + 0.1*textutil.Jaccard(terms, tokens)
+	}
+	return scores, nil
+}
+
+// LLMReranker 大模型列表式重排序器：一次提交全部候选，由大模型给出按相关度排列的编号
+type LLMReranker struct {
+	llm ChatModel
+}
+
+// NewLLMReranker 创建大模型重排序器
+func NewLLMReranker(llm ChatModel) *LLMReranker {
+	return &LLMReranker{llm: llm}
+}
+
+const llmRerankPrompt = `你是检索结果排序助手。根据与问题的相关程度，对编号的候选段落从最相关到最不相关排序。
+与问题无关的段落可以省略。只输出JSON：{"ranking": [3, 0, 5]}`
+
+// Rerank 按大模型给出的排序计算相关度：第k位（从0开始）的文档为1-k/n，未列出的文档为0
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "问题：%s\n\n", query)
+	for i, doc := range documents {
+		fmt.Fprintf(&b, "[%d] %s\n\n", i, doc)
+	}
+	output, err := complete(ctx, r.llm, llmRerankPrompt, b.String())
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Ranking []int `json:"ranking"`
+	}
+	if err := parseJSONResponse(output, &parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Ranking) == 0 {
+		return nil, errors.New("llm returned empty ranking")
+	}
+
+	n := float64(len(documents))
+	scores := make([]float64, len(documents))
+	seen := make(map[int]bool, len(parsed.Ranking))
+	rank := 0
+	for _, i := range parsed.Ranking {
+		if i < 0 || i >= len(documents) || seen[i] {
+			continue
+		}
+		seen[i] = true
+		scores[i] = 1 - float64(rank)/n
+		rank++
+	}
+	return scores, nil
+}
@@ -1 +1,296 @@
 package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// DocumentChunkCollection 文档分块向量集合，向量ID为chunk_id
+const DocumentChunkCollection = "document_chunks"
+
+// RetrievalFilter 从分面过滤下推到检索器的条件。同一字段的多个取值之间为或，字段之间为且，
+// 空字段不限制；检索器无法判断的条件由分面服务在融合后再过滤
+type RetrievalFilter struct {
+	DomainIDs    []uint64
+	DocumentIDs  []string
+	Tags         []string
+	ContentTypes []string
+	SourceTypes  []string
+	Created      []models.TimeRange // 创建时间区间，区间之间为或，左闭右开
+}
+
+// documentScoped 是否有只有文档分块能满足的条件
+func (f *RetrievalFilter) documentScoped() bool {
+	return f != nil && (len(f.DocumentIDs) > 0 || len(f.Tags) > 0 || len(f.ContentTypes) > 0)
+}
+
+// allowsType 是否接受该类型的结果
+func (f *RetrievalFilter) allowsType(t string) bool {
+	if f == nil || len(f.SourceTypes) == 0 {
+		return true
+	}
+	for _, st := range f.SourceTypes {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsDomain 是否接受该知识域的结果
+func (f *RetrievalFilter) allowsDomain(domainID uint64) bool {
+	if f == nil || len(f.DomainIDs) == 0 {
+		return true
+	}
+	for _, id := range f.DomainIDs {
+		if id == domainID {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsCreated 创建时间是否落在任一区间内
+func (f *RetrievalFilter) allowsCreated(t time.Time) bool {
+	if f == nil || len(f.Created) == 0 {
+		return true
+	}
+	for _, r := range f.Created {
+		if (r.Start == nil || !t.Before(*r.Start)) && (r.End == nil || t.Before(*r.End)) {
+			return true
+		}
+	}
+	return false
+}
+
+// entityDomains 实体检索的知识域范围，domainID为0时取过滤条件中的知识域；
+// 两者都为空时返回nil表示不限知识域，domainID不在过滤条件中时ok为false
+func entityDomains(domainID uint64, f *RetrievalFilter) (domains []uint64, ok bool) {
+	if domainID == 0 {
+		if f == nil {
+			return nil, true
+		}
+		return f.DomainIDs, true
+	}
+	if !f.allowsDomain(domainID) {
+		return nil, false
+	}
+	return []uint64{domainID}, true
+}
+
+// chunkFilter 文档分块向量的过滤表达式，基于索引时写入的分块元数据
+func chunkFilter(domainID uint64, f *RetrievalFilter) map[string]interface{} {
+	var parts []string
+	if domainID != 0 {
+		parts = append(parts, fmt.Sprintf(`metadata["domain_id"] == %d`, domainID))
+	}
+	if f != nil {
+		if len(f.DomainIDs) > 0 {
+			ids := make([]string, len(f.DomainIDs))
+			for i, id := range f.DomainIDs {
+				ids[i] = strconv.FormatUint(id, 10)
+			}
+			parts = append(parts, fmt.Sprintf(`metadata["domain_id"] in [%s]`, strings.Join(ids, ", ")))
+		}
+		if len(f.DocumentIDs) > 0 {
+			parts = append(parts, fmt.Sprintf(`metadata["document_id"] in [%s]`, quoteAll(f.DocumentIDs)))
+		}
+		if len(f.ContentTypes) > 0 {
+			parts = append(parts, fmt.Sprintf(`metadata["content_type"] in [%s]`, quoteAll(f.ContentTypes)))
+		}
+		if len(f.Tags) > 0 {
+			parts = append(parts, fmt.Sprintf(`json_contains_any(metadata["tags"], [%s])`, quoteAll(f.Tags)))
+		}
+		if len(f.Created) > 0 {
+			ranges := make([]string, 0, len(f.Created))
+			for _, r := range f.Created {
+				var bounds []string
+				if r.Start != nil {
+					bounds = append(bounds, fmt.Sprintf(`metadata["created_at"] >= %d`, r.Start.Unix()))
+				}
+				if r.End != nil {
+					bounds = append(bounds, fmt.Sprintf(`metadata["created_at"] < %d`, r.End.Unix()))
+				}
+				if len(bounds) == 0 {
+					bounds = []string{"true"}
+				}
+				ranges = append(ranges, "("+strings.Join(bounds, " && ")+")")
+			}
+			parts = append(parts, "("+strings.Join(ranges, " || ")+")")
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"expr": strings.Join(parts, " && ")}
+}
+
+// quoteAll 把字符串列表转换为过滤表达式中的字符串字面量列表
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// VectorRetriever 向量检索器，检索文档分块
+type VectorRetriever struct {
+	vector   repository.VectorRepository
+	chunks   repository.DocumentChunkRepository
+	embedder Embedder
+}
+
+// NewVectorRetriever 创建向量检索器
+func NewVectorRetriever(vector repository.VectorRepository, chunks repository.DocumentChunkRepository, embedder Embedder) *VectorRetriever {
+	return &VectorRetriever{vector: vector, chunks: chunks, embedder: embedder}
+}
+
+// Retrieve 检索与查询语义相近的文档分块，分面过滤转换为向量库的元数据过滤表达式
+func (r *VectorRetriever) Retrieve(ctx context.Context, query string, domainID uint64, limit int, filter *RetrievalFilter) ([]models.SearchResult, error) {
+	if !filter.allowsType("chunk") {
+		return nil, nil
+	}
+	vector, err := embedQuery(ctx, r.embedder, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	hits, err := r.vector.Search(ctx, DocumentChunkCollection, [][]float32{vector}, limit, chunkFilter(domainID, filter))
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	chunks, err := r.chunks.GetByChunkIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunks: %w", err)
+	}
+	byID := make(map[string]*models.DocumentChunk, len(chunks))
+	for _, c := range chunks {
+		byID[c.ChunkID] = c
+	}
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		chunk, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, chunkToResult(chunk, hit.Score))
+	}
+	return results, nil
+}
+
+// chunkToResult 将文档分块转换为搜索结果
+func chunkToResult(chunk *models.DocumentChunk, score float64) models.SearchResult {
+	title, _ := chunk.Metadata["title"].(string)
+	return models.SearchResult{
+		ID:      chunk.ChunkID,
+		Type:    "chunk",
+		Title:   title,
+		Content: chunk.Content,
+		Source:  chunk.DocumentID,
+		Score:   score,
+		Metadata: map[string]interface{}{
+			"document_id": chunk.DocumentID,
+			"start_pos":   chunk.StartPos,
+			"end_pos":     chunk.EndPos,
+		},
+		CreatedAt: chunk.CreatedAt,
+	}
+}
+
+// GraphRetriever 图检索器，按实体匹配度检索并对中心实体加权
+type GraphRetriever struct {
+	graph           repository.GraphRepository
+	centralityBoost float64 // 中心度加权系数，分数乘以 (1 + boost * centrality)
+}
+
+// NewGraphRetriever 创建图检索器
+func NewGraphRetriever(graph repository.GraphRepository, centralityBoost float64) *GraphRetriever {
+	return &GraphRetriever{graph: graph, centralityBoost: centralityBoost}
+}
+
+// Retrieve 检索与查询相关的实体。实体不属于文档，有文档、标签或内容类型过滤时不返回结果
+func (r *GraphRetriever) Retrieve(ctx context.Context, query string, domainID uint64, limit int, filter *RetrievalFilter) ([]models.SearchResult, error) {
+	if r.graph == nil {
+		return nil, ErrGraphUnavailable
+	}
+	if !filter.allowsType("entity") || filter.documentScoped() {
+		return nil, nil
+	}
+
+	domains, ok := entityDomains(domainID, filter)
+	if !ok {
+		return nil, nil
+	}
+
+	// 多取一些候选，中心度加权后再截断
+	entities, err := r.graph.SearchEntities(ctx, query, domains, nil, limit*2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search entities: %w", err)
+	}
+
+	results := make([]models.SearchResult, 0, len(entities))
+	for _, e := range entities {
+		if !filter.allowsCreated(e.CreatedAt) {
+			continue
+		}
+		results = append(results, entityToResult(e, r.score(e)))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// score 实体得分：置信度乘以中心度加权
+func (r *GraphRetriever) score(e *models.KnowledgeEntity) float64 {
+	base := e.Score
+	if base <= 0 {
+		base = 1
+	}
+	return base * (1 + r.centralityBoost*e.Centrality())
+}
+
+// entityToResult 将实体转换为搜索结果
+func entityToResult(e *models.KnowledgeEntity, score float64) models.SearchResult {
+	content := e.Name
+	if desc, ok := e.Properties["description"].(string); ok && desc != "" {
+		content = e.Name + ": " + desc
+	}
+
+	return models.SearchResult{
+		ID:      e.ID,
+		Type:    "entity",
+		Title:   e.Name,
+		Content: content,
+		Source:  e.Source,
+		Score:   score,
+		Metadata: map[string]interface{}{
+			"entity_type": e.Type,
+			"labels":      strings.Join(e.Labels, ","),
+			"centrality":  e.Centrality(),
+			"domain_id":   e.DomainID,
+		},
+		CreatedAt: e.CreatedAt,
+	}
+}
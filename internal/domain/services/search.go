@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+	rrfK               = 60 // RRF融合常数
+)
+
+// Retriever 单路检索器，filter为nil时不做分面过滤
+type Retriever interface {
+	Retrieve(ctx context.Context, query string, domainID uint64, limit int, filter *RetrievalFilter) ([]models.SearchResult, error)
+}
+
+// Searcher 检索服务，负责结果缓存、查询规划、多路检索、结果融合、反馈先验加权、重排序、学习到的纠正、分面、高亮、查询建议和全局检索
+type Searcher struct {
+	retrievers  map[string]Retriever
+	planner     *QueryPlanner
+	global      *GlobalSearcher
+	priors      *QualityPriors
+	rerank      *RerankStage
+	corrections *CorrectionLearner
+	highlighter *Highlighter
+	facets      *Faceter
+	suggester   *QuerySuggester
+	cache       *SearchCache
+}
+
+// NewSearcher 创建检索服务，retrievers的键为检索器名称。planner为nil时按原始查询检索，
+// priors为nil时不按反馈调整排序，rerank为nil时忽略重排序选项，corrections为nil时不返回学习到的纠正，
+// highlighter为nil时忽略高亮选项，facets为nil时不计算分面，suggester为nil时不返回查询建议和相关查询，cache为nil时不缓存结果
+func NewSearcher(retrievers map[string]Retriever, planner *QueryPlanner, global *GlobalSearcher, priors *QualityPriors, rerank *RerankStage, corrections *CorrectionLearner, highlighter *Highlighter, facets *Faceter, suggester *QuerySuggester, cache *SearchCache) *Searcher {
+	return &Searcher{retrievers: retrievers, planner: planner, global: global, priors: priors, rerank: rerank, corrections: corrections, highlighter: highlighter, facets: facets, suggester: suggester, cache: cache}
+}
+
+// Search 执行检索，命中缓存时直接返回缓存的结果，元数据cache记录是否命中
+func (s *Searcher) Search(ctx context.Context, req *models.SearchRequest) (*models.SearchResponse, error) {
+	start := time.Now()
+	cached, entry := s.cache.Lookup(ctx, req)
+	if cached != nil {
+		cached.QueryID = NewID("query")
+		cached.Query = req.Query
+		if cached.Metadata["cache"] == SearchCacheSemantic {
+			s.refresh(ctx, req, cached)
+		}
+		cached.ProcessingMS = int(time.Since(start).Milliseconds())
+		return cached, nil
+	}
+
+	resp, err := s.search(entry.WithVector(ctx), req)
+	if err != nil {
+		return nil, err
+	}
+	// 有检索器或重排序降级的结果不完整，不缓存，下次请求重新检索
+	if _, degraded := resp.Metadata["degraded"]; !degraded {
+		s.cache.Store(ctx, entry, resp)
+	}
+	resp.ProcessingMS = int(time.Since(start).Milliseconds())
+	return resp, nil
+}
+
+// refresh 语义命中的结果来自相近的另一个查询，按本次查询重新计算高亮、查询建议和相关查询。
+// 查询计划属于被复用的查询，移到元数据cached_plan中，响应不带本次查询的计划
+func (s *Searcher) refresh(ctx context.Context, req *models.SearchRequest, resp *models.SearchResponse) {
+	if resp.Plan != nil {
+		resp.Metadata["cached_plan"] = resp.Plan
+		resp.Plan = nil
+	}
+	if normalizeSearchOptions(req.Options).Highlight {
+		for i := range resp.Results {
+			resp.Results[i].Highlights = nil
+			delete(resp.Results[i].Metadata, "highlights")
+		}
+		s.highlighter.Apply(ctx, req.Query, resp.Results)
+	}
+	resp.Suggestions, resp.RelatedQueries = s.suggester.Suggest(ctx, req.Query, req.DomainID)
+}
+
+// search 执行检索，不经过缓存
+func (s *Searcher) search(ctx context.Context, req *models.SearchRequest) (*models.SearchResponse, error) {
+	opts := normalizeSearchOptions(req.Options)
+
+	resp := &models.SearchResponse{
+		QueryID:  NewID("query"),
+		Query:    req.Query,
+		Metadata: map[string]interface{}{"mode": opts.Mode},
+	}
+
+	resp.Plan = s.planner.Plan(ctx, req)
+	query := resp.Plan.Rewritten
+
+	var (
+		results  []models.SearchResult
+		degraded []string
+	)
+	switch opts.Mode {
+	case models.SearchModeGlobal:
+		answer, err := s.global.Search(ctx, query, req.DomainID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Metadata["answer"] = answer.Answer
+		results = aboveThreshold(answer.Results, opts.ScoreThreshold)
+	default:
+		filter, window, err := s.facets.Pushdown(req.Filters)
+		if err != nil {
+			return nil, err
+		}
+		results, degraded = s.searchLocal(ctx, resp.Plan, req.DomainID, opts, filter, window)
+	}
+
+	results = s.priors.Apply(ctx, results)
+	if opts.Rerank {
+		attempted := s.rerank.applies(results)
+		var reranked bool
+		results, reranked = s.rerank.Apply(ctx, query, results)
+		resp.Metadata["reranked"] = reranked
+		if attempted && !reranked {
+			degraded = append(degraded, "rerank")
+		}
+	}
+	if len(degraded) > 0 {
+		resp.Metadata["degraded"] = degraded
+	}
+	results = s.corrections.Surface(ctx, query, req.DomainID, results)
+	results = filterResults(results, opts)
+	results, aggregations, err := s.facets.Apply(ctx, req.Filters, results)
+	if err != nil {
+		return nil, err
+	}
+	resp.Aggregations = aggregations
+	resp.TotalHits = len(results)
+	resp.Results = paginate(results, opts.Offset, opts.Limit)
+	if opts.Highlight {
+		s.highlighter.Apply(ctx, query, resp.Results)
+	}
+	resp.Suggestions, resp.RelatedQueries = s.suggester.Suggest(ctx, req.Query, req.DomainID)
+	return resp, nil
+}
+
+// retrievalTask 一路检索：检索器和检索文本，name为融合时的来源名称
+type retrievalTask struct {
+	name      string
+	retriever Retriever
+	text      string
+}
+
+// retrievalTasks 按查询计划展开检索：计划启用的每个检索器检索改写后的查询和各子查询，
+// 有假设答案时向量检索器额外以假设答案检索
+func (s *Searcher) retrievalTasks(plan *models.QueryPlan) []retrievalTask {
+	enabled := s.retrievers
+	if len(plan.Retrievers) > 0 {
+		enabled = make(map[string]Retriever, len(plan.Retrievers))
+		for _, name := range plan.Retrievers {
+			if r, ok := s.retrievers[name]; ok {
+				enabled[name] = r
+			}
+		}
+	}
+
+	queries := plan.Queries()
+	var tasks []retrievalTask
+	for name, retriever := range enabled {
+		for i, q := range queries {
+			taskName := name
+			if len(queries) > 1 {
+				taskName = fmt.Sprintf("%s:q%d", name, i)
+			}
+			tasks = append(tasks, retrievalTask{name: taskName, retriever: retriever, text: q})
+		}
+	}
+	if vector, ok := enabled["vector"]; ok && plan.HypotheticalAnswer != "" {
+		tasks = append(tasks, retrievalTask{name: "vector:hyde", retriever: vector, text: plan.HypotheticalAnswer})
+	}
+	return tasks
+}
+
+// searchLocal 按查询计划并行执行多路检索并以RRF融合，单路失败时降级，返回融合结果和失败的检索路。
+// 分面过滤下推到各检索器，每路至少取window个候选，使分面统计不局限于当前页
+func (s *Searcher) searchLocal(ctx context.Context, plan *models.QueryPlan, domainID uint64, opts models.SearchOptions, filter *RetrievalFilter, window int) ([]models.SearchResult, []string) {
+	candidates := opts.Offset + opts.Limit
+	if opts.Rerank && s.rerank != nil && s.rerank.opts.TopN > candidates {
+		candidates = s.rerank.opts.TopN
+	}
+	if window > candidates {
+		candidates = window
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		lists  = make(map[string][]models.SearchResult)
+		failed []string
+	)
+	for _, task := range s.retrievalTasks(plan) {
+		wg.Add(1)
+		go func(task retrievalTask) {
+			defer wg.Done()
+			results, err := task.retriever.Retrieve(ctx, task.text, domainID, candidates, filter)
+			if err != nil {
+				log.Printf("Warning: %s retriever failed: %v", task.name, err)
+				mu.Lock()
+				failed = append(failed, task.name)
+				mu.Unlock()
+				return
+			}
+			mu.Lock()
+			lists[task.name] = aboveThreshold(results, opts.ScoreThreshold)
+			mu.Unlock()
+		}(task)
+	}
+	wg.Wait()
+
+	sort.Strings(failed)
+	return fuseResults(lists), failed
+}
+
+// fuseResults 以Reciprocal Rank Fusion融合多路结果，原始分数和来源记录在元数据中
+func fuseResults(lists map[string][]models.SearchResult) []models.SearchResult {
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	index := make(map[string]int)
+	var fused []models.SearchResult
+	for _, name := range names {
+		for rank, r := range lists[name] {
+			key := r.Type + ":" + r.ID
+			i, ok := index[key]
+			if !ok {
+				i = len(fused)
+				index[key] = i
+				if r.Metadata == nil {
+					r.Metadata = make(map[string]interface{})
+				}
+				r.Metadata["retrievers"] = []string{}
+				r.Metadata["raw_score"] = r.Score
+				r.Score = 0
+				fused = append(fused, r)
+			}
+			fused[i].Score += 1.0 / float64(rrfK+rank+1)
+			fused[i].Metadata["retrievers"] = append(fused[i].Metadata["retrievers"].([]string), name)
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	return fused
+}
+
+// normalizeSearchOptions 补全检索选项默认值
+func normalizeSearchOptions(opts models.SearchOptions) models.SearchOptions {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSearchLimit
+	}
+	if opts.Limit > maxSearchLimit {
+		opts.Limit = maxSearchLimit
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+	if opts.Mode == "" {
+		opts.Mode = models.SearchModeLocal
+	}
+	if opts.Plan != models.QueryPlannerLLM && opts.Plan != models.QueryPlannerHeuristic {
+		opts.Plan = models.QueryPlannerAuto
+	}
+	return opts
+}
+
+// aboveThreshold 按检索器原始分数过滤
+func aboveThreshold(results []models.SearchResult, threshold float64) []models.SearchResult {
+	if threshold <= 0 {
+		return results
+	}
+	filtered := results[:0]
+	for _, r := range results {
+		if r.Score >= threshold {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// filterResults 按结果类型过滤
+func filterResults(results []models.SearchResult, opts models.SearchOptions) []models.SearchResult {
+	if len(opts.ResultTypes) == 0 {
+		return results
+	}
+	types := make(map[string]bool, len(opts.ResultTypes))
+	for _, t := range opts.ResultTypes {
+		types[t] = true
+	}
+
+	filtered := results[:0]
+	for _, r := range results {
+		if types[r.Type] {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// paginate 分页截取
+func paginate(results []models.SearchResult, offset, limit int) []models.SearchResult {
+	if offset >= len(results) {
+		return []models.SearchResult{}
+	}
+	end := offset + limit
+	if end > len(results) {
+		end = len(results)
+	}
+	return results[offset:end]
+}
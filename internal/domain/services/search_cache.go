@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// 检索结果缓存在响应元数据cache中的取值
+const (
+	SearchCacheHit      = "hit"      // 规范化后的查询和参数完全相同
+	SearchCacheSemantic = "semantic" // 参数相同、查询语义相近
+	SearchCacheMiss     = "miss"
+)
+
+// searchCacheAllDomains 不限知识域的检索使用的版本号，任一知识域的文档变更都会递增
+const searchCacheAllDomains = "all"
+
+// SearchCacheOptions 检索结果缓存参数
+type SearchCacheOptions struct {
+	TTL               time.Duration // 结果缓存时长
+	SemanticThreshold float64       // 查询向量相似度达到该值时复用近义查询的结果，0表示不启用语义缓存
+	SemanticEntries   int           // 每个知识域的语义索引保留的最近查询数
+}
+
+// DefaultSearchCacheOptions 默认检索结果缓存参数
+func DefaultSearchCacheOptions() SearchCacheOptions {
+	return SearchCacheOptions{TTL: 30 * time.Minute, SemanticThreshold: 0.95, SemanticEntries: 256}
+}
+
+// SearchCache 检索结果缓存，键由规范化的查询、知识域、过滤条件、检索选项和会话上下文组成。
+// 每个知识域有一个版本号，文档创建、更新和删除后递增，缓存键包含版本号，因此只有该知识域的旧结果失效，
+// 不需要扫描键；失效的结果不再被读取，随TTL过期。
+// 精确匹配未命中时，按查询向量在知识域最近查询的进程内索引中查找参数相同的近义查询，
+// 相似度达到阈值且知识域版本未变时复用其结果，减少重复的智能体请求带来的向量化和向量检索负载
+type SearchCache struct {
+	cache    repository.CacheRepository
+	embedder Embedder
+	opts     SearchCacheOptions
+	semantic *semanticIndex
+}
+
+// NewSearchCache 创建检索结果缓存，embedder为nil时只做精确匹配
+func NewSearchCache(cache repository.CacheRepository, embedder Embedder, opts SearchCacheOptions) *SearchCache {
+	return &SearchCache{cache: cache, embedder: embedder, opts: opts, semantic: newSemanticIndex(opts.SemanticEntries)}
+}
+
+// searchCacheKey 参与缓存键计算的请求内容，会话上下文会影响查询改写
+type searchCacheKey struct {
+	Query          string                 `json:"query"`
+	DomainID       uint64                 `json:"domain_id"`
+	Filters        map[string]interface{} `json:"filters"`
+	Options        models.SearchOptions   `json:"options"`
+	PreviousQuery  string                 `json:"previous_query"`
+	ConversationID string                 `json:"conversation_id"`
+}
+
+// SearchCacheEntry 一次检索在缓存中的位置，未命中时用于写入结果，也是语义索引的条目
+type SearchCacheEntry struct {
+	scope   string
+	version int64
+	query   string    // 规范化的查询
+	params  string    // 查询以外参数的摘要，语义匹配要求参数相同
+	key     string    // 结果的缓存键
+	vector  []float32 // 查询向量，未启用语义缓存或向量化失败时为空
+}
+
+// Lookup 查找请求的缓存结果，先精确匹配再语义匹配。命中时返回的响应元数据cache记录命中方式，
+// 语义命中时还记录被复用的查询cached_query和相似度，响应中的查询计划属于被复用的查询；
+// 版本号读取失败时不使用缓存，两个返回值都为nil
+func (c *SearchCache) Lookup(ctx context.Context, req *models.SearchRequest) (*models.SearchResponse, *SearchCacheEntry) {
+	if c == nil {
+		return nil, nil
+	}
+	entry, err := c.locate(ctx, req)
+	if err != nil {
+		log.Printf("Warning: failed to locate search cache: %v", err)
+		return nil, nil
+	}
+	if resp := c.get(ctx, entry.key); resp != nil {
+		resp.Metadata["cache"] = SearchCacheHit
+		return resp, entry
+	}
+
+	if c.embedder == nil || c.opts.SemanticThreshold <= 0 {
+		return nil, entry
+	}
+	vector, err := embedOne(ctx, c.embedder, entry.query)
+	if err != nil {
+		log.Printf("Warning: failed to embed query for semantic cache: %v", err)
+		return nil, entry
+	}
+	entry.vector = vector
+	match, similarity := c.semantic.nearest(entry, c.opts.SemanticThreshold)
+	if match == nil {
+		return nil, entry
+	}
+	resp := c.get(ctx, match.key)
+	if resp == nil {
+		return nil, entry
+	}
+	resp.Metadata["cache"] = SearchCacheSemantic
+	resp.Metadata["cached_query"] = match.query
+	resp.Metadata["cache_similarity"] = similarity
+	return resp, entry
+}
+
+// WithVector 在上下文中记录Lookup计算的查询向量，检索时向量检索器直接复用
+func (e *SearchCacheEntry) WithVector(ctx context.Context) context.Context {
+	if e == nil || len(e.vector) == 0 {
+		return ctx
+	}
+	return withQueryEmbedding(ctx, e.query, e.vector)
+}
+
+// Store 缓存未命中的检索结果，并把查询加入语义索引，失败时只记录警告
+func (c *SearchCache) Store(ctx context.Context, entry *SearchCacheEntry, resp *models.SearchResponse) {
+	if c == nil || entry == nil {
+		return
+	}
+	resp.Metadata["cache"] = SearchCacheMiss
+	if err := c.cache.Set(ctx, entry.key, resp, int(c.opts.TTL.Seconds())); err != nil {
+		log.Printf("Warning: failed to write search cache: %v", err)
+		return
+	}
+	if len(entry.vector) > 0 {
+		c.semantic.add(*entry)
+	}
+}
+
+// locate 计算请求的缓存键，包含知识域当前的版本号
+func (c *SearchCache) locate(ctx context.Context, req *models.SearchRequest) (*SearchCacheEntry, error) {
+	scope := searchCacheScope(req.DomainID)
+	version, err := c.version(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	key := searchCacheKey{
+		DomainID:       req.DomainID,
+		Filters:        req.Filters,
+		Options:        normalizeSearchOptions(req.Options),
+		PreviousQuery:  NormalizeQuery(req.Context.PreviousQuery),
+		ConversationID: req.Context.ConversationID,
+	}
+	params, err := digest(key)
+	if err != nil {
+		return nil, err
+	}
+	key.Query = NormalizeQuery(req.Query)
+	full, err := digest(key)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchCacheEntry{
+		scope:   scope,
+		version: version,
+		query:   key.Query,
+		params:  params,
+		key:     fmt.Sprintf("search:result:%s:v%d:%s", scope, version, full),
+	}, nil
+}
+
+// digest JSON序列化后的SHA-256摘要，映射按键排序，结果稳定
+func digest(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// get 读取缓存的响应，未命中或读取失败时返回nil
+func (c *SearchCache) get(ctx context.Context, key string) *models.SearchResponse {
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Warning: failed to read search cache: %v", err)
+		}
+		return nil
+	}
+	var resp models.SearchResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		log.Printf("Warning: failed to decode cached search response: %v", err)
+		return nil
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]interface{})
+	}
+	return &resp
+}
+
+// InvalidateDomain 使知识域的缓存结果失效，同时使不限知识域的缓存结果失效
+func (c *SearchCache) InvalidateDomain(ctx context.Context, domainID uint64) {
+	if c == nil {
+		return
+	}
+	scopes := []string{searchCacheAllDomains}
+	if domainID != 0 {
+		scopes = append(scopes, searchCacheScope(domainID))
+	}
+	for _, scope := range scopes {
+		if _, err := c.cache.Incr(repository.WithRemoteOnly(ctx), searchCacheVersionKey(scope)); err != nil {
+			log.Printf("Warning: failed to invalidate search cache for %s: %v", scope, err)
+		}
+	}
+}
+
+// version 知识域当前的版本号，从未变更过时为0。版本号只读远端，
+// 两级缓存的本地副本会让其他实例在失效后继续读到旧版本
+func (c *SearchCache) version(ctx context.Context, scope string) (int64, error) {
+	value, err := c.cache.Get(repository.WithRemoteOnly(ctx), searchCacheVersionKey(scope))
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func searchCacheScope(domainID uint64) string {
+	if domainID == 0 {
+		return searchCacheAllDomains
+	}
+	return strconv.FormatUint(domainID, 10)
+}
+
+func searchCacheVersionKey(scope string) string {
+	return "search:version:" + scope
+}
+
+// semanticIndex 进程内的语义索引，按知识域保存最近查询的向量。条目记录写入时的知识域版本号，
+// 版本变化后旧条目不再匹配，并在下次写入时清除
+type semanticIndex struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string][]SearchCacheEntry // 按知识域，最近写入的在末尾
+}
+
+func newSemanticIndex(capacity int) *semanticIndex {
+	return &semanticIndex{capacity: capacity, entries: make(map[string][]SearchCacheEntry)}
+}
+
+// add 加入查询，同一查询只保留最新的条目，超出容量时淘汰最早的条目
+func (idx *semanticIndex) add(entry SearchCacheEntry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	kept := idx.entries[entry.scope][:0]
+	for _, e := range idx.entries[entry.scope] {
+		if e.version == entry.version && e.key != entry.key {
+			kept = append(kept, e)
+		}
+	}
+	kept = append(kept, entry)
+	if idx.capacity > 0 && len(kept) > idx.capacity {
+		kept = kept[len(kept)-idx.capacity:]
+	}
+	idx.entries[entry.scope] = kept
+}
+
+// nearest 查找同一知识域版本、参数相同且相似度达到阈值的最相似查询
+func (idx *semanticIndex) nearest(entry *SearchCacheEntry, threshold float64) (*SearchCacheEntry, float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var (
+		best      *SearchCacheEntry
+		bestScore float64
+	)
+	for i := range idx.entries[entry.scope] {
+		e := &idx.entries[entry.scope][i]
+		if e.version != entry.version || e.params != entry.params {
+			continue
+		}
+		if score := cosineSimilarity(entry.vector, e.vector); score >= threshold && score > bestScore {
+			match := *e
+			best, bestScore = &match, score
+		}
+	}
+	return best, bestScore
+}
+
+// WrapDocuments 包装文档仓储，文档创建、更新和删除成功后使所属知识域的缓存结果失效，
+// 更新时知识域发生变化则新旧知识域都失效
+func (c *SearchCache) WrapDocuments(documents repository.DocumentRepository) repository.DocumentRepository {
+	if c == nil || documents == nil {
+		return documents
+	}
+	return &invalidatingDocumentRepository{DocumentRepository: documents, cache: c}
+}
+
+// invalidatingDocumentRepository 写操作后使检索结果缓存失效的文档仓储
+type invalidatingDocumentRepository struct {
+	repository.DocumentRepository
+	cache *SearchCache
+}
+
+func (r *invalidatingDocumentRepository) Create(ctx context.Context, document *models.Document) error {
+	if err := r.DocumentRepository.Create(ctx, document); err != nil {
+		return err
+	}
+	r.cache.InvalidateDomain(ctx, document.DomainID)
+	return nil
+}
+
+func (r *invalidatingDocumentRepository) Update(ctx context.Context, document *models.Document) error {
+	previous, _ := r.DocumentRepository.GetByID(ctx, document.ID)
+	if err := r.DocumentRepository.Update(ctx, document); err != nil {
+		return err
+	}
+	r.cache.InvalidateDomain(ctx, document.DomainID)
+	if previous != nil && previous.DomainID != document.DomainID {
+		r.cache.InvalidateDomain(ctx, previous.DomainID)
+	}
+	return nil
+}
+
+func (r *invalidatingDocumentRepository) Delete(ctx context.Context, id uint64) error {
+	previous, _ := r.DocumentRepository.GetByID(ctx, id)
+	if err := r.DocumentRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	if previous != nil {
+		r.cache.InvalidateDomain(ctx, previous.DomainID)
+	} else {
+		// 删除前未能读取文档，无法确定知识域，只使不限知识域的结果失效
+		r.cache.InvalidateDomain(ctx, 0)
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/repo/memory"
+)
+
+// stubDocuments 内存中的文档仓储，只实现包装器用到的方法
+type stubDocuments struct {
+	repository.DocumentRepository
+	docs map[uint64]*models.Document
+}
+
+func (s *stubDocuments) Create(ctx context.Context, document *models.Document) error {
+	document.ID = uint64(len(s.docs) + 1)
+	copied := *document
+	s.docs[document.ID] = &copied
+	return nil
+}
+
+func (s *stubDocuments) GetByID(ctx context.Context, id uint64) (*models.Document, error) {
+	d, ok := s.docs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (s *stubDocuments) Update(ctx context.Context, document *models.Document) error {
+	copied := *document
+	s.docs[document.ID] = &copied
+	return nil
+}
+
+func (s *stubDocuments) Delete(ctx context.Context, id uint64) error {
+	delete(s.docs, id)
+	return nil
+}
+
+// stubRetriever 返回固定结果或错误，记录调用次数
+type stubRetriever struct {
+	mu      sync.Mutex
+	calls   int
+	results []models.SearchResult
+	err     error
+}
+
+func (r *stubRetriever) Retrieve(ctx context.Context, query string, domainID uint64, limit int, filter *RetrievalFilter) ([]models.SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return r.results, r.err
+}
+
+func (r *stubRetriever) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func newTestSearchCache(embedder Embedder, threshold float64) *SearchCache {
+	opts := DefaultSearchCacheOptions()
+	opts.SemanticThreshold = threshold
+	return NewSearchCache(memory.NewCacheRepository(1000), embedder, opts)
+}
+
+// storeResponse 以未命中的方式写入请求的结果
+func storeResponse(t *testing.T, c *SearchCache, req *models.SearchRequest, id string) {
+	t.Helper()
+	resp, entry := c.Lookup(context.Background(), req)
+	if resp != nil {
+		t.Fatalf("unexpected cache %v for %q", resp.Metadata["cache"], req.Query)
+	}
+	if entry == nil {
+		t.Fatal("expected a cache entry")
+	}
+	c.Store(context.Background(), entry, &models.SearchResponse{
+		Query:    req.Query,
+		Results:  []models.SearchResult{{ID: id, Type: "chunk"}},
+		Metadata: map[string]interface{}{},
+	})
+}
+
+func cacheState(c *SearchCache, req *models.SearchRequest) interface{} {
+	resp, _ := c.Lookup(context.Background(), req)
+	if resp == nil {
+		return SearchCacheMiss
+	}
+	return resp.Metadata["cache"]
+}
+
+func TestSearchCacheNormalizesQuery(t *testing.T) {
+	c := newTestSearchCache(nil, 0)
+	storeResponse(t, c, &models.SearchRequest{Query: "Hello World", DomainID: 1}, "r1")
+
+	resp, _ := c.Lookup(context.Background(), &models.SearchRequest{Query: "  hello   WORLD ", DomainID: 1})
+	if resp == nil || resp.Metadata["cache"] != SearchCacheHit {
+		t.Fatalf("expected a hit after normalization, got %v", resp)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ID != "r1" {
+		t.Fatalf("unexpected cached results %v", resp.Results)
+	}
+}
+
+func TestSearchCacheKeyIncludesParameters(t *testing.T) {
+	c := newTestSearchCache(nil, 0)
+	base := models.SearchRequest{Query: "q", DomainID: 1, Filters: map[string]interface{}{"tags": []string{"a"}}}
+	storeResponse(t, c, &base, "r1")
+
+	cases := map[string]models.SearchRequest{
+		"domain":  {Query: "q", DomainID: 2, Filters: base.Filters},
+		"filters": {Query: "q", DomainID: 1, Filters: map[string]interface{}{"tags": []string{"b"}}},
+		"options": {Query: "q", DomainID: 1, Filters: base.Filters, Options: models.SearchOptions{Limit: 5}},
+		"context": {Query: "q", DomainID: 1, Filters: base.Filters, Context: models.SearchContext{PreviousQuery: "p"}},
+	}
+	for name, req := range cases {
+		req := req
+		if got := cacheState(c, &req); got != SearchCacheMiss {
+			t.Errorf("%s: expected miss, got %v", name, got)
+		}
+	}
+	if got := cacheState(c, &base); got != SearchCacheHit {
+		t.Fatalf("expected hit for the original request, got %v", got)
+	}
+}
+
+func TestSearchCacheInvalidateDomain(t *testing.T) {
+	c := newTestSearchCache(nil, 0)
+	one := &models.SearchRequest{Query: "q", DomainID: 1}
+	two := &models.SearchRequest{Query: "q", DomainID: 2}
+	all := &models.SearchRequest{Query: "q"}
+	for _, req := range []*models.SearchRequest{one, two, all} {
+		storeResponse(t, c, req, "r")
+	}
+
+	c.InvalidateDomain(context.Background(), 1)
+
+	if got := cacheState(c, one); got != SearchCacheMiss {
+		t.Errorf("domain 1: expected miss, got %v", got)
+	}
+	if got := cacheState(c, all); got != SearchCacheMiss {
+		t.Errorf("all domains: expected miss, got %v", got)
+	}
+	if got := cacheState(c, two); got != SearchCacheHit {
+		t.Errorf("domain 2: expected hit, got %v", got)
+	}
+}
+
+func TestWrapDocumentsInvalidatesOnUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	c := newTestSearchCache(nil, 0)
+	documents := c.WrapDocuments(&stubDocuments{docs: map[uint64]*models.Document{}})
+
+	doc := &models.Document{DomainID: 1, Title: "t"}
+	if err := documents.Create(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	versions := func() (int64, int64) {
+		v1, err1 := c.version(ctx, searchCacheScope(1))
+		v2, err2 := c.version(ctx, searchCacheScope(2))
+		if err := errors.Join(err1, err2); err != nil {
+			t.Fatal(err)
+		}
+		return v1, v2
+	}
+	v1, v2 := versions()
+	if v1 != 1 || v2 != 0 {
+		t.Fatalf("after create: versions = %d, %d, want 1, 0", v1, v2)
+	}
+
+	doc.Title = "updated"
+	if err := documents.Update(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	if v1, v2 = versions(); v1 != 2 || v2 != 0 {
+		t.Fatalf("after update: versions = %d, %d, want 2, 0", v1, v2)
+	}
+
+	if err := documents.Delete(ctx, doc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if v1, v2 = versions(); v1 != 3 || v2 != 0 {
+		t.Fatalf("after delete: versions = %d, %d, want 3, 0", v1, v2)
+	}
+}
+
+func TestWrapDocumentsInvalidatesBothDomainsOnMove(t *testing.T) {
+	ctx := context.Background()
+	c := newTestSearchCache(nil, 0)
+	documents := c.WrapDocuments(&stubDocuments{docs: map[uint64]*models.Document{}})
+	doc := &models.Document{DomainID: 1}
+	if err := documents.Create(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	one := &models.SearchRequest{Query: "q", DomainID: 1}
+	two := &models.SearchRequest{Query: "q", DomainID: 2}
+	three := &models.SearchRequest{Query: "q", DomainID: 3}
+	for _, req := range []*models.SearchRequest{one, two, three} {
+		storeResponse(t, c, req, "r")
+	}
+
+	doc.DomainID = 2
+	if err := documents.Update(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	if cacheState(c, one) != SearchCacheMiss || cacheState(c, two) != SearchCacheMiss {
+		t.Fatal("expected both the old and the new domain to be invalidated")
+	}
+	if got := cacheState(c, three); got != SearchCacheHit {
+		t.Fatalf("domain 3: expected hit, got %v", got)
+	}
+}
+
+func TestSearcherCachesCompleteResults(t *testing.T) {
+	ctx := context.Background()
+	retriever := &stubRetriever{results: []models.SearchResult{{ID: "c1", Type: "chunk", Score: 1}}}
+	s := NewSearcher(map[string]Retriever{"keyword": retriever}, nil, nil, nil, nil, nil, nil, nil, nil, newTestSearchCache(nil, 0))
+	req := &models.SearchRequest{Query: "q", DomainID: 1}
+
+	first, err := s.Search(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Metadata["cache"] != SearchCacheMiss {
+		t.Fatalf("first search: cache = %v, want miss", first.Metadata["cache"])
+	}
+	second, err := s.Search(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Metadata["cache"] != SearchCacheHit {
+		t.Fatalf("second search: cache = %v, want hit", second.Metadata["cache"])
+	}
+	if second.QueryID == first.QueryID {
+		t.Fatal("cached response should get a new query ID")
+	}
+	if retriever.count() != 1 {
+		t.Fatalf("retriever called %d times, want 1", retriever.count())
+	}
+}
+
+func TestSearcherDoesNotCacheDegradedResults(t *testing.T) {
+	ctx := context.Background()
+	ok := &stubRetriever{results: []models.SearchResult{{ID: "c1", Type: "chunk", Score: 1}}}
+	failing := &stubRetriever{err: errors.New("unavailable")}
+	s := NewSearcher(map[string]Retriever{"keyword": ok, "vector": failing}, nil, nil, nil, nil, nil, nil, nil, nil, newTestSearchCache(nil, 0))
+	req := &models.SearchRequest{Query: "q", DomainID: 1}
+
+	for i := 0; i < 2; i++ {
+		resp, err := s.Search(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, degraded := resp.Metadata["degraded"]; !degraded {
+			t.Fatalf("search %d: expected degraded metadata", i)
+		}
+		if _, cached := resp.Metadata["cache"]; cached {
+			t.Fatalf("search %d: degraded result was cached: %v", i, resp.Metadata["cache"])
+		}
+	}
+	if ok.count() != 2 {
+		t.Fatalf("retriever called %d times, want 2", ok.count())
+	}
+}
+
+// stubEmbedder 按规范化文本返回固定向量，记录调用次数
+type stubEmbedder struct {
+	mu      sync.Mutex
+	calls   int
+	vectors map[string][]float32
+}
+
+func (e *stubEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v, ok := e.vectors[NormalizeQuery(text)]
+		if !ok {
+			return nil, errors.New("unknown text " + text)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func newSemanticEmbedder() *stubEmbedder {
+	return &stubEmbedder{vectors: map[string][]float32{
+		"reset password":        {1, 0},
+		"how to reset password": {0.99, 0.14}, // 与reset password的余弦相似度约0.99
+		"change email":          {0.8, 0.6},   // 约0.8
+	}}
+}
+
+func TestSearchCacheSemanticHit(t *testing.T) {
+	c := newTestSearchCache(newSemanticEmbedder(), 0.95)
+	storeResponse(t, c, &models.SearchRequest{Query: "reset password", DomainID: 1}, "r1")
+
+	resp, _ := c.Lookup(context.Background(), &models.SearchRequest{Query: "How to reset password", DomainID: 1})
+	if resp == nil || resp.Metadata["cache"] != SearchCacheSemantic {
+		t.Fatalf("expected a semantic hit, got %v", resp)
+	}
+	if resp.Metadata["cached_query"] != "reset password" {
+		t.Fatalf("cached_query = %v", resp.Metadata["cached_query"])
+	}
+	if similarity, _ := resp.Metadata["cache_similarity"].(float64); similarity < 0.95 {
+		t.Fatalf("cache_similarity = %v", resp.Metadata["cache_similarity"])
+	}
+}
+
+func TestSearchCacheSemanticMisses(t *testing.T) {
+	ctx := context.Background()
+	c := newTestSearchCache(newSemanticEmbedder(), 0.95)
+	storeResponse(t, c, &models.SearchRequest{Query: "reset password", DomainID: 1}, "r1")
+
+	if got := cacheState(c, &models.SearchRequest{Query: "change email", DomainID: 1}); got != SearchCacheMiss {
+		t.Errorf("below threshold: expected miss, got %v", got)
+	}
+	if got := cacheState(c, &models.SearchRequest{Query: "how to reset password", DomainID: 2}); got != SearchCacheMiss {
+		t.Errorf("other domain: expected miss, got %v", got)
+	}
+	if got := cacheState(c, &models.SearchRequest{Query: "how to reset password", DomainID: 1, Options: models.SearchOptions{Limit: 3}}); got != SearchCacheMiss {
+		t.Errorf("different options: expected miss, got %v", got)
+	}
+
+	c.InvalidateDomain(ctx, 1)
+	if got := cacheState(c, &models.SearchRequest{Query: "how to reset password", DomainID: 1}); got != SearchCacheMiss {
+		t.Errorf("after invalidation: expected miss, got %v", got)
+	}
+}
+
+func TestSearchCacheDisabledSemanticThreshold(t *testing.T) {
+	embedder := newSemanticEmbedder()
+	c := newTestSearchCache(embedder, 0)
+	storeResponse(t, c, &models.SearchRequest{Query: "reset password", DomainID: 1}, "r1")
+	if got := cacheState(c, &models.SearchRequest{Query: "how to reset password", DomainID: 1}); got != SearchCacheMiss {
+		t.Fatalf("expected miss with semantic cache disabled, got %v", got)
+	}
+	if embedder.calls != 0 {
+		t.Fatalf("embedder called %d times with semantic cache disabled", embedder.calls)
+	}
+}
+
+func TestSearchCacheEntryVectorReused(t *testing.T) {
+	ctx := context.Background()
+	embedder := newSemanticEmbedder()
+	c := newTestSearchCache(embedder, 0.95)
+	_, entry := c.Lookup(ctx, &models.SearchRequest{Query: "Reset  Password", DomainID: 1})
+	if embedder.calls != 1 {
+		t.Fatalf("lookup embedded %d times, want 1", embedder.calls)
+	}
+
+	vector, err := embedQuery(entry.WithVector(ctx), embedder, "reset password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedder.calls != 1 || len(vector) != 2 || vector[0] != 1 {
+		t.Fatalf("expected the lookup vector to be reused, calls = %d, vector = %v", embedder.calls, vector)
+	}
+	if _, err := embedQuery(entry.WithVector(ctx), embedder, "change email"); err != nil || embedder.calls != 2 {
+		t.Fatalf("a different query should be embedded, calls = %d, err = %v", embedder.calls, err)
+	}
+}
+
+func TestSearcherSemanticHitBorrowsPlan(t *testing.T) {
+	ctx := context.Background()
+	retriever := &stubRetriever{results: []models.SearchResult{{ID: "c1", Type: "chunk", Score: 1}}}
+	s := NewSearcher(map[string]Retriever{"keyword": retriever}, nil, nil, nil, nil, nil, nil, nil, nil, newTestSearchCache(newSemanticEmbedder(), 0.95))
+
+	if _, err := s.Search(ctx, &models.SearchRequest{Query: "reset password", DomainID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.Search(ctx, &models.SearchRequest{Query: "how to reset password", DomainID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Metadata["cache"] != SearchCacheSemantic {
+		t.Fatalf("cache = %v, want semantic", resp.Metadata["cache"])
+	}
+	if resp.Plan != nil {
+		t.Fatalf("semantic hit should not carry the cached query's plan as its own: %+v", resp.Plan)
+	}
+	if resp.Metadata["cached_plan"] == nil {
+		t.Fatal("expected the borrowed plan in metadata cached_plan")
+	}
+	if resp.Query != "how to reset password" || retriever.count() != 1 {
+		t.Fatalf("query = %q, retriever calls = %d", resp.Query, retriever.count())
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// EnsureCollection 确保向量集合存在，不存在时按向量维度创建集合和索引
+func EnsureCollection(ctx context.Context, vector repository.VectorRepository, collectionName string, dimension int) error {
+	exists, err := vector.HasCollection(ctx, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", collectionName, err)
+	}
+	if exists {
+		return nil
+	}
+
+	if err := vector.CreateCollection(ctx, collectionName, dimension); err != nil {
+		return err
+	}
+	return vector.CreateIndex(ctx, collectionName, nil)
+}
+
+// domainFilter 按知识域过滤向量的表达式，domainID为0时不过滤
+func domainFilter(domainID uint64) map[string]interface{} {
+	if domainID == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"expr": fmt.Sprintf(`metadata["domain_id"] == %d`, domainID),
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"log"
 
 	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/infra/llm"
 	"github.com/xyzbit/ino/internal/infra/milvus"
 	"github.com/xyzbit/ino/internal/infra/mysql"
 	"github.com/xyzbit/ino/internal/infra/redis"
+	"github.com/xyzbit/ino/internal/infra/rerank"
 )
 
 // Init 初始化所有基础设施
@@ -15,6 +17,8 @@ func Init() {
 	mysql.Init()
 	redis.Init()
 	milvus.Init()
+	llm.Init()
+	rerank.Init()
 
 	// 初始化种子数据
 	if err := models.SeedData(mysql.DB); err != nil {
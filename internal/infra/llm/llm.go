@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/xyzbit/ino/config"
+	"github.com/xyzbit/ino/internal/domain/services"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// Client 大模型客户端，兼容OpenAI Chat Completions和Embeddings协议
+type Client struct {
+	httpClient     *http.Client
+	baseURL        string
+	apiKey         string
+	model          string
+	embeddingModel string
+}
+
+var DefaultClient *Client
+
+// Init 初始化大模型客户端，未配置API Key时不启用
+func Init() {
+	cfg := config.AppConfig.Eino
+
+	if cfg.APIKey == "" {
+		log.Printf("Warning: eino.api_key is not set, LLM features are disabled")
+		return
+	}
+
+	DefaultClient = &Client{
+		httpClient:     &http.Client{Timeout: cfg.Timeout},
+		baseURL:        strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:         cfg.APIKey,
+		model:          cfg.Model,
+		embeddingModel: cfg.EmbeddingModel,
+	}
+	log.Printf("LLM client initialized, model: %s, embedding model: %s", cfg.Model, cfg.EmbeddingModel)
+}
+
+// chatRequest Chat Completions请求
+type chatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []services.ChatMessage `json:"messages"`
+}
+
+// chatResponse Chat Completions响应
+type chatResponse struct {
+	Choices []struct {
+		Message services.ChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Chat 调用对话模型
+func (c *Client) Chat(ctx context.Context, messages []services.ChatMessage) (string, error) {
+	var resp chatResponse
+	err := c.post(ctx, "/chat/completions", chatRequest{Model: c.model, Messages: messages}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("chat completion returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// embeddingRequest Embeddings请求
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingResponse Embeddings响应
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed 批量向量化文本，返回顺序与输入一致
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var resp embeddingResponse
+	err := c.post(ctx, "/embeddings", embeddingRequest{Model: c.embeddingModel, Input: texts}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// post 发送JSON请求并解析响应
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("llm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("llm request %s failed with status %d: %s", path, resp.StatusCode, textutil.Truncate(string(data), 512))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// 编译期检查接口实现
+var (
+	_ services.ChatModel = (*Client)(nil)
+	_ services.Embedder  = (*Client)(nil)
+)
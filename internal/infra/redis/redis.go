@@ -11,6 +11,8 @@ import (
 
 var (
 	Redis *redis.Client
+
+	connected bool
 )
 
 // InitRedis 初始化Redis连接
@@ -32,10 +34,16 @@ func Init() {
 		log.Printf("Warning: Failed to connect to Redis: %v", err)
 		// 在开发阶段先不Fatal，允许不连接Redis启动
 	} else {
+		connected = true
 		log.Printf("Connected to Redis successfully")
 	}
 }
 
+// Available 初始化时是否连接成功，未连接时应使用进程内缓存代替
+func Available() bool {
+	return Redis != nil && connected
+}
+
 // Close 关闭数据库连接
 func Close() {
 	if Redis != nil {
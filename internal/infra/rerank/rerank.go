@@ -0,0 +1,170 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/xyzbit/ino/config"
+	"github.com/xyzbit/ino/internal/domain/services"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+)
+
+// 重排序接口协议
+const (
+	ProtocolCohere = "cohere" // Cohere和Jina：documents入参，results[].relevance_score出参
+	ProtocolTEI    = "tei"    // Text Embeddings Inference：texts入参，[]{index, score}出参
+)
+
+// Client 交叉编码器重排序客户端，调用POST {base_url}/rerank
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+	protocol   string
+}
+
+var DefaultClient *Client
+
+// Init 初始化重排序客户端，只在使用交叉编码器且配置了接口地址时启用
+func Init() {
+	cfg := config.AppConfig.Search.Rerank
+	if cfg.Provider != services.RerankerCrossEncoder {
+		return
+	}
+	if cfg.BaseURL == "" {
+		log.Printf("Warning: search.rerank.base_url is not set, cross-encoder reranking is disabled")
+		return
+	}
+
+	DefaultClient = NewClient(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Protocol)
+	log.Printf("Rerank client initialized, model: %s, protocol: %s", cfg.Model, DefaultClient.protocol)
+}
+
+// NewClient 创建重排序客户端，协议为空时使用Cohere协议
+func NewClient(baseURL, apiKey, model, protocol string) *Client {
+	if protocol == "" {
+		protocol = ProtocolCohere
+	}
+	return &Client{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		protocol:   protocol,
+	}
+}
+
+// cohereRequest Cohere/Jina重排序请求
+type cohereRequest struct {
+	Model           string   `json:"model,omitempty"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+// cohereResponse Cohere/Jina重排序响应
+type cohereResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// teiRequest TEI重排序请求
+type teiRequest struct {
+	Query     string   `json:"query"`
+	Texts     []string `json:"texts"`
+	RawScores bool     `json:"raw_scores"`
+}
+
+// teiResponse TEI重排序响应
+type teiResponse []struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// Rerank 计算每个文档与查询的相关度，顺序与输入一致，接口未返回的文档为0
+func (c *Client) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	if len(documents) == 0 {
+		return scores, nil
+	}
+
+	set := func(index int, score float64) error {
+		if index < 0 || index >= len(documents) {
+			return fmt.Errorf("rerank index %d out of range", index)
+		}
+		scores[index] = score
+		return nil
+	}
+	switch c.protocol {
+	case ProtocolTEI:
+		var resp teiResponse
+		if err := c.post(ctx, teiRequest{Query: query, Texts: documents}, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp {
+			if err := set(r.Index, r.Score); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		var resp cohereResponse
+		req := cohereRequest{Model: c.model, Query: query, Documents: documents, TopN: len(documents)}
+		if err := c.post(ctx, req, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Results {
+			if err := set(r.Index, r.RelevanceScore); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return scores, nil
+}
+
+// post 发送JSON请求并解析响应
+func (c *Client) post(ctx context.Context, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/rerank", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rerank request failed with status %d: %s", resp.StatusCode, textutil.Truncate(string(data), 512))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// 编译期检查接口实现
+var _ services.Reranker = (*Client)(nil)
@@ -0,0 +1,131 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/services"
+)
+
+func TestRerankCohere(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("unexpected authorization %q", got)
+		}
+		var req cohereRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if req.Query != "q" || len(req.Documents) != 3 || req.TopN != 3 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		w.Write([]byte(`{"results":[{"index":2,"relevance_score":0.9},{"index":0,"relevance_score":0.5}]}`))
+	}))
+	defer server.Close()
+
+	scores, err := NewClient(server.URL, "key", "m", ProtocolCohere).Rerank(context.Background(), "q", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	want := []float64{0.5, 0, 0.9}
+	for i := range want {
+		if scores[i] != want[i] {
+			t.Fatalf("scores = %v, want %v", scores, want)
+		}
+	}
+}
+
+func TestRerankTEI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"index":1,"score":0.8},{"index":0,"score":0.1}]`))
+	}))
+	defer server.Close()
+
+	scores, err := NewClient(server.URL, "", "", ProtocolTEI).Rerank(context.Background(), "q", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if scores[0] != 0.1 || scores[1] != 0.8 {
+		t.Fatalf("scores = %v", scores)
+	}
+}
+
+func TestRerankMalformedResponse(t *testing.T) {
+	cases := map[string]string{
+		"invalid json":       `{"results":`,
+		"index out of range": `{"results":[{"index":5,"relevance_score":0.9}]}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			if _, err := NewClient(server.URL, "", "", "").Rerank(context.Background(), "q", []string{"a", "b"}); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestRerankErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL, "", "", "").Rerank(context.Background(), "q", []string{"a"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRerankStageBudgetFallback(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(2 * time.Second):
+		}
+		w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.9}]}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	stage := services.NewRerankStage(NewClient(server.URL, "", "", ""), services.RerankOptions{TopN: 10, Budget: 50 * time.Millisecond, MaxChars: 100})
+	results := []models.SearchResult{{ID: "a", Content: "a"}, {ID: "b", Content: "b"}}
+
+	start := time.Now()
+	got, reranked := stage.Apply(context.Background(), "q", results)
+	if reranked {
+		t.Fatal("expected fallback when the budget is exceeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Apply took %s, budget not enforced", elapsed)
+	}
+	if got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("fused order not kept: %v, %v", got[0].ID, got[1].ID)
+	}
+}
+
+func TestRerankStageMalformedFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`not json`))
+	}))
+	defer server.Close()
+
+	stage := services.NewRerankStage(NewClient(server.URL, "", "", ""), services.DefaultRerankOptions())
+	results := []models.SearchResult{{ID: "a", Content: "a"}, {ID: "b", Content: "b"}}
+	got, reranked := stage.Apply(context.Background(), "q", results)
+	if reranked || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("expected fused order on malformed response, reranked=%v", reranked)
+	}
+}
@@ -0,0 +1,184 @@
+// Package textutil 提供中英文混合文本的分词、相似度和聚类等轻量文本处理工具
+package textutil
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stopWords 常见英文停用词，中文按二元组切分后停用词影响较小，不单独处理
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "i": true, "in": true, "is": true,
+	"it": true, "its": true, "not": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "were": true, "with": true, "you": true, "your": true,
+}
+
+// IsCJK 是否为中日韩文字
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Tokenize 切分文本：字母数字连续段按词切分并去除停用词，中日韩文字连续段按二元组切分
+func Tokenize(s string) []string {
+	var (
+		tokens []string
+		word   []rune
+		cjk    []rune
+	)
+	flushWord := func() {
+		if len(word) > 1 || (len(word) == 1 && unicode.IsDigit(word[0])) {
+			if w := string(word); !stopWords[w] {
+				tokens = append(tokens, w)
+			}
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case IsCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// TokenSet 文本的词集合
+func TokenSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range Tokenize(s) {
+		set[t] = true
+	}
+	return set
+}
+
+// Jaccard 两个词集合的Jaccard相似度
+func Jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	inter := 0
+	for t := range a {
+		if b[t] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// Cluster 文本聚类结果
+type Cluster struct {
+	Label   string // 代表性文本：与簇内其他文本平均相似度最高的成员
+	Members []int  // 成员在输入中的下标
+}
+
+// ClusterTexts 按词集合的Jaccard相似度对文本做贪心聚类：文本加入与其种子最相似且相似度
+// 不低于threshold的簇，否则自成一簇。结果按簇大小降序排列，没有有效词的文本被忽略。
+func ClusterTexts(texts []string, threshold float64) []Cluster {
+	type cluster struct {
+		seed    map[string]bool
+		members []int
+	}
+	sets := make([]map[string]bool, len(texts))
+	var clusters []*cluster
+	for i, text := range texts {
+		sets[i] = TokenSet(text)
+		if len(sets[i]) == 0 {
+			continue
+		}
+		var best *cluster
+		bestScore := threshold
+		for _, c := range clusters {
+			if score := Jaccard(sets[i], c.seed); score >= bestScore {
+				best, bestScore = c, score
+			}
+		}
+		if best == nil {
+			best = &cluster{seed: sets[i]}
+			clusters = append(clusters, best)
+		}
+		best.members = append(best.members, i)
+	}
+
+	result := make([]Cluster, 0, len(clusters))
+	for _, c := range clusters {
+		result = append(result, Cluster{Label: strings.TrimSpace(texts[centroid(c.members, sets)]), Members: c.members})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return len(result[i].Members) > len(result[j].Members) })
+	return result
+}
+
+// centroid 返回与簇内其他成员相似度之和最高的成员
+func centroid(members []int, sets []map[string]bool) int {
+	best, bestScore := members[0], -1.0
+	for _, i := range members {
+		score := 0.0
+		for _, j := range members {
+			if i != j {
+				score += Jaccard(sets[i], sets[j])
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// EstimateTokens 估算文本的模型token数：中日韩文字每字计1个，字母数字连续段每4个字符计1个，
+// 其他非空白字符各计1个。与BPE分词器的实际结果相比略为偏大，适合做预算控制
+func EstimateTokens(s string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range s {
+		switch {
+		case IsCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Truncate 把文本截断到不超过n个字符，截断时末尾加省略号；n不大于0时不截断
+func Truncate(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
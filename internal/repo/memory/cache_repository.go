@@ -0,0 +1,550 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+// ErrWrongType 对已存在的键执行了与其类型不符的操作，与Redis的WRONGTYPE一致
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// 键的值类型
+const (
+	kindString = iota
+	kindHash
+	kindList
+	kindSet
+)
+
+// entry 一个键及其值，按类型只使用对应字段
+type entry struct {
+	key      string
+	kind     int
+	str      string
+	hash     map[string]string
+	list     []string
+	set      map[string]struct{}
+	expireAt time.Time // 零值表示不过期
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+type cacheRepository struct {
+	mu      sync.Mutex
+	maxKeys int
+	items   map[string]*list.Element
+	lru     *list.List // 队首为最近访问
+	now     func() time.Time
+}
+
+// NewCacheRepository 创建进程内缓存仓储实例，语义与Redis实现一致。过期键在访问时惰性删除，
+// 键数超过maxKeys时淘汰最久未访问的键，maxKeys不大于0表示不限制
+func NewCacheRepository(maxKeys int) repository.CacheRepository {
+	return &cacheRepository{
+		maxKeys: maxKeys,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// lookup 查找未过期的键并标记为最近访问，调用方需持有锁
+func (r *cacheRepository) lookup(key string) *entry {
+	el, ok := r.items[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	if e.expired(r.now()) {
+		r.remove(el)
+		return nil
+	}
+	r.lru.MoveToFront(el)
+	return e
+}
+
+// lookupKind 查找指定类型的键，类型不符时返回ErrWrongType
+func (r *cacheRepository) lookupKind(key string, kind int) (*entry, error) {
+	e := r.lookup(key)
+	if e != nil && e.kind != kind {
+		return nil, ErrWrongType
+	}
+	return e, nil
+}
+
+// obtain 查找指定类型的键，不存在时创建，调用方需持有锁
+func (r *cacheRepository) obtain(key string, kind int) (*entry, error) {
+	e, err := r.lookupKind(key, kind)
+	if err != nil || e != nil {
+		return e, err
+	}
+	e = &entry{key: key, kind: kind}
+	switch kind {
+	case kindHash:
+		e.hash = make(map[string]string)
+	case kindSet:
+		e.set = make(map[string]struct{})
+	}
+	r.insert(e)
+	return e, nil
+}
+
+// insert 插入新键，超出容量时淘汰最久未访问的键
+func (r *cacheRepository) insert(e *entry) {
+	r.items[e.key] = r.lru.PushFront(e)
+	for r.maxKeys > 0 && r.lru.Len() > r.maxKeys {
+		r.remove(r.lru.Back())
+	}
+}
+
+func (r *cacheRepository) remove(el *list.Element) {
+	r.lru.Remove(el)
+	delete(r.items, el.Value.(*entry).key)
+}
+
+// removeIfEmpty 集合类型的值为空时删除键，与Redis一致
+func (r *cacheRepository) removeIfEmpty(e *entry) {
+	if len(e.hash) == 0 && len(e.list) == 0 && len(e.set) == 0 {
+		if el, ok := r.items[e.key]; ok {
+			r.remove(el)
+		}
+	}
+}
+
+// expireAt ttl（秒）对应的过期时间，0表示不过期
+func (r *cacheRepository) expireAt(ttl int) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return r.now().Add(time.Duration(ttl) * time.Second)
+}
+
+func notFound(key string) error {
+	return fmt.Errorf("%w: cache key %s", repository.ErrNotFound, key)
+}
+
+// encodeValues 序列化多个值
+func encodeValues(values []interface{}) ([]string, error) {
+	encoded := make([]string, len(values))
+	for i, v := range values {
+		s, err := repository.EncodeCacheValue(v)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = s
+	}
+	return encoded, nil
+}
+
+// Set 设置键值，覆盖任意类型的旧值
+func (r *cacheRepository) Set(ctx context.Context, key string, value interface{}, ttl int) error {
+	s, err := repository.EncodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.items[key]; ok {
+		r.remove(el)
+	}
+	r.insert(&entry{key: key, kind: kindString, str: s, expireAt: r.expireAt(ttl)})
+	return nil
+}
+
+// Get 获取键值
+func (r *cacheRepository) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindString)
+	if err != nil {
+		return "", err
+	}
+	if e == nil {
+		return "", notFound(key)
+	}
+	return e.str, nil
+}
+
+// Del 删除键
+func (r *cacheRepository) Del(ctx context.Context, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := r.items[key]; ok {
+			r.remove(el)
+		}
+	}
+	return nil
+}
+
+// Exists 键是否存在
+func (r *cacheRepository) Exists(ctx context.Context, key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookup(key) != nil, nil
+}
+
+// Expire 设置键的过期时间，ttl不大于0时立即删除键，与Redis一致
+func (r *cacheRepository) Expire(ctx context.Context, key string, ttl int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.lookup(key)
+	if e == nil {
+		return nil
+	}
+	if ttl <= 0 {
+		r.remove(r.items[key])
+		return nil
+	}
+	e.expireAt = r.expireAt(ttl)
+	return nil
+}
+
+// Keys 返回匹配glob模式（*、?、[...]）的未过期键，同时清理已过期的键
+func (r *cacheRepository) Keys(ctx context.Context, pattern string) ([]string, error) {
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	var keys []string
+	for el := r.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*entry)
+		if e.expired(now) {
+			r.remove(el)
+		} else if re.MatchString(e.key) {
+			keys = append(keys, e.key)
+		}
+		el = next
+	}
+	return keys, nil
+}
+
+// TTL 键剩余的过期时间，不过期时返回0
+func (r *cacheRepository) TTL(ctx context.Context, key string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.lookup(key)
+	if e == nil {
+		return 0, notFound(key)
+	}
+	if e.expireAt.IsZero() {
+		return 0, nil
+	}
+	return e.expireAt.Sub(r.now()), nil
+}
+
+// Incr 原子地将整数值加一，键不存在时从0开始，保留原有的过期时间
+func (r *cacheRepository) Incr(ctx context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.obtain(key, kindString)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if e.str != "" {
+		if n, err = strconv.ParseInt(e.str, 10, 64); err != nil {
+			return 0, fmt.Errorf("value of cache key %s is not an integer", key)
+		}
+	}
+	n++
+	e.str = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// globRegexp 把Redis的glob模式转换为正则表达式
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+		case '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "^") {
+				class = "^" + strings.ReplaceAll(class[1:], `\`, `\\`)
+			} else {
+				class = strings.ReplaceAll(class, `\`, `\\`)
+			}
+			b.WriteString("[" + class + "]")
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// HSet 设置哈希字段
+func (r *cacheRepository) HSet(ctx context.Context, key, field string, value interface{}) error {
+	s, err := repository.EncodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.obtain(key, kindHash)
+	if err != nil {
+		return err
+	}
+	e.hash[field] = s
+	return nil
+}
+
+// HGet 获取哈希字段
+func (r *cacheRepository) HGet(ctx context.Context, key, field string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindHash)
+	if err != nil {
+		return "", err
+	}
+	if e == nil {
+		return "", notFound(key)
+	}
+	value, ok := e.hash[field]
+	if !ok {
+		return "", notFound(key + "." + field)
+	}
+	return value, nil
+}
+
+// HGetAll 获取哈希的全部字段，键不存在时返回空映射
+func (r *cacheRepository) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindHash)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]string)
+	if e != nil {
+		for field, value := range e.hash {
+			all[field] = value
+		}
+	}
+	return all, nil
+}
+
+// HDel 删除哈希字段
+func (r *cacheRepository) HDel(ctx context.Context, key string, fields ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindHash)
+	if err != nil || e == nil {
+		return err
+	}
+	for _, field := range fields {
+		delete(e.hash, field)
+	}
+	r.removeIfEmpty(e)
+	return nil
+}
+
+// LPush 从列表头部依次插入，与Redis一致，最后一个值位于队首
+func (r *cacheRepository) LPush(ctx context.Context, key string, values ...interface{}) error {
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.obtain(key, kindList)
+	if err != nil {
+		return err
+	}
+	pushed := make([]string, 0, len(encoded)+len(e.list))
+	for i := len(encoded) - 1; i >= 0; i-- {
+		pushed = append(pushed, encoded[i])
+	}
+	e.list = append(pushed, e.list...)
+	r.removeIfEmpty(e)
+	return nil
+}
+
+// RPush 从列表尾部插入
+func (r *cacheRepository) RPush(ctx context.Context, key string, values ...interface{}) error {
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.obtain(key, kindList)
+	if err != nil {
+		return err
+	}
+	e.list = append(e.list, encoded...)
+	r.removeIfEmpty(e)
+	return nil
+}
+
+// LPop 从列表头部弹出
+func (r *cacheRepository) LPop(ctx context.Context, key string) (string, error) {
+	return r.pop(key, true)
+}
+
+// RPop 从列表尾部弹出
+func (r *cacheRepository) RPop(ctx context.Context, key string) (string, error) {
+	return r.pop(key, false)
+}
+
+func (r *cacheRepository) pop(key string, head bool) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindList)
+	if err != nil {
+		return "", err
+	}
+	if e == nil {
+		return "", notFound(key)
+	}
+	var value string
+	if head {
+		value, e.list = e.list[0], e.list[1:]
+	} else {
+		n := len(e.list) - 1
+		value, e.list = e.list[n], e.list[:n]
+	}
+	r.removeIfEmpty(e)
+	return value, nil
+}
+
+// LLen 列表长度
+func (r *cacheRepository) LLen(ctx context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindList)
+	if err != nil || e == nil {
+		return 0, err
+	}
+	return int64(len(e.list)), nil
+}
+
+// LRange 获取列表区间内的元素，下标含两端，负数表示从尾部计数，与Redis一致
+func (r *cacheRepository) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindList)
+	if err != nil || e == nil {
+		return []string{}, err
+	}
+	n := int64(len(e.list))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string(nil), e.list[start:stop+1]...), nil
+}
+
+// SAdd 向集合添加成员
+func (r *cacheRepository) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	encoded, err := encodeValues(members)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.obtain(key, kindSet)
+	if err != nil {
+		return err
+	}
+	for _, m := range encoded {
+		e.set[m] = struct{}{}
+	}
+	r.removeIfEmpty(e)
+	return nil
+}
+
+// SMembers 获取集合的全部成员
+func (r *cacheRepository) SMembers(ctx context.Context, key string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindSet)
+	if err != nil || e == nil {
+		return []string{}, err
+	}
+	members := make([]string, 0, len(e.set))
+	for m := range e.set {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// SRem 从集合删除成员
+func (r *cacheRepository) SRem(ctx context.Context, key string, members ...interface{}) error {
+	encoded, err := encodeValues(members)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindSet)
+	if err != nil || e == nil {
+		return err
+	}
+	for _, m := range encoded {
+		delete(e.set, m)
+	}
+	r.removeIfEmpty(e)
+	return nil
+}
+
+// SCard 集合成员数
+func (r *cacheRepository) SCard(ctx context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, err := r.lookupKind(key, kindSet)
+	if err != nil || e == nil {
+		return 0, err
+	}
+	return int64(len(e.set)), nil
+}
@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+type tieredCacheRepository struct {
+	local    repository.CacheRepository
+	remote   repository.CacheRepository
+	localTTL int
+}
+
+// NewTieredCacheRepository 创建两级缓存：本地内存缓存在前，远端（Redis）缓存在后。
+// 字符串和哈希的读取先查本地，未命中时回源并在本地保留localTTL秒（不超过远端剩余的过期时间），
+// 热点键由本地LRU留存；写操作先写远端再使本地副本失效，其他实例上的本地副本最多滞后localTTL秒。
+// 列表和集合常作为队列和计数使用，只读写远端；上下文由repository.WithRemoteOnly标记时也只读写远端
+func NewTieredCacheRepository(local, remote repository.CacheRepository, localTTL int) repository.CacheRepository {
+	return &tieredCacheRepository{local: local, remote: remote, localTTL: localTTL}
+}
+
+// invalidate 使本地副本失效，本地缓存的错误不影响结果
+func (r *tieredCacheRepository) invalidate(ctx context.Context, keys ...string) {
+	_ = r.local.Del(ctx, keys...)
+}
+
+// ttl 本地副本的过期时间，不超过远端的过期时间
+func (r *tieredCacheRepository) ttl(remoteTTL int) int {
+	if remoteTTL > 0 && (r.localTTL <= 0 || remoteTTL < r.localTTL) {
+		return remoteTTL
+	}
+	return r.localTTL
+}
+
+// fillTTL 回源读取后本地副本的过期时间（秒），不超过远端剩余的过期时间。
+// 无法读取远端过期时间或剩余不足1秒时返回false，不保留本地副本
+func (r *tieredCacheRepository) fillTTL(ctx context.Context, key string) (int, bool) {
+	remaining, err := r.remote.TTL(ctx, key)
+	if err != nil {
+		return 0, false
+	}
+	if remaining == 0 {
+		return r.localTTL, true
+	}
+	seconds := int(remaining / time.Second)
+	if seconds <= 0 {
+		return 0, false
+	}
+	return r.ttl(seconds), true
+}
+
+// Set 设置键值，同时写入本地
+func (r *tieredCacheRepository) Set(ctx context.Context, key string, value interface{}, ttl int) error {
+	s, err := repository.EncodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	if err := r.remote.Set(ctx, key, s, ttl); err != nil || repository.IsRemoteOnly(ctx) {
+		r.invalidate(ctx, key)
+		return err
+	}
+	_ = r.local.Set(ctx, key, s, r.ttl(ttl))
+	return nil
+}
+
+// Get 获取键值，本地未命中时回源
+func (r *tieredCacheRepository) Get(ctx context.Context, key string) (string, error) {
+	if repository.IsRemoteOnly(ctx) {
+		return r.remote.Get(ctx, key)
+	}
+	if value, err := r.local.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	value, err := r.remote.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if ttl, ok := r.fillTTL(ctx, key); ok {
+		_ = r.local.Set(ctx, key, value, ttl)
+	}
+	return value, nil
+}
+
+// Del 删除键
+func (r *tieredCacheRepository) Del(ctx context.Context, keys ...string) error {
+	defer r.invalidate(ctx, keys...)
+	return r.remote.Del(ctx, keys...)
+}
+
+// Exists 键是否存在
+func (r *tieredCacheRepository) Exists(ctx context.Context, key string) (bool, error) {
+	if repository.IsRemoteOnly(ctx) {
+		return r.remote.Exists(ctx, key)
+	}
+	if ok, err := r.local.Exists(ctx, key); err == nil && ok {
+		return true, nil
+	}
+	return r.remote.Exists(ctx, key)
+}
+
+// Expire 设置键的过期时间
+func (r *tieredCacheRepository) Expire(ctx context.Context, key string, ttl int) error {
+	defer r.invalidate(ctx, key)
+	return r.remote.Expire(ctx, key, ttl)
+}
+
+// Keys 以远端为准列出键
+func (r *tieredCacheRepository) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return r.remote.Keys(ctx, pattern)
+}
+
+// TTL 以远端为准返回键剩余的过期时间
+func (r *tieredCacheRepository) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.remote.TTL(ctx, key)
+}
+
+// Incr 原子地将整数值加一，计数以远端为准
+func (r *tieredCacheRepository) Incr(ctx context.Context, key string) (int64, error) {
+	defer r.invalidate(ctx, key)
+	return r.remote.Incr(ctx, key)
+}
+
+// HSet 设置哈希字段
+func (r *tieredCacheRepository) HSet(ctx context.Context, key, field string, value interface{}) error {
+	defer r.invalidate(ctx, key)
+	return r.remote.HSet(ctx, key, field, value)
+}
+
+// HGet 获取哈希字段。本地只保存完整的哈希，本地存在该键时以本地为准
+func (r *tieredCacheRepository) HGet(ctx context.Context, key, field string) (string, error) {
+	if repository.IsRemoteOnly(ctx) {
+		return r.remote.HGet(ctx, key, field)
+	}
+	if ok, err := r.local.Exists(ctx, key); err == nil && ok {
+		value, err := r.local.HGet(ctx, key, field)
+		if err == nil || errors.Is(err, repository.ErrNotFound) {
+			return value, err
+		}
+	}
+	return r.remote.HGet(ctx, key, field)
+}
+
+// HGetAll 获取哈希的全部字段，本地未命中时回源并在本地保存完整的哈希
+func (r *tieredCacheRepository) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if repository.IsRemoteOnly(ctx) {
+		return r.remote.HGetAll(ctx, key)
+	}
+	if ok, err := r.local.Exists(ctx, key); err == nil && ok {
+		if all, err := r.local.HGetAll(ctx, key); err == nil {
+			return all, nil
+		}
+	}
+	all, err := r.remote.HGetAll(ctx, key)
+	if err != nil || len(all) == 0 {
+		return all, err
+	}
+	r.invalidate(ctx, key)
+	ttl, ok := r.fillTTL(ctx, key)
+	if !ok {
+		return all, nil
+	}
+	for field, value := range all {
+		if err := r.local.HSet(ctx, key, field, value); err != nil {
+			r.invalidate(ctx, key)
+			return all, nil
+		}
+	}
+	if ttl > 0 {
+		_ = r.local.Expire(ctx, key, ttl)
+	}
+	return all, nil
+}
+
+// HDel 删除哈希字段
+func (r *tieredCacheRepository) HDel(ctx context.Context, key string, fields ...string) error {
+	defer r.invalidate(ctx, key)
+	return r.remote.HDel(ctx, key, fields...)
+}
+
+// LPush 从列表头部插入
+func (r *tieredCacheRepository) LPush(ctx context.Context, key string, values ...interface{}) error {
+	return r.remote.LPush(ctx, key, values...)
+}
+
+// RPush 从列表尾部插入
+func (r *tieredCacheRepository) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return r.remote.RPush(ctx, key, values...)
+}
+
+// LPop 从列表头部弹出
+func (r *tieredCacheRepository) LPop(ctx context.Context, key string) (string, error) {
+	return r.remote.LPop(ctx, key)
+}
+
+// RPop 从列表尾部弹出
+func (r *tieredCacheRepository) RPop(ctx context.Context, key string) (string, error) {
+	return r.remote.RPop(ctx, key)
+}
+
+// LLen 列表长度
+func (r *tieredCacheRepository) LLen(ctx context.Context, key string) (int64, error) {
+	return r.remote.LLen(ctx, key)
+}
+
+// LRange 获取列表区间内的元素
+func (r *tieredCacheRepository) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return r.remote.LRange(ctx, key, start, stop)
+}
+
+// SAdd 向集合添加成员
+func (r *tieredCacheRepository) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return r.remote.SAdd(ctx, key, members...)
+}
+
+// SMembers 获取集合的全部成员
+func (r *tieredCacheRepository) SMembers(ctx context.Context, key string) ([]string, error) {
+	return r.remote.SMembers(ctx, key)
+}
+
+// SRem 从集合删除成员
+func (r *tieredCacheRepository) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return r.remote.SRem(ctx, key, members...)
+}
+
+// SCard 集合成员数
+func (r *tieredCacheRepository) SCard(ctx context.Context, key string) (int64, error) {
+	return r.remote.SCard(ctx, key)
+}
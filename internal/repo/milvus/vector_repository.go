@@ -178,6 +178,7 @@ func (r *vectorRepository) Search(ctx context.Context, collectionName string, ve
 	if err != nil {
 		return nil, fmt.Errorf("failed to create search params: %w", err)
 	}
+	expr := ""
 	if params != nil {
 		if ef, ok := params["ef"].(int); ok {
 			searchParams, err = entity.NewIndexHNSWSearchParam(ef)
@@ -185,6 +186,10 @@ func (r *vectorRepository) Search(ctx context.Context, collectionName string, ve
 				return nil, fmt.Errorf("failed to create search params: %w", err)
 			}
 		}
+		// 过滤表达式，如 metadata["domain_id"] == 1
+		if e, ok := params["expr"].(string); ok {
+			expr = e
+		}
 	}
 
 	// 转换向量数据
@@ -198,7 +203,7 @@ func (r *vectorRepository) Search(ctx context.Context, collectionName string, ve
 		ctx,
 		collectionName,
 		[]string{},
-		"",
+		expr,
 		[]string{"id", "metadata"},
 		vectorEntities,
 		"vector",
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type communitySummaryRepository struct {
+	db *gorm.DB
+}
+
+// NewCommunitySummaryRepository 创建社区摘要仓储实例
+func NewCommunitySummaryRepository(db *gorm.DB) repository.CommunitySummaryRepository {
+	return &communitySummaryRepository{db: db}
+}
+
+// Create 创建社区摘要
+func (r *communitySummaryRepository) Create(ctx context.Context, summary *models.CommunitySummary) error {
+	return r.db.WithContext(ctx).Create(summary).Error
+}
+
+// Update 更新社区摘要
+func (r *communitySummaryRepository) Update(ctx context.Context, summary *models.CommunitySummary) error {
+	return r.db.WithContext(ctx).Save(summary).Error
+}
+
+// Delete 删除社区摘要
+func (r *communitySummaryRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&models.CommunitySummary{}, id).Error
+}
+
+// ListByDomain 根据知识域获取社区摘要列表，按社区规模降序
+func (r *communitySummaryRepository) ListByDomain(ctx context.Context, domainID uint64, offset, limit int) ([]*models.CommunitySummary, error) {
+	var summaries []*models.CommunitySummary
+	err := r.db.WithContext(ctx).
+		Where("domain_id = ?", domainID).
+		Order("size DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&summaries).Error
+	return summaries, err
+}
+
+// ListByCommunityIDs 根据社区ID批量获取社区摘要
+func (r *communitySummaryRepository) ListByCommunityIDs(ctx context.Context, domainID uint64, communityIDs []string) ([]*models.CommunitySummary, error) {
+	var summaries []*models.CommunitySummary
+	if len(communityIDs) == 0 {
+		return summaries, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("domain_id = ? AND community_id IN ?", domainID, communityIDs).
+		Find(&summaries).Error
+	return summaries, err
+}
+
+// CountByDomain 根据知识域获取社区摘要总数
+func (r *communitySummaryRepository) CountByDomain(ctx context.Context, domainID uint64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.CommunitySummary{}).
+		Where("domain_id = ?", domainID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,202 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type conversationRepository struct {
+	db *gorm.DB
+}
+
+// NewConversationRepository 创建对话仓储实例
+func NewConversationRepository(db *gorm.DB) repository.ConversationRepository {
+	return &conversationRepository{db: db}
+}
+
+// Create 创建对话
+func (r *conversationRepository) Create(ctx context.Context, conversation *models.Conversation) error {
+	return r.db.WithContext(ctx).Omit(omitConversationFields(conversation)...).Create(conversation).Error
+}
+
+// omitConversationFields 不级联保存关联的知识域和用户；匿名对话的user_id保持为NULL以满足外键约束
+func omitConversationFields(conversation *models.Conversation) []string {
+	fields := []string{clause.Associations}
+	if conversation.UserID == 0 {
+		fields = append(fields, "UserID")
+	}
+	return fields
+}
+
+// GetByID 根据ID获取对话
+func (r *conversationRepository) GetByID(ctx context.Context, id uint64) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.db.WithContext(ctx).Preload("Domain").First(&conversation, id).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &conversation, nil
+}
+
+// GetByConversationID 根据对话ID获取对话
+func (r *conversationRepository) GetByConversationID(ctx context.Context, conversationID string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.db.WithContext(ctx).
+		Preload("Domain").
+		Where("conversation_id = ?", conversationID).
+		First(&conversation).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &conversation, nil
+}
+
+// Update 以Version做乐观锁更新对话，条件不满足时返回ErrStaleVersion
+func (r *conversationRepository) Update(ctx context.Context, conversation *models.Conversation) error {
+	fields := []string{"DomainID", "Content", "Tags", "ProcessedAt", "DistillAttempts", "Version"}
+	if conversation.UserID != 0 {
+		fields = append(fields, "UserID")
+	}
+	version := conversation.Version
+	conversation.Version++
+	result := r.db.WithContext(ctx).
+		Model(conversation).
+		Where("version = ?", version).
+		Select(fields).
+		Updates(conversation)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = fmt.Errorf("%w: conversation %s", repository.ErrStaleVersion, conversation.ConversationID)
+	}
+	if result.Error != nil {
+		conversation.Version = version
+	}
+	return result.Error
+}
+
+// Delete 删除对话
+func (r *conversationRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&models.Conversation{}, id).Error
+}
+
+// List 获取对话列表
+func (r *conversationRepository) List(ctx context.Context, offset, limit int) ([]*models.Conversation, error) {
+	var conversations []*models.Conversation
+	err := r.db.WithContext(ctx).
+		Preload("Domain").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// ListByDomain 根据知识域获取对话列表
+func (r *conversationRepository) ListByDomain(ctx context.Context, domainID uint64, offset, limit int) ([]*models.Conversation, error) {
+	var conversations []*models.Conversation
+	err := r.db.WithContext(ctx).
+		Preload("Domain").
+		Where("domain_id = ?", domainID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// ListByUser 根据用户获取对话列表
+func (r *conversationRepository) ListByUser(ctx context.Context, userID uint64, offset, limit int) ([]*models.Conversation, error) {
+	var conversations []*models.Conversation
+	err := r.db.WithContext(ctx).
+		Preload("Domain").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// Count 获取对话总数
+func (r *conversationRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Conversation{}).Count(&count).Error
+	return count, err
+}
+
+// CountByDomain 根据知识域获取对话总数
+func (r *conversationRepository) CountByDomain(ctx context.Context, domainID uint64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Conversation{}).
+		Where("domain_id = ?", domainID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByUser 根据用户获取对话总数
+func (r *conversationRepository) CountByUser(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Conversation{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// ListUnprocessed 获取尚未提炼记忆且未用尽重试次数的对话
+func (r *conversationRepository) ListUnprocessed(ctx context.Context, maxAttempts, limit int) ([]*models.Conversation, error) {
+	var conversations []*models.Conversation
+	err := r.db.WithContext(ctx).
+		Where("processed_at IS NULL AND distill_attempts < ?", maxAttempts).
+		Order("distill_attempts ASC, id ASC").
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// MarkProcessed 标记对话已提炼，只在版本号未变时更新
+func (r *conversationRepository) MarkProcessed(ctx context.Context, id, version uint64, processedAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Conversation{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{
+			"processed_at": processedAt,
+			"version":      gorm.Expr("version + 1"),
+		})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return fmt.Errorf("%w: conversation %d", repository.ErrStaleVersion, id)
+	}
+	return result.Error
+}
+
+// UpdateContent 只更新对话内容，只在版本号未变时更新
+func (r *conversationRepository) UpdateContent(ctx context.Context, conversation *models.Conversation) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Conversation{}).
+		Where("id = ? AND version = ?", conversation.ID, conversation.Version).
+		Updates(map[string]interface{}{
+			"content": conversation.Content,
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return fmt.Errorf("%w: conversation %s", repository.ErrStaleVersion, conversation.ConversationID)
+	}
+	if result.Error == nil {
+		conversation.Version++
+	}
+	return result.Error
+}
+
+// RecordDistillFailure 递增提炼失败次数，不改变版本号
+func (r *conversationRepository) RecordDistillFailure(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Conversation{}).
+		Where("id = ?", id).
+		UpdateColumn("distill_attempts", gorm.Expr("distill_attempts + 1")).Error
+}
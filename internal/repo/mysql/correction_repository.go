@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type correctionRepository struct {
+	db *gorm.DB
+}
+
+// NewCorrectionRepository 创建纠正仓储实例
+func NewCorrectionRepository(db *gorm.DB) repository.CorrectionRepository {
+	return &correctionRepository{db: db}
+}
+
+// Create 创建纠正
+func (r *correctionRepository) Create(ctx context.Context, correction *models.Correction) error {
+	return r.db.WithContext(ctx).Create(correction).Error
+}
+
+// Update 更新纠正
+func (r *correctionRepository) Update(ctx context.Context, correction *models.Correction) error {
+	return r.db.WithContext(ctx).Save(correction).Error
+}
+
+// GetByCorrectionIDs 根据纠正ID批量获取纠正
+func (r *correctionRepository) GetByCorrectionIDs(ctx context.Context, correctionIDs []string) ([]*models.Correction, error) {
+	var corrections []*models.Correction
+	if len(correctionIDs) == 0 {
+		return corrections, nil
+	}
+	err := r.db.WithContext(ctx).Where("correction_id IN ?", correctionIDs).Find(&corrections).Error
+	return corrections, err
+}
+
+// ExistingSourceRefs 返回refs中已学习过的来源标识
+func (r *correctionRepository) ExistingSourceRefs(ctx context.Context, refs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(refs) == 0 {
+		return existing, nil
+	}
+	var found []string
+	err := r.db.WithContext(ctx).
+		Model(&models.Correction{}).
+		Where("source_ref IN ?", refs).
+		Pluck("source_ref", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range found {
+		existing[ref] = true
+	}
+	return existing, nil
+}
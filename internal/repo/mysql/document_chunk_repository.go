@@ -42,6 +42,16 @@ func (r *documentChunkRepository) GetByChunkID(ctx context.Context, chunkID stri
 	return &chunk, nil
 }
 
+// GetByChunkIDs 根据分块ID批量获取文档分块
+func (r *documentChunkRepository) GetByChunkIDs(ctx context.Context, chunkIDs []string) ([]*models.DocumentChunk, error) {
+	var chunks []*models.DocumentChunk
+	if len(chunkIDs) == 0 {
+		return chunks, nil
+	}
+	err := r.db.WithContext(ctx).Where("chunk_id IN ?", chunkIDs).Find(&chunks).Error
+	return chunks, err
+}
+
 // Update 更新文档分块
 func (r *documentChunkRepository) Update(ctx context.Context, chunk *models.DocumentChunk) error {
 	return r.db.WithContext(ctx).Save(chunk).Error
@@ -45,6 +45,19 @@ func (r *documentRepository) GetByDocumentID(ctx context.Context, documentID str
 	return &document, nil
 }
 
+// GetByDocumentIDs 根据文档ID批量获取文档
+func (r *documentRepository) GetByDocumentIDs(ctx context.Context, documentIDs []string) ([]*models.Document, error) {
+	var documents []*models.Document
+	if len(documentIDs) == 0 {
+		return documents, nil
+	}
+	err := r.db.WithContext(ctx).
+		Preload("Domain").
+		Where("document_id IN ?", documentIDs).
+		Find(&documents).Error
+	return documents, err
+}
+
 // Update 更新文档
 func (r *documentRepository) Update(ctx context.Context, document *models.Document) error {
 	return r.db.WithContext(ctx).Save(document).Error
@@ -27,7 +27,7 @@ func (r *domainRepository) GetByID(ctx context.Context, id uint64) (*models.Doma
 	var domain models.Domain
 	err := r.db.WithContext(ctx).First(&domain, id).Error
 	if err != nil {
-		return nil, err
+		return nil, wrapNotFound(err)
 	}
 	return &domain, nil
 }
@@ -0,0 +1,272 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"github.com/xyzbit/ino/internal/pkg/textutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	feedbackTrendDays       = 30   // 趋势统计的天数
+	feedbackIssueSample     = 1000 // 参与问题聚类的最近负面评论数
+	feedbackTopIssues       = 10
+	feedbackIssueSimilarity = 0.3 // 评论归入同一问题的最低Jaccard相似度
+	feedbackIssueMaxRunes   = 120
+)
+
+type feedbackRepository struct {
+	db *gorm.DB
+}
+
+// NewFeedbackRepository 创建反馈仓储实例
+func NewFeedbackRepository(db *gorm.DB) repository.FeedbackRepository {
+	return &feedbackRepository{db: db}
+}
+
+// Create 创建反馈
+func (r *feedbackRepository) Create(ctx context.Context, feedback *models.Feedback) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(feedback).Error
+}
+
+// GetByID 根据ID获取反馈
+func (r *feedbackRepository) GetByID(ctx context.Context, id uint64) (*models.Feedback, error) {
+	var feedback models.Feedback
+	err := r.db.WithContext(ctx).First(&feedback, id).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &feedback, nil
+}
+
+// GetByQueryID 获取某次检索的全部反馈
+func (r *feedbackRepository) GetByQueryID(ctx context.Context, queryID string) ([]*models.Feedback, error) {
+	var feedback []*models.Feedback
+	err := r.db.WithContext(ctx).
+		Where("query_id = ?", queryID).
+		Order("created_at ASC").
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// GetByQueryAndUser 获取用户对某次检索的反馈
+func (r *feedbackRepository) GetByQueryAndUser(ctx context.Context, queryID string, userID uint64) (*models.Feedback, error) {
+	var feedback models.Feedback
+	err := r.db.WithContext(ctx).
+		Where("query_id = ? AND user_id = ?", queryID, userID).
+		Order("id DESC").
+		First(&feedback).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &feedback, nil
+}
+
+// Update 更新反馈
+func (r *feedbackRepository) Update(ctx context.Context, feedback *models.Feedback) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(feedback).Error
+}
+
+// Delete 删除反馈
+func (r *feedbackRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&models.Feedback{}, id).Error
+}
+
+// List 获取反馈列表，按时间倒序
+func (r *feedbackRepository) List(ctx context.Context, offset, limit int) ([]*models.Feedback, error) {
+	var feedback []*models.Feedback
+	err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// ListByUser 获取用户的反馈列表
+func (r *feedbackRepository) ListByUser(ctx context.Context, userID uint64, offset, limit int) ([]*models.Feedback, error) {
+	var feedback []*models.Feedback
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// ListByType 获取某类型的反馈列表
+func (r *feedbackRepository) ListByType(ctx context.Context, feedbackType models.FeedbackType, offset, limit int) ([]*models.Feedback, error) {
+	var feedback []*models.Feedback
+	err := r.db.WithContext(ctx).
+		Where("feedback_type = ?", feedbackType).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// Count 获取反馈总数
+func (r *feedbackRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Feedback{}).Count(&count).Error
+	return count, err
+}
+
+// CountByType 获取某类型的反馈数
+func (r *feedbackRepository) CountByType(ctx context.Context, feedbackType models.FeedbackType) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Feedback{}).Where("feedback_type = ?", feedbackType).Count(&count).Error
+	return count, err
+}
+
+// ListSince 按ID顺序分页获取since之后的反馈
+func (r *feedbackRepository) ListSince(ctx context.Context, since time.Time, afterID uint64, limit int) ([]*models.Feedback, error) {
+	var feedback []*models.Feedback
+	err := r.db.WithContext(ctx).
+		Where("created_at >= ? AND id > ?", since, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// ListUpdatedAfter 按更新时间和ID顺序分页获取在(after, afterID)之后创建或修改的反馈
+func (r *feedbackRepository) ListUpdatedAfter(ctx context.Context, after time.Time, afterID uint64, limit int) ([]*models.Feedback, error) {
+	var feedback []*models.Feedback
+	err := r.db.WithContext(ctx).
+		Where("updated_at > ? OR (updated_at = ? AND id > ?)", after, after, afterID).
+		Order("updated_at ASC, id ASC").
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// GetStats 统计反馈：各类型数量和占比、平均评分、最近30天的每日趋势，以及负面评论聚类出的常见问题
+func (r *feedbackRepository) GetStats(ctx context.Context) (*models.FeedbackStats, error) {
+	stats := &models.FeedbackStats{
+		TopIssues: []models.FeedbackIssue{},
+		TrendData: []models.FeedbackTrend{},
+	}
+
+	var counts []struct {
+		FeedbackType models.FeedbackType
+		Count        int
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Feedback{}).
+		Select("feedback_type, COUNT(*) AS count").
+		Group("feedback_type").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		stats.TotalFeedback += c.Count
+		switch c.FeedbackType {
+		case models.FeedbackTypePositive:
+			stats.PositiveCount = c.Count
+		case models.FeedbackTypeNegative:
+			stats.NegativeCount = c.Count
+		case models.FeedbackTypeNeutral:
+			stats.NeutralCount = c.Count
+		}
+	}
+	if stats.TotalFeedback > 0 {
+		stats.PositiveRate = float64(stats.PositiveCount) / float64(stats.TotalFeedback)
+		stats.NegativeRate = float64(stats.NegativeCount) / float64(stats.TotalFeedback)
+	}
+
+	var avg struct{ AverageRating *float64 }
+	err = r.db.WithContext(ctx).
+		Model(&models.Feedback{}).
+		Select("AVG(rating) AS average_rating").
+		Where("rating BETWEEN 1 AND 5").
+		Scan(&avg).Error
+	if err != nil {
+		return nil, err
+	}
+	if avg.AverageRating != nil {
+		stats.AverageRating = *avg.AverageRating
+	}
+
+	if stats.TrendData, err = r.trend(ctx); err != nil {
+		return nil, err
+	}
+	if stats.TopIssues, err = r.topIssues(ctx); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// trend 最近feedbackTrendDays天的每日统计，没有反馈的日期不返回
+func (r *feedbackRepository) trend(ctx context.Context) ([]models.FeedbackTrend, error) {
+	var rows []struct {
+		Day           string
+		PositiveCount int
+		NegativeCount int
+		AverageRating *float64
+	}
+	y, m, d := time.Now().AddDate(0, 0, -feedbackTrendDays+1).Date()
+	since := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	err := r.db.WithContext(ctx).
+		Model(&models.Feedback{}).
+		Select(`DATE_FORMAT(created_at, '%Y-%m-%d') AS day,
+			SUM(feedback_type = ?) AS positive_count,
+			SUM(feedback_type = ?) AS negative_count,
+			AVG(CASE WHEN rating BETWEEN 1 AND 5 THEN rating END) AS average_rating`,
+			models.FeedbackTypePositive, models.FeedbackTypeNegative).
+		Where("created_at >= ?", since).
+		Group("day").
+		Order("day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	trend := make([]models.FeedbackTrend, 0, len(rows))
+	for _, row := range rows {
+		date, err := time.ParseInLocation("2006-01-02", row.Day, time.Local)
+		if err != nil {
+			continue
+		}
+		t := models.FeedbackTrend{Date: date, PositiveCount: row.PositiveCount, NegativeCount: row.NegativeCount}
+		if row.AverageRating != nil {
+			t.AverageRating = *row.AverageRating
+		}
+		trend = append(trend, t)
+	}
+	return trend, nil
+}
+
+// topIssues 对最近的负面评论聚类，按问题出现次数降序返回
+func (r *feedbackRepository) topIssues(ctx context.Context) ([]models.FeedbackIssue, error) {
+	var comments []string
+	err := r.db.WithContext(ctx).
+		Model(&models.Feedback{}).
+		Where("feedback_type = ? AND comment IS NOT NULL AND comment <> ''", models.FeedbackTypeNegative).
+		Order("created_at DESC").
+		Limit(feedbackIssueSample).
+		Pluck("comment", &comments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []models.FeedbackIssue{}
+	for _, cluster := range textutil.ClusterTexts(comments, feedbackIssueSimilarity) {
+		if len(issues) == feedbackTopIssues {
+			break
+		}
+		label := []rune(cluster.Label)
+		if len(label) > feedbackIssueMaxRunes {
+			label = append(label[:feedbackIssueMaxRunes], '…')
+		}
+		issues = append(issues, models.FeedbackIssue{Issue: string(label), Count: len(cluster.Members)})
+	}
+	return issues, nil
+}
@@ -0,0 +1,45 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type memoryAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewMemoryAuditRepository 创建记忆审计日志仓储实例
+func NewMemoryAuditRepository(db *gorm.DB) repository.MemoryAuditRepository {
+	return &memoryAuditRepository{db: db}
+}
+
+// Create 批量写入审计日志
+func (r *memoryAuditRepository) Create(ctx context.Context, logs ...*models.MemoryAuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(logs).Error
+}
+
+// ListByUser 获取用户的审计日志，按时间倒序
+func (r *memoryAuditRepository) ListByUser(ctx context.Context, userID uint64, offset, limit int) ([]*models.MemoryAuditLog, error) {
+	var logs []*models.MemoryAuditLog
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// CountByUser 获取用户的审计日志总数
+func (r *memoryAuditRepository) CountByUser(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.MemoryAuditLog{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
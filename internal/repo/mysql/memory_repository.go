@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+type memoryRepository struct {
+	db *gorm.DB
+}
+
+// NewMemoryRepository 创建用户记忆仓储实例
+func NewMemoryRepository(db *gorm.DB) repository.MemoryRepository {
+	return &memoryRepository{db: db}
+}
+
+// Create 创建记忆
+func (r *memoryRepository) Create(ctx context.Context, memory *models.Memory) error {
+	return r.db.WithContext(ctx).Create(memory).Error
+}
+
+// GetByMemoryID 根据记忆ID获取记忆
+func (r *memoryRepository) GetByMemoryID(ctx context.Context, memoryID string) (*models.Memory, error) {
+	var memory models.Memory
+	err := r.db.WithContext(ctx).Where("memory_id = ?", memoryID).First(&memory).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &memory, nil
+}
+
+// GetByMemoryIDs 根据记忆ID批量获取记忆
+func (r *memoryRepository) GetByMemoryIDs(ctx context.Context, memoryIDs []string) ([]*models.Memory, error) {
+	var memories []*models.Memory
+	if len(memoryIDs) == 0 {
+		return memories, nil
+	}
+	err := r.db.WithContext(ctx).Where("memory_id IN ?", memoryIDs).Find(&memories).Error
+	return memories, err
+}
+
+// Update 更新记忆
+func (r *memoryRepository) Update(ctx context.Context, memory *models.Memory) error {
+	return r.db.WithContext(ctx).Save(memory).Error
+}
+
+// Delete 删除记忆
+func (r *memoryRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&models.Memory{}, id).Error
+}
+
+// ListByUser 获取用户的记忆列表，domainID为0时不限知识域
+func (r *memoryRepository) ListByUser(ctx context.Context, userID, domainID uint64, offset, limit int) ([]*models.Memory, error) {
+	var memories []*models.Memory
+	err := r.byUser(ctx, userID, domainID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&memories).Error
+	return memories, err
+}
+
+// ListByConversation 获取从对话中提炼的记忆
+func (r *memoryRepository) ListByConversation(ctx context.Context, conversationID string) ([]*models.Memory, error) {
+	var memories []*models.Memory
+	err := r.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Order("created_at ASC").
+		Find(&memories).Error
+	return memories, err
+}
+
+// CountByUser 获取用户的记忆总数，domainID为0时不限知识域
+func (r *memoryRepository) CountByUser(ctx context.Context, userID, domainID uint64) (int64, error) {
+	var count int64
+	err := r.byUser(ctx, userID, domainID).Model(&models.Memory{}).Count(&count).Error
+	return count, err
+}
+
+// ListUserIDs 获取在知识域中有未归档记忆的用户
+func (r *memoryRepository) ListUserIDs(ctx context.Context, domainID uint64) ([]uint64, error) {
+	var userIDs []uint64
+	err := r.db.WithContext(ctx).
+		Model(&models.Memory{}).
+		Where("domain_id = ? AND archived_at IS NULL", domainID).
+		Distinct().
+		Order("user_id").
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// ListByKeyword 获取内容包含关键词的记忆（含已归档），domainID为0时不限知识域
+func (r *memoryRepository) ListByKeyword(ctx context.Context, userID, domainID uint64, keyword string) ([]*models.Memory, error) {
+	var memories []*models.Memory
+	db := r.db.WithContext(ctx).Where("user_id = ? AND content LIKE ?", userID, "%"+escapeLike(keyword)+"%")
+	if domainID != 0 {
+		db = db.Where("domain_id = ?", domainID)
+	}
+	err := db.Order("created_at ASC").Find(&memories).Error
+	return memories, err
+}
+
+func (r *memoryRepository) byUser(ctx context.Context, userID, domainID uint64) *gorm.DB {
+	db := r.db.WithContext(ctx).Where("user_id = ? AND archived_at IS NULL", userID)
+	if domainID != 0 {
+		db = db.Where("domain_id = ?", domainID)
+	}
+	return db
+}
+
+// RecordAccess 记录记忆被检索命中
+func (r *memoryRepository) RecordAccess(ctx context.Context, memoryIDs []string, at time.Time) error {
+	if len(memoryIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&models.Memory{}).
+		Where("memory_id IN ?", memoryIDs).
+		UpdateColumns(map[string]interface{}{
+			"access_count":  gorm.Expr("access_count + 1"),
+			"last_accessed": at,
+		}).Error
+}
+
+// UpdateDecay 更新记忆衰减后的重要度
+func (r *memoryRepository) UpdateDecay(ctx context.Context, memoryID string, importance float64, decayedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Memory{}).
+		Where("memory_id = ?", memoryID).
+		UpdateColumns(map[string]interface{}{
+			"importance_score": importance,
+			"decayed_at":       decayedAt,
+		}).Error
+}
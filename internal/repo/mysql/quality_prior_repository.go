@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+)
+
+const qualityPriorBatchSize = 500
+
+type qualityPriorRepository struct {
+	db *gorm.DB
+}
+
+// NewQualityPriorRepository 创建质量先验仓储实例
+func NewQualityPriorRepository(db *gorm.DB) repository.QualityPriorRepository {
+	return &qualityPriorRepository{db: db}
+}
+
+// ReplaceAll 在事务中清空并写入新的先验
+func (r *qualityPriorRepository) ReplaceAll(ctx context.Context, priors []*models.QualityPrior) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.QualityPrior{}).Error; err != nil {
+			return err
+		}
+		if len(priors) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(priors, qualityPriorBatchSize).Error
+	})
+}
+
+// List 获取全部先验
+func (r *qualityPriorRepository) List(ctx context.Context) ([]*models.QualityPrior, error) {
+	var priors []*models.QualityPrior
+	err := r.db.WithContext(ctx).Find(&priors).Error
+	return priors, err
+}
@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const querySuggestionBatchSize = 500
+
+type querySuggestionRepository struct {
+	db *gorm.DB
+}
+
+// NewQuerySuggestionRepository 创建查询建议仓储实例
+func NewQuerySuggestionRepository(db *gorm.DB) repository.QuerySuggestionRepository {
+	return &querySuggestionRepository{db: db}
+}
+
+// Create 创建查询建议
+func (r *querySuggestionRepository) Create(ctx context.Context, suggestion *models.QuerySuggestion) error {
+	return r.db.WithContext(ctx).Create(suggestion).Error
+}
+
+// Update 更新查询建议
+func (r *querySuggestionRepository) Update(ctx context.Context, suggestion *models.QuerySuggestion) error {
+	return r.db.WithContext(ctx).Save(suggestion).Error
+}
+
+// Delete 删除查询建议
+func (r *querySuggestionRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&models.QuerySuggestion{}, id).Error
+}
+
+// GetByID 根据ID获取查询建议
+func (r *querySuggestionRepository) GetByID(ctx context.Context, id uint64) (*models.QuerySuggestion, error) {
+	var suggestion models.QuerySuggestion
+	if err := r.db.WithContext(ctx).First(&suggestion, id).Error; err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &suggestion, nil
+}
+
+// List 按条件分页获取查询建议，按分数降序
+func (r *querySuggestionRepository) List(ctx context.Context, filter models.SuggestionFilter) ([]*models.QuerySuggestion, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.QuerySuggestion{})
+	if filter.DomainID != 0 {
+		db = db.Where("domain_id = ?", filter.DomainID)
+	}
+	if filter.Query != "" {
+		db = db.Where("query LIKE ?", escapeLike(filter.Query)+"%")
+	}
+	if filter.Source != "" {
+		db = db.Where("source = ?", filter.Source)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var suggestions []*models.QuerySuggestion
+	err := db.Order("score DESC, id ASC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&suggestions).Error
+	return suggestions, total, err
+}
+
+// ListActive 获取查询在知识域及全部知识域下生效的建议
+func (r *querySuggestionRepository) ListActive(ctx context.Context, domainID uint64, query string, limit int) ([]*models.QuerySuggestion, error) {
+	var suggestions []*models.QuerySuggestion
+	err := r.db.WithContext(ctx).
+		Where("query = ? AND status = ? AND domain_id IN ?", query, models.SuggestionStatusActive, []uint64{0, domainID}).
+		Order("score DESC").
+		Limit(limit).
+		Find(&suggestions).Error
+	return suggestions, err
+}
+
+// Complete 获取查询或建议以prefix开头的生效建议
+func (r *querySuggestionRepository) Complete(ctx context.Context, domainID uint64, prefix string, limit int) ([]*models.QuerySuggestion, error) {
+	pattern := escapeLike(prefix) + "%"
+	var suggestions []*models.QuerySuggestion
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND domain_id IN ?", models.SuggestionStatusActive, []uint64{0, domainID}).
+		Where("suggestion LIKE ?", pattern).
+		Order("score DESC").
+		Limit(limit).
+		Find(&suggestions).Error
+	return suggestions, err
+}
+
+// ReplaceAuto 在事务中删除生效的自动建议并写入新建议，与保留的建议重复时跳过
+func (r *querySuggestionRepository) ReplaceAuto(ctx context.Context, suggestions []*models.QuerySuggestion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("source = ? AND status = ?", models.SuggestionSourceAuto, models.SuggestionStatusActive).
+			Delete(&models.QuerySuggestion{}).Error
+		if err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(suggestions, querySuggestionBatchSize).Error
+	})
+}
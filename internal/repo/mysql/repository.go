@@ -1,6 +1,10 @@
 package mysql
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/xyzbit/ino/internal/domain/repository"
 	"gorm.io/gorm"
 )
@@ -12,6 +16,31 @@ func NewRepository(db *gorm.DB) *repository.Repository {
 		Domain:        NewDomainRepository(db),
 		Document:      NewDocumentRepository(db),
 		DocumentChunk: NewDocumentChunkRepository(db),
+		Conversation:  NewConversationRepository(db),
+		Memory:        NewMemoryRepository(db),
+		MemoryAudit:   NewMemoryAuditRepository(db),
+		Feedback:      NewFeedbackRepository(db),
+		SearchLog:     NewSearchLogRepository(db),
+		QualityPrior:  NewQualityPriorRepository(db),
+		Correction:    NewCorrectionRepository(db),
+		Suggestion:    NewQuerySuggestionRepository(db),
+		Community:     NewCommunitySummaryRepository(db),
 		// 其他仓储将在后续添加
 	}
 }
+
+// wrapNotFound 将记录不存在的错误包装为repository.ErrNotFound，同时保留原始错误
+func wrapNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %w", repository.ErrNotFound, err)
+	}
+	return err
+}
+
+// likeEscaper 转义LIKE模式中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike 转义LIKE模式中的通配符，使关键词按字面匹配
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,253 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/xyzbit/ino/internal/domain/models"
+	"github.com/xyzbit/ino/internal/domain/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const searchStatsTopN = 10 // 热门查询和热门知识域的返回数
+
+type searchLogRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchLogRepository 创建搜索日志仓储实例
+func NewSearchLogRepository(db *gorm.DB) repository.SearchLogRepository {
+	return &searchLogRepository{db: db}
+}
+
+// omitSearchLogFields 不级联保存关联；匿名检索和未指定知识域的检索保持为NULL以满足外键约束
+func omitSearchLogFields(log *models.SearchLog) []string {
+	fields := []string{clause.Associations}
+	if log.UserID == 0 {
+		fields = append(fields, "UserID")
+	}
+	if log.DomainID == 0 {
+		fields = append(fields, "DomainID")
+	}
+	return fields
+}
+
+// Create 创建搜索日志
+func (r *searchLogRepository) Create(ctx context.Context, log *models.SearchLog) error {
+	return r.db.WithContext(ctx).Omit(omitSearchLogFields(log)...).Create(log).Error
+}
+
+// GetByID 根据ID获取搜索日志
+func (r *searchLogRepository) GetByID(ctx context.Context, id uint64) (*models.SearchLog, error) {
+	var log models.SearchLog
+	err := r.db.WithContext(ctx).First(&log, id).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &log, nil
+}
+
+// GetByQueryID 根据查询ID获取搜索日志
+func (r *searchLogRepository) GetByQueryID(ctx context.Context, queryID string) (*models.SearchLog, error) {
+	var log models.SearchLog
+	err := r.db.WithContext(ctx).Where("query_id = ?", queryID).First(&log).Error
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return &log, nil
+}
+
+// GetByQueryIDs 根据查询ID批量获取搜索日志
+func (r *searchLogRepository) GetByQueryIDs(ctx context.Context, queryIDs []string) ([]*models.SearchLog, error) {
+	var logs []*models.SearchLog
+	if len(queryIDs) == 0 {
+		return logs, nil
+	}
+	err := r.db.WithContext(ctx).Where("query_id IN ?", queryIDs).Find(&logs).Error
+	return logs, err
+}
+
+// Update 更新搜索日志
+func (r *searchLogRepository) Update(ctx context.Context, log *models.SearchLog) error {
+	return r.db.WithContext(ctx).Omit(omitSearchLogFields(log)...).Save(log).Error
+}
+
+// Delete 删除搜索日志
+func (r *searchLogRepository) Delete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&models.SearchLog{}, id).Error
+}
+
+// List 获取搜索日志列表，按时间倒序
+func (r *searchLogRepository) List(ctx context.Context, offset, limit int) ([]*models.SearchLog, error) {
+	var logs []*models.SearchLog
+	err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// ListByUser 获取用户的搜索日志
+func (r *searchLogRepository) ListByUser(ctx context.Context, userID uint64, offset, limit int) ([]*models.SearchLog, error) {
+	var logs []*models.SearchLog
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// ListByDomain 获取知识域的搜索日志
+func (r *searchLogRepository) ListByDomain(ctx context.Context, domainID uint64, offset, limit int) ([]*models.SearchLog, error) {
+	var logs []*models.SearchLog
+	err := r.db.WithContext(ctx).
+		Where("domain_id = ?", domainID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// Count 获取搜索日志总数
+func (r *searchLogRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.SearchLog{}).Count(&count).Error
+	return count, err
+}
+
+// inWindow 按创建时间限定统计范围
+func inWindow(db *gorm.DB, column string, window models.TimeRange) *gorm.DB {
+	if window.Start != nil {
+		db = db.Where(column+" >= ?", *window.Start)
+	}
+	if window.End != nil {
+		db = db.Where(column+" < ?", *window.End)
+	}
+	return db
+}
+
+// GetStats 统计时间范围内的检索：总量、独立用户、平均响应时间、热门查询、热门知识域、
+// 小时分布，以及点击率、零结果率和平均结果数等质量指标
+func (r *searchLogRepository) GetStats(ctx context.Context, window models.TimeRange) (*models.SearchStats, error) {
+	stats := &models.SearchStats{
+		TimeRange:          window,
+		TopQueries:         []models.QueryCount{},
+		PopularDomains:     []models.DomainCount{},
+		HourlyDistribution: make([]models.HourlyCount, 24),
+	}
+	for hour := range stats.HourlyDistribution {
+		stats.HourlyDistribution[hour].Hour = hour
+	}
+
+	var summary struct {
+		TotalSearches   int
+		UniqueUsers     int
+		AvgResponseTime *float64
+		ZeroResults     int
+		AvgResults      *float64
+		Clicked         int
+	}
+	err := inWindow(r.db.WithContext(ctx).Model(&models.SearchLog{}), "search_logs.created_at", window).
+		Select(`COUNT(*) AS total_searches,
+			COUNT(DISTINCT user_id) AS unique_users,
+			AVG(response_time_ms) AS avg_response_time,
+			COALESCE(SUM(COALESCE(CAST(JSON_EXTRACT(results, '$.total_hits') AS UNSIGNED), 0) = 0), 0) AS zero_results,
+			AVG(COALESCE(CAST(JSON_EXTRACT(results, '$.total_hits') AS UNSIGNED), 0)) AS avg_results,
+			COALESCE(SUM(EXISTS (
+				SELECT 1 FROM feedback
+				WHERE feedback.query_id = search_logs.query_id
+					AND JSON_LENGTH(feedback.context, '$.clicked_results') > 0
+			)), 0) AS clicked`).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.TotalSearches = summary.TotalSearches
+	stats.UniqueUsers = summary.UniqueUsers
+	if summary.AvgResponseTime != nil {
+		stats.AvgResponseTime = *summary.AvgResponseTime
+	}
+	if summary.AvgResults != nil {
+		stats.QualityMetrics.AvgResultsCount = *summary.AvgResults
+	}
+	if stats.TotalSearches == 0 {
+		return stats, nil
+	}
+	stats.QualityMetrics.ZeroResultRate = float64(summary.ZeroResults) / float64(stats.TotalSearches)
+	stats.QualityMetrics.ClickThroughRate = float64(summary.Clicked) / float64(stats.TotalSearches)
+
+	err = inWindow(r.db.WithContext(ctx).Model(&models.SearchLog{}), "created_at", window).
+		Select("LOWER(TRIM(query_text)) AS query, COUNT(*) AS count").
+		Group("query").
+		Order("count DESC").
+		Limit(searchStatsTopN).
+		Scan(&stats.TopQueries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = inWindow(r.db.WithContext(ctx).Model(&models.SearchLog{}), "search_logs.created_at", window).
+		Select("domains.domain_name, COUNT(*) AS count").
+		Joins("JOIN domains ON domains.id = search_logs.domain_id").
+		Group("domains.id, domains.domain_name").
+		Order("count DESC").
+		Limit(searchStatsTopN).
+		Scan(&stats.PopularDomains).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var hourly []models.HourlyCount
+	err = inWindow(r.db.WithContext(ctx).Model(&models.SearchLog{}), "created_at", window).
+		Select("HOUR(created_at) AS hour, COUNT(*) AS count").
+		Group("hour").
+		Scan(&hourly).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hourly {
+		if h.Hour >= 0 && h.Hour < len(stats.HourlyDistribution) {
+			stats.HourlyDistribution[h.Hour].Count = h.Count
+		}
+	}
+	return stats, nil
+}
+
+// ListRecent 按ID倒序获取since之后创建、ID小于beforeID的搜索日志，beforeID为0时从最新的开始
+func (r *searchLogRepository) ListRecent(ctx context.Context, since time.Time, beforeID uint64, limit int) ([]*models.SearchLog, error) {
+	var logs []*models.SearchLog
+	query := r.db.WithContext(ctx).Where("created_at >= ?", since)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	err := query.
+		Order("id DESC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// PopularQueries 时间范围内有命中结果的热门查询，按忽略大小写和首尾空白归并
+func (r *searchLogRepository) PopularQueries(ctx context.Context, domainID uint64, prefix string, window models.TimeRange, limit int) ([]models.QueryCount, error) {
+	queries := []models.QueryCount{}
+	db := inWindow(r.db.WithContext(ctx).Model(&models.SearchLog{}), "created_at", window).
+		Where("COALESCE(CAST(JSON_EXTRACT(results, '$.total_hits') AS UNSIGNED), 0) > 0")
+	if domainID != 0 {
+		db = db.Where("domain_id = ?", domainID)
+	}
+	if prefix != "" {
+		db = db.Where("LOWER(TRIM(query_text)) LIKE ?", escapeLike(strings.ToLower(prefix))+"%")
+	}
+	err := db.Select("LOWER(TRIM(query_text)) AS query, COUNT(*) AS count").
+		Group("query").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&queries).Error
+	return queries, err
+}
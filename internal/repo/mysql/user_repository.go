@@ -27,7 +27,7 @@ func (r *userRepository) GetByID(ctx context.Context, id uint64) (*models.User,
 	var user models.User
 	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
-		return nil, err
+		return nil, wrapNotFound(err)
 	}
 	return &user, nil
 }
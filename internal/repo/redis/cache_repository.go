@@ -0,0 +1,225 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/xyzbit/ino/internal/domain/repository"
+)
+
+const scanCount = 500 // SCAN每次迭代的建议返回数
+
+type cacheRepository struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+// NewCacheRepository 创建Redis缓存仓储实例，所有键加上namespace前缀，使不同环境可以共用同一个Redis
+func NewCacheRepository(client goredis.UniversalClient, namespace string) repository.CacheRepository {
+	prefix := ""
+	if namespace != "" {
+		prefix = strings.TrimSuffix(namespace, ":") + ":"
+	}
+	return &cacheRepository{client: client, prefix: prefix}
+}
+
+// key 加上命名空间前缀
+func (r *cacheRepository) key(key string) string {
+	return r.prefix + key
+}
+
+// wrapNil 将键不存在的错误包装为repository.ErrNotFound
+func wrapNil(err error) error {
+	if errors.Is(err, goredis.Nil) {
+		return fmt.Errorf("%w: %w", repository.ErrNotFound, err)
+	}
+	return err
+}
+
+// encodeValues 序列化多个值
+func encodeValues(values []interface{}) ([]interface{}, error) {
+	encoded := make([]interface{}, len(values))
+	for i, v := range values {
+		s, err := repository.EncodeCacheValue(v)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = s
+	}
+	return encoded, nil
+}
+
+// Set 设置键值
+func (r *cacheRepository) Set(ctx context.Context, key string, value interface{}, ttl int) error {
+	s, err := repository.EncodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(key), s, time.Duration(ttl)*time.Second).Err()
+}
+
+// Get 获取键值
+func (r *cacheRepository) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, r.key(key)).Result()
+	return value, wrapNil(err)
+}
+
+// Del 删除键
+func (r *cacheRepository) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = r.key(k)
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+// Exists 键是否存在
+func (r *cacheRepository) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.key(key)).Result()
+	return n > 0, err
+}
+
+// Expire 设置键的过期时间
+func (r *cacheRepository) Expire(ctx context.Context, key string, ttl int) error {
+	return r.client.Expire(ctx, r.key(key), time.Duration(ttl)*time.Second).Err()
+}
+
+// Keys 以SCAN遍历匹配的键，不会像KEYS一样阻塞Redis。返回的键不含命名空间前缀
+func (r *cacheRepository) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var (
+		keys   []string
+		cursor uint64
+	)
+	for {
+		batch, next, err := r.client.Scan(ctx, cursor, r.key(pattern), scanCount).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range batch {
+			keys = append(keys, strings.TrimPrefix(k, r.prefix))
+		}
+		if cursor = next; cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// TTL 键剩余的过期时间，不过期时返回0
+func (r *cacheRepository) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, r.key(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case ttl == -1: // 键存在但不过期
+		return 0, nil
+	case ttl <= 0: // 键不存在或即将过期
+		return 0, fmt.Errorf("%w: cache key %s", repository.ErrNotFound, key)
+	}
+	return ttl, nil
+}
+
+// Incr 原子地将整数值加一
+func (r *cacheRepository) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, r.key(key)).Result()
+}
+
+// HSet 设置哈希字段
+func (r *cacheRepository) HSet(ctx context.Context, key, field string, value interface{}) error {
+	s, err := repository.EncodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, r.key(key), field, s).Err()
+}
+
+// HGet 获取哈希字段
+func (r *cacheRepository) HGet(ctx context.Context, key, field string) (string, error) {
+	value, err := r.client.HGet(ctx, r.key(key), field).Result()
+	return value, wrapNil(err)
+}
+
+// HGetAll 获取哈希的全部字段
+func (r *cacheRepository) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r.client.HGetAll(ctx, r.key(key)).Result()
+}
+
+// HDel 删除哈希字段
+func (r *cacheRepository) HDel(ctx context.Context, key string, fields ...string) error {
+	return r.client.HDel(ctx, r.key(key), fields...).Err()
+}
+
+// LPush 从列表头部插入
+func (r *cacheRepository) LPush(ctx context.Context, key string, values ...interface{}) error {
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return err
+	}
+	return r.client.LPush(ctx, r.key(key), encoded...).Err()
+}
+
+// RPush 从列表尾部插入
+func (r *cacheRepository) RPush(ctx context.Context, key string, values ...interface{}) error {
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return err
+	}
+	return r.client.RPush(ctx, r.key(key), encoded...).Err()
+}
+
+// LPop 从列表头部弹出
+func (r *cacheRepository) LPop(ctx context.Context, key string) (string, error) {
+	value, err := r.client.LPop(ctx, r.key(key)).Result()
+	return value, wrapNil(err)
+}
+
+// RPop 从列表尾部弹出
+func (r *cacheRepository) RPop(ctx context.Context, key string) (string, error) {
+	value, err := r.client.RPop(ctx, r.key(key)).Result()
+	return value, wrapNil(err)
+}
+
+// LLen 列表长度
+func (r *cacheRepository) LLen(ctx context.Context, key string) (int64, error) {
+	return r.client.LLen(ctx, r.key(key)).Result()
+}
+
+// LRange 获取列表区间内的元素
+func (r *cacheRepository) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return r.client.LRange(ctx, r.key(key), start, stop).Result()
+}
+
+// SAdd 向集合添加成员
+func (r *cacheRepository) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	encoded, err := encodeValues(members)
+	if err != nil {
+		return err
+	}
+	return r.client.SAdd(ctx, r.key(key), encoded...).Err()
+}
+
+// SMembers 获取集合的全部成员
+func (r *cacheRepository) SMembers(ctx context.Context, key string) ([]string, error) {
+	return r.client.SMembers(ctx, r.key(key)).Result()
+}
+
+// SRem 从集合删除成员
+func (r *cacheRepository) SRem(ctx context.Context, key string, members ...interface{}) error {
+	encoded, err := encodeValues(members)
+	if err != nil {
+		return err
+	}
+	return r.client.SRem(ctx, r.key(key), encoded...).Err()
+}
+
+// SCard 集合成员数
+func (r *cacheRepository) SCard(ctx context.Context, key string) (int64, error) {
+	return r.client.SCard(ctx, r.key(key)).Result()
+}
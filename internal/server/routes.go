@@ -28,12 +28,50 @@ func RegisterRoutes(r *gin.Engine, version string) {
 		// 知识查询接口
 		knowledge.POST("/search", search.SearchKnowledge)
 
+		// 记忆查询接口
+		memory := v1.Group("/knowledge")
+		{
+			memory.POST("/memory", search.QueryMemory)
+			memory.POST("/context", search.AssembleContext)
+			memory.GET("/suggest", search.AutocompleteQuery)
+		}
+
+		// 用户记忆管理接口
+		memories := v1.Group("/users/:id/memories")
+		{
+			memories.GET("", manager.ListUserMemories)
+			memories.POST("/search", manager.SearchUserMemories)
+			memories.POST("/forget", manager.ForgetUserTopic)
+			memories.GET("/audit", manager.ListUserMemoryAudit)
+			memories.PUT("/:memory_id", manager.UpdateUserMemory)
+			memories.DELETE("/:memory_id", manager.DeleteUserMemory)
+			memories.POST("/:memory_id/pin", manager.PinUserMemory)
+			memories.DELETE("/:memory_id/pin", manager.UnpinUserMemory)
+		}
+
 		// 管理接口
 		admin := v1.Group("/admin")
 		{
 			admin.GET("/stats", manager.GetStats)
+			admin.GET("/feedback/stats", manager.GetFeedbackStats)
+			admin.GET("/search/stats", manager.GetSearchStats)
+			admin.GET("/suggestions", manager.ListSuggestions)
+			admin.POST("/suggestions", manager.CreateSuggestion)
+			admin.POST("/suggestions/mine", manager.MineSuggestions)
+			admin.PUT("/suggestions/:id", manager.UpdateSuggestion)
+			admin.DELETE("/suggestions/:id", manager.DeleteSuggestion)
 			admin.GET("/users", manager.GetUsers)
 			admin.POST("/users", manager.CreateUser)
+
+			// 图分析
+			admin.GET("/graph/analytics", manager.GetGraphAnalytics)
+			admin.POST("/graph/analytics", manager.RunGraphAnalytics)
+			admin.GET("/graph/communities", manager.ListCommunitySummaries)
+			admin.POST("/graph/communities/summarize", manager.SummarizeCommunities)
+			admin.POST("/graph/traverse", manager.TraverseGraph)
+			admin.GET("/graph/relations/history", manager.GetRelationHistory)
+			admin.GET("/graph/export", manager.ExportGraph)
+			admin.POST("/graph/import", manager.ImportGraph)
 		}
 	}
 }